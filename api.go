@@ -1,13 +1,52 @@
 package avcontrol
 
 import (
+	"encoding/json"
+	"fmt"
 	"strings"
+	"time"
 )
 
 // StateRequest is the JSON object that a consumer of the av-control-api sends in a PUT request
 // to set state
 type StateRequest struct {
 	Devices map[DeviceID]DeviceState `json:"devices,omitempty"`
+
+	// Phases are sets of device states that are applied in order, after Devices.
+	// Each phase is started once every device in the previous phase has finished.
+	Phases []StatePhase `json:"phases,omitempty"`
+
+	// AbortOnError skips the remaining phases once a phase has an error.
+	AbortOnError bool `json:"abortOnError,omitempty"`
+
+	// Adjust changes devices relative to their current state. Adjustments are applied before Devices.
+	Adjust map[DeviceID]DeviceAdjustment `json:"adjust,omitempty"`
+}
+
+// StatePhase is a set of device states that are all set at the same time.
+type StatePhase struct {
+	// Delay is how long to wait after the previous phase has finished before starting this phase.
+	Delay Duration `json:"delay,omitempty"`
+
+	Devices map[DeviceID]DeviceState `json:"devices"`
+
+	// Adjust changes devices relative to their current state. Adjustments are applied before Devices.
+	Adjust map[DeviceID]DeviceAdjustment `json:"adjust,omitempty"`
+}
+
+// DeviceAdjustment is a change to the state of a device relative to its current state, like pressing a volume up button.
+// Each field is read from the device, changed, and set again while no other adjustment to the same device can run.
+type DeviceAdjustment struct {
+	TogglePower bool `json:"togglePower,omitempty"`
+	ToggleBlank bool `json:"toggleBlank,omitempty"`
+
+	// Volumes changes the volume of each block by the given amount, on the same scale volumes are set on.
+	// Volumes are kept within the range of the block's port, and a change too small to register on the
+	// port still moves the volume one of its steps.
+	Volumes map[string]int `json:"volumes,omitempty"`
+
+	// ToggleMutes are the blocks to mute if they are unmuted, or unmute if they are muted.
+	ToggleMutes []string `json:"toggleMutes,omitempty"`
 }
 
 // StateResponse is the JSON object that the API responds with when getting or setting state
@@ -21,6 +60,8 @@ type DeviceState struct {
 	PoweredOn *bool `json:"poweredOn,omitempty"`
 	Blanked   *bool `json:"blanked,omitempty"`
 
+	// Inputs maps output ports to the inputs they are set to. Requests can use port labels (see PortConfig.Label),
+	// but responses always use the names the driver knows the ports by.
 	Inputs  map[string]Input `json:"inputs,omitempty"`
 	Volumes map[string]int   `json:"volumes,omitempty"`
 	Mutes   map[string]bool  `json:"mutes,omitempty"`
@@ -48,6 +89,81 @@ type DeviceStateError struct {
 
 	// Error is the error that happened.
 	Error string `json:"error"`
+
+	// Code is a machine-readable description of Error. It is one of the ErrorCode constants.
+	Code string `json:"code,omitempty"`
+
+	// Phase is the phase of the StateRequest this error happened in. Errors from
+	// StateRequest.Devices are in phase 0, and errors from StateRequest.Phases[i] are in phase i+1.
+	Phase int `json:"phase,omitempty"`
+}
+
+// ErrorResponse is the JSON object that is returned in the body of an http response when a request fails entirely.
+type ErrorResponse struct {
+	// Error is the error that happened.
+	Error string `json:"error"`
+
+	// Code is a machine-readable description of Error. It is one of the ErrorCode constants.
+	Code string `json:"code"`
+}
+
+// Machine-readable error codes, used in DeviceStateError.Code and ErrorResponse.Code.
+const (
+	ErrorCodeNotCapable          = "not_capable"
+	ErrorCodeInvalidBlock        = "invalid_block"
+	ErrorCodeInvalidPort         = "invalid_port"
+	ErrorCodeInvalidDevice       = "invalid_device"
+	ErrorCodeInvalidScale        = "invalid_scale"
+	ErrorCodeOutOfRange          = "out_of_range"
+	ErrorCodeDriverNotRegistered = "driver_not_registered"
+	ErrorCodeConfigNotSupported  = "config_not_supported"
+	ErrorCodePhaseSkipped        = "phase_skipped"
+	ErrorCodeNoDevices           = "no_devices"
+	ErrorCodeTimeout             = "timeout"
+	ErrorCodeCanceled            = "canceled"
+	ErrorCodeUnreachable         = "unreachable"
+
+	// ErrorCodeDeviceError is used for errors returned by a device that don't have a more specific code.
+	ErrorCodeDeviceError = "device_error"
+
+	// These codes are only used in ErrorResponse.
+	ErrorCodeBadRequest     = "bad_request"
+	ErrorCodeUnauthorized   = "unauthorized"
+	ErrorCodeForbidden      = "forbidden"
+	ErrorCodeNotFound       = "not_found"
+	ErrorCodeInvalidRoute   = "invalid_route"
+	ErrorCodeNoPath         = "no_path"
+	ErrorCodeNotImplemented = "not_implemented"
+	ErrorCodeInternal       = "internal"
+)
+
+// RouteRequest is the JSON object that a consumer of the av-control-api sends in a PUT request
+// to route a source to a destination, across the connections in the room.
+type RouteRequest struct {
+	Source      DeviceID `json:"source"`
+	Destination DeviceID `json:"destination"`
+
+	// Output is the output on the destination to route the source to. It is empty for displays.
+	Output string `json:"output,omitempty"`
+
+	// Kind is the kind of signal to route (see PortKindAudio, PortKindVideo, and PortKindAudioVideo).
+	// It defaults to PortKindAudioVideo.
+	Kind string `json:"kind,omitempty"`
+}
+
+// RoomRoutes maps the id of each display in a room to the source it is showing. Displays are the devices
+// in the room that connections go to, but not from. Errors are the devices whose state couldn't be read,
+// which may make some sources wrong or missing.
+type RoomRoutes struct {
+	Displays map[DeviceID]DisplayRoute `json:"displays,omitempty"`
+	Errors   []DeviceStateError        `json:"errors,omitempty"`
+}
+
+// DisplayRoute is the source a display is showing, found by following its current inputs back
+// through the connections in the room.
+type DisplayRoute struct {
+	Source DeviceID `json:"source,omitempty"`
+	Error  *string  `json:"error,omitempty"`
 }
 
 // RoomHealth maps device id to device health status for each device in the room.
@@ -71,6 +187,17 @@ type DeviceInfo struct {
 	Error *string     `json:"error,omitempty"`
 }
 
+// RoomCapabilities maps device id to the capabilities of each device in the room.
+type RoomCapabilities struct {
+	Devices map[DeviceID]DeviceCapabilities `json:"devices,omitempty"`
+}
+
+// DeviceCapabilities lists what a device (or every device created by a driver) can do. See Capabilities.
+type DeviceCapabilities struct {
+	Capabilities []string `json:"capabilities"`
+	Error        *string  `json:"error,omitempty"`
+}
+
 // DeviceID is a string in the format of Building-Room-DeviceName
 type DeviceID string
 
@@ -83,3 +210,27 @@ func (id DeviceID) Room() string {
 
 	return split[0] + "-" + split[1]
 }
+
+// Duration is a time.Duration that is represented in JSON as a string, like "1.5s" or "300ms".
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var str string
+	if err := json.Unmarshal(b, &str); err != nil {
+		return fmt.Errorf("duration must be a string: %w", err)
+	}
+
+	dur, err := time.ParseDuration(str)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(dur)
+	return nil
+}
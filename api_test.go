@@ -1,6 +1,10 @@
 package avcontrol
 
-import "testing"
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
 
 var deviceIDTests = []struct {
 	id   string
@@ -21,3 +25,27 @@ func TestDeviceID(t *testing.T) {
 		}
 	}
 }
+
+func TestDurationJSON(t *testing.T) {
+	var phase StatePhase
+	if err := json.Unmarshal([]byte(`{"delay": "1.5s"}`), &phase); err != nil {
+		t.Fatalf("unable to unmarshal: %s", err)
+	}
+
+	if time.Duration(phase.Delay) != 1500*time.Millisecond {
+		t.Fatalf("expected 1.5s, got %s", time.Duration(phase.Delay))
+	}
+
+	b, err := json.Marshal(phase.Delay)
+	if err != nil {
+		t.Fatalf("unable to marshal: %s", err)
+	}
+
+	if string(b) != `"1.5s"` {
+		t.Fatalf("expected %q, got %q", `"1.5s"`, b)
+	}
+
+	if err := json.Unmarshal([]byte(`{"delay": 15}`), &phase); err == nil {
+		t.Fatalf("expected an error unmarshaling a number")
+	}
+}
@@ -0,0 +1,107 @@
+package avcontrol
+
+import (
+	"context"
+	"time"
+)
+
+// AuditStore stores a record of every change that is requested to the state of a room.
+type AuditStore interface {
+	// Record stores the given AuditRecord.
+	Record(context.Context, AuditRecord) error
+
+	// History returns the records that match the given AuditQuery, oldest first.
+	// If there are more than AuditQuery.Limit matching records, the most recent ones are returned.
+	History(context.Context, AuditQuery) ([]AuditRecord, error)
+}
+
+// AuditRecord is a record of a single request to set the state of a room.
+type AuditRecord struct {
+	// Time is when the request was finished.
+	Time time.Time `json:"time"`
+
+	// RequestID is the ID of the request, from CtxRequestID.
+	RequestID string `json:"requestID,omitempty"`
+
+	// ClientIP is the IP address of the client that made the request.
+	ClientIP string `json:"clientIP,omitempty"`
+
+	// Principal is the name of the authenticated principal that made the request.
+	Principal string `json:"principal,omitempty"`
+
+	// Room is the ID of the room the request was for.
+	Room string `json:"room"`
+
+	// Scene is the name of the scene that was applied, if the request was to apply a scene.
+	Scene string `json:"scene,omitempty"`
+
+	// Schedule is the ID of the schedule that made the request, if it was made by a schedule.
+	Schedule string `json:"schedule,omitempty"`
+
+	// Request is the state that was requested.
+	Request StateRequest `json:"request"`
+
+	// Response is the state that was set, along with any errors setting it.
+	Response StateResponse `json:"response"`
+
+	// Error is set if the request failed entirely.
+	Error string `json:"error,omitempty"`
+}
+
+// AuditQuery is used to filter the records returned by AuditStore.History.
+type AuditQuery struct {
+	// Room is the ID of the room to get records for.
+	Room string
+
+	// Since and Until limit the records returned to those that happened between them, inclusive.
+	// The zero value of either one leaves that end of the range open.
+	Since time.Time
+	Until time.Time
+
+	// Before limits the records returned to those that happened before it. Unlike Until, it is exclusive,
+	// so the time of the oldest record returned can be used as Before to get the records before it.
+	Before time.Time
+
+	// Limit is the most records that are returned. If it is zero, every matching record is returned.
+	Limit int
+
+	// Devices limits the records returned to those that included at least one of the given devices.
+	// If it is empty, every record for the room is returned.
+	Devices []DeviceID
+}
+
+// Includes returns true if the record included any of the given devices,
+// either in the request or in the response.
+func (r AuditRecord) Includes(ids ...DeviceID) bool {
+	for _, id := range ids {
+		if _, ok := r.Request.Devices[id]; ok {
+			return true
+		}
+
+		if _, ok := r.Request.Adjust[id]; ok {
+			return true
+		}
+
+		for _, phase := range r.Request.Phases {
+			if _, ok := phase.Devices[id]; ok {
+				return true
+			}
+
+			if _, ok := phase.Adjust[id]; ok {
+				return true
+			}
+		}
+
+		if _, ok := r.Response.Devices[id]; ok {
+			return true
+		}
+
+		for _, err := range r.Response.Errors {
+			if err.ID == id {
+				return true
+			}
+		}
+	}
+
+	return false
+}
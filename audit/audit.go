@@ -0,0 +1,190 @@
+// Package audit stores a durable record of every change requested to the state of a room.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	_ avcontrol.AuditStore = &store{}
+	_ Pruner               = &store{}
+)
+
+// store is an avcontrol.AuditStore backed by bbolt. Each room has its own bucket,
+// and records are keyed by the time they were recorded so that they are stored in order.
+type store struct {
+	db *bolt.DB
+}
+
+// New opens (or creates) the audit log at path and returns an AuditStore backed by it.
+func New(path string) (avcontrol.AuditStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("unable to open audit log: %w", err)
+	}
+
+	return &store{
+		db: db,
+	}, nil
+}
+
+// key returns the key for a record at t. The sequence number keeps records
+// that happen at the same time from overwriting each other.
+func key(t time.Time, seq uint64) []byte {
+	k := make([]byte, 16)
+	binary.BigEndian.PutUint64(k[:8], uint64(t.UnixNano()))
+	binary.BigEndian.PutUint64(k[8:], seq)
+
+	return k
+}
+
+// Record stores rec in the bucket for rec.Room.
+func (s *store) Record(ctx context.Context, rec avcontrol.AuditRecord) error {
+	if rec.Room == "" {
+		return fmt.Errorf("record must have a room")
+	}
+
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+
+	buf, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("unable to marshal record: %w", err)
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(rec.Room))
+		if err != nil {
+			return fmt.Errorf("unable to create bucket: %w", err)
+		}
+
+		seq, err := b.NextSequence()
+		if err != nil {
+			return fmt.Errorf("unable to get sequence: %w", err)
+		}
+
+		return b.Put(key(rec.Time, seq), buf)
+	})
+	if err != nil {
+		return fmt.Errorf("unable to store record: %w", err)
+	}
+
+	return nil
+}
+
+// History returns the records for q.Room that match q, oldest first. Records are read newest
+// first, so that only the most recent q.Limit records are read if there are more than that.
+func (s *store) History(ctx context.Context, q avcontrol.AuditQuery) ([]avcontrol.AuditRecord, error) {
+	var recs []avcontrol.AuditRecord
+
+	// end is the key just after the newest record to return
+	var end []byte
+	if !q.Until.IsZero() {
+		end = key(q.Until.Add(1), 0)
+	}
+
+	if !q.Before.IsZero() && (end == nil || bytes.Compare(key(q.Before, 0), end) < 0) {
+		end = key(q.Before, 0)
+	}
+
+	var since []byte
+	if !q.Since.IsZero() {
+		since = key(q.Since, 0)
+	}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(q.Room))
+		if b == nil {
+			return nil
+		}
+
+		c := b.Cursor()
+
+		k, v := c.Last()
+		if end != nil {
+			// the record before the first key at or after end
+			if k, _ = c.Seek(end); k == nil {
+				k, v = c.Last()
+			} else {
+				k, v = c.Prev()
+			}
+		}
+
+		for ; k != nil; k, v = c.Prev() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if since != nil && bytes.Compare(k, since) < 0 {
+				break
+			}
+
+			var rec avcontrol.AuditRecord
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return fmt.Errorf("unable to unmarshal record: %w", err)
+			}
+
+			if len(q.Devices) > 0 && !rec.Includes(q.Devices...) {
+				continue
+			}
+
+			recs = append(recs, rec)
+			if q.Limit > 0 && len(recs) >= q.Limit {
+				break
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get history: %w", err)
+	}
+
+	// put them back in order
+	for i, j := 0, len(recs)-1; i < j; i, j = i+1, j-1 {
+		recs[i], recs[j] = recs[j], recs[i]
+	}
+
+	return recs, nil
+}
+
+// Prune deletes every record from before the given time, in every room. It returns the number of records deleted.
+func (s *store) Prune(ctx context.Context, before time.Time) (int, error) {
+	var n int
+	end := key(before, 0)
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			c := b.Cursor()
+
+			// deleting moves the cursor to the next key
+			for k, _ := c.First(); k != nil && bytes.Compare(k, end) < 0; k, _ = c.First() {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+
+				if err := c.Delete(); err != nil {
+					return fmt.Errorf("unable to delete record: %w", err)
+				}
+
+				n++
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("unable to prune audit log: %w", err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,208 @@
+package audit
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/matryer/is"
+)
+
+func boolP(b bool) *bool {
+	return &b
+}
+
+func TestHistory(t *testing.T) {
+	is := is.New(t)
+
+	dir, err := ioutil.TempDir("", "av-control-api-audit-test")
+	is.NoErr(err)
+	defer os.RemoveAll(dir)
+
+	s, err := New(filepath.Join(dir, "audit.db"))
+	is.NoErr(err)
+
+	ctx := context.Background()
+	start := time.Date(2020, 6, 1, 9, 0, 0, 0, time.UTC)
+
+	recs := []avcontrol.AuditRecord{
+		{
+			Time:      start,
+			RequestID: "1",
+			Room:      "ITB-1101",
+			Request: avcontrol.StateRequest{
+				Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+					"ITB-1101-D1": {PoweredOn: boolP(true)},
+				},
+			},
+		},
+		{
+			Time:      start.Add(time.Minute),
+			RequestID: "2",
+			Room:      "ITB-1101",
+			Request: avcontrol.StateRequest{
+				Phases: []avcontrol.StatePhase{
+					{
+						Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+							"ITB-1101-D2": {PoweredOn: boolP(true)},
+						},
+					},
+				},
+			},
+		},
+		{
+			Time:      start.Add(2 * time.Minute),
+			RequestID: "3",
+			Room:      "ITB-1101",
+			Response: avcontrol.StateResponse{
+				Errors: []avcontrol.DeviceStateError{
+					{ID: "ITB-1101-D1", Error: "unable to connect"},
+				},
+			},
+		},
+		{
+			Time:      start.Add(time.Minute),
+			RequestID: "4",
+			Room:      "ITB-1102",
+		},
+	}
+
+	for _, rec := range recs {
+		is.NoErr(s.Record(ctx, rec))
+	}
+
+	requestIDs := func(recs []avcontrol.AuditRecord) []string {
+		var ids []string
+		for _, rec := range recs {
+			ids = append(ids, rec.RequestID)
+		}
+
+		return ids
+	}
+
+	tests := []struct {
+		name  string
+		query avcontrol.AuditQuery
+		ids   []string
+	}{
+		{
+			name:  "Room",
+			query: avcontrol.AuditQuery{Room: "ITB-1101"},
+			ids:   []string{"1", "2", "3"},
+		},
+		{
+			name:  "MissingRoom",
+			query: avcontrol.AuditQuery{Room: "ITB-1103"},
+		},
+		{
+			name: "Range",
+			query: avcontrol.AuditQuery{
+				Room:  "ITB-1101",
+				Since: start.Add(time.Minute),
+				Until: start.Add(time.Minute),
+			},
+			ids: []string{"2"},
+		},
+		{
+			name: "Since",
+			query: avcontrol.AuditQuery{
+				Room:  "ITB-1101",
+				Since: start.Add(30 * time.Second),
+			},
+			ids: []string{"2", "3"},
+		},
+		{
+			name: "Devices",
+			query: avcontrol.AuditQuery{
+				Room:    "ITB-1101",
+				Devices: []avcontrol.DeviceID{"ITB-1101-D1"},
+			},
+			ids: []string{"1", "3"},
+		},
+		{
+			name: "Limit",
+			query: avcontrol.AuditQuery{
+				Room:  "ITB-1101",
+				Limit: 2,
+			},
+			ids: []string{"2", "3"},
+		},
+		{
+			name: "Before",
+			query: avcontrol.AuditQuery{
+				Room:   "ITB-1101",
+				Before: start.Add(time.Minute),
+				Limit:  2,
+			},
+			ids: []string{"1"},
+		},
+		{
+			name: "BeforeAndUntil",
+			query: avcontrol.AuditQuery{
+				Room:   "ITB-1101",
+				Until:  start.Add(time.Minute),
+				Before: start.Add(2 * time.Minute),
+			},
+			ids: []string{"1", "2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			history, err := s.History(ctx, tt.query)
+			is.NoErr(err)
+			is.Equal(requestIDs(history), tt.ids)
+		})
+	}
+}
+
+func TestRecordNoRoom(t *testing.T) {
+	is := is.New(t)
+
+	dir, err := ioutil.TempDir("", "av-control-api-audit-test")
+	is.NoErr(err)
+	defer os.RemoveAll(dir)
+
+	s, err := New(filepath.Join(dir, "audit.db"))
+	is.NoErr(err)
+
+	err = s.Record(context.Background(), avcontrol.AuditRecord{})
+	is.True(err != nil)
+}
+
+func TestPrune(t *testing.T) {
+	is := is.New(t)
+
+	dir, err := ioutil.TempDir("", "av-control-api-audit-test")
+	is.NoErr(err)
+	defer os.RemoveAll(dir)
+
+	s, err := New(filepath.Join(dir, "audit.db"))
+	is.NoErr(err)
+
+	ctx := context.Background()
+	start := time.Date(2020, 6, 1, 9, 0, 0, 0, time.UTC)
+
+	for i, room := range []string{"ITB-1101", "ITB-1101", "ITB-1102", "ITB-1101"} {
+		is.NoErr(s.Record(ctx, avcontrol.AuditRecord{Time: start.Add(time.Duration(i) * time.Hour), Room: room}))
+	}
+
+	n, err := s.(Pruner).Prune(ctx, start.Add(2*time.Hour))
+	is.NoErr(err)
+	is.Equal(n, 2)
+
+	history, err := s.History(ctx, avcontrol.AuditQuery{Room: "ITB-1101"})
+	is.NoErr(err)
+	is.Equal(len(history), 1)
+	is.True(history[0].Time.Equal(start.Add(3 * time.Hour)))
+
+	history, err = s.History(ctx, avcontrol.AuditQuery{Room: "ITB-1102"})
+	is.NoErr(err)
+	is.Equal(len(history), 1)
+}
@@ -0,0 +1,26 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"go.uber.org/zap"
+)
+
+// Record stores rec in store, if there is one. setErr is the error returned while setting the state in rec.
+// Failures to store rec are logged instead of returned, so that they don't fail the state change itself.
+func Record(ctx context.Context, log *zap.Logger, store avcontrol.AuditStore, rec avcontrol.AuditRecord, setErr error) {
+	if store == nil {
+		return
+	}
+
+	rec.Time = time.Now()
+	if setErr != nil {
+		rec.Error = setErr.Error()
+	}
+
+	if err := store.Record(ctx, rec); err != nil {
+		log.Warn("unable to record state change in audit log", zap.Error(err))
+	}
+}
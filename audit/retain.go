@@ -0,0 +1,37 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Pruner is implemented by audit logs that can delete old records, like the one returned by New.
+type Pruner interface {
+	// Prune deletes every record from before the given time, and returns the number of records deleted.
+	Prune(context.Context, time.Time) (int, error)
+}
+
+// Retain prunes the records in p that are older than retention, every interval, until ctx is done.
+// Records are pruned once right away.
+func Retain(ctx context.Context, log *zap.Logger, p Pruner, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := p.Prune(ctx, time.Now().Add(-retention))
+		switch {
+		case err != nil:
+			log.Warn("unable to prune audit log", zap.Error(err))
+		case n > 0:
+			log.Info("Pruned audit log", zap.Int("numRecords", n), zap.Duration("retention", retention))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package avcontrol
+
+import (
+	"context"
+	"errors"
+	"path"
+)
+
+// ErrUnauthenticated is returned by an Authenticator when a token is missing or invalid.
+var ErrUnauthenticated = errors.New("invalid or missing token")
+
+// Authenticator authenticates the bearer tokens sent to the API.
+type Authenticator interface {
+	// Authenticate returns the Principal that token belongs to.
+	// If token isn't valid, an error wrapping ErrUnauthenticated is returned.
+	Authenticate(ctx context.Context, token string) (Principal, error)
+}
+
+// Principal is who (or what) a request to the API was made by.
+type Principal struct {
+	// Name identifies the principal in logs and in the audit log.
+	Name string `json:"name"`
+
+	// Rooms is the list of rooms this principal can control. Each entry is a pattern
+	// (see path.Match), so "ITB-*" allows access to every room in the ITB building.
+	Rooms []string `json:"rooms,omitempty"`
+
+	// Admin allows access to every room, along with the debug endpoints.
+	Admin bool `json:"admin,omitempty"`
+}
+
+// CanAccess returns true if p is allowed to control room.
+func (p Principal) CanAccess(room string) bool {
+	if p.Admin {
+		return true
+	}
+
+	for _, pattern := range p.Rooms {
+		if ok, err := path.Match(pattern, room); err == nil && ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,180 @@
+// Package auth authenticates the bearer tokens sent to the API, using static API keys and JWTs.
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/golang-jwt/jwt/v4"
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the configuration for an Authenticator.
+type Config struct {
+	// Keys are static API keys.
+	Keys []Key `yaml:"keys"`
+
+	// JWT configures validation of JWTs. If it is nil, JWTs are not accepted.
+	JWT *JWTConfig `yaml:"jwt"`
+}
+
+// Key is a static API key, and the principal it belongs to.
+type Key struct {
+	Name  string   `yaml:"name"`
+	Key   string   `yaml:"key"`
+	Rooms []string `yaml:"rooms"`
+	Admin bool     `yaml:"admin"`
+}
+
+// JWTConfig configures how JWTs are validated.
+type JWTConfig struct {
+	// JWKS is the path or http(s) URL of the JSON Web Key Set used to verify JWTs.
+	JWKS string `yaml:"jwks"`
+
+	// Issuer and Audience, if set, must match the iss and aud claims of the JWT.
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+
+	// NameClaim, RoomsClaim, and AdminClaim are the claims that hold the principal's
+	// name, list of room patterns, and admin flag. They default to sub, rooms, and admin.
+	NameClaim  string `yaml:"nameClaim"`
+	RoomsClaim string `yaml:"roomsClaim"`
+	AdminClaim string `yaml:"adminClaim"`
+}
+
+type authenticator struct {
+	keys []Key
+	jwt  *JWTConfig
+	jwks *keySet
+}
+
+// New reads the config file at configPath and returns an Authenticator built from it.
+func New(configPath string) (avcontrol.Authenticator, error) {
+	buf, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file: %w", err)
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(buf, &config); err != nil {
+		return nil, fmt.Errorf("unable to parse config: %w", err)
+	}
+
+	return NewWithConfig(config)
+}
+
+// NewWithConfig is like New but expects the already parsed config as an argument.
+func NewWithConfig(config Config) (avcontrol.Authenticator, error) {
+	a := &authenticator{
+		keys: config.Keys,
+		jwt:  config.JWT,
+	}
+
+	for i, key := range a.keys {
+		switch {
+		case key.Name == "":
+			return nil, fmt.Errorf("keys[%d]: must have a name", i)
+		case key.Key == "":
+			return nil, fmt.Errorf("keys[%d]: %s: must have a key", i, key.Name)
+		}
+	}
+
+	if a.jwt != nil {
+		if a.jwt.JWKS == "" {
+			return nil, errors.New("jwt: must have a jwks path or url")
+		}
+
+		if a.jwt.NameClaim == "" {
+			a.jwt.NameClaim = "sub"
+		}
+
+		if a.jwt.RoomsClaim == "" {
+			a.jwt.RoomsClaim = "rooms"
+		}
+
+		if a.jwt.AdminClaim == "" {
+			a.jwt.AdminClaim = "admin"
+		}
+
+		a.jwks = &keySet{
+			source: a.jwt.JWKS,
+		}
+
+		if err := a.jwks.refresh(context.Background()); err != nil {
+			return nil, fmt.Errorf("jwt: unable to load jwks: %w", err)
+		}
+	}
+
+	return a, nil
+}
+
+// Authenticate returns the principal that token belongs to. Static API keys are checked first,
+// then token is validated as a JWT if JWTs are configured.
+func (a *authenticator) Authenticate(ctx context.Context, token string) (avcontrol.Principal, error) {
+	if token == "" {
+		return avcontrol.Principal{}, avcontrol.ErrUnauthenticated
+	}
+
+	for _, key := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(key.Key), []byte(token)) == 1 {
+			return avcontrol.Principal{
+				Name:  key.Name,
+				Rooms: key.Rooms,
+				Admin: key.Admin,
+			}, nil
+		}
+	}
+
+	if a.jwt == nil || strings.Count(token, ".") != 2 {
+		return avcontrol.Principal{}, avcontrol.ErrUnauthenticated
+	}
+
+	return a.authenticateJWT(ctx, token)
+}
+
+func (a *authenticator) authenticateJWT(ctx context.Context, token string) (avcontrol.Principal, error) {
+	claims := jwt.MapClaims{}
+
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}))
+	_, err := parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return a.jwks.key(ctx, kid)
+	})
+	if err != nil {
+		return avcontrol.Principal{}, fmt.Errorf("%w: %s", avcontrol.ErrUnauthenticated, err)
+	}
+
+	if a.jwt.Issuer != "" && !claims.VerifyIssuer(a.jwt.Issuer, true) {
+		return avcontrol.Principal{}, fmt.Errorf("%w: invalid issuer", avcontrol.ErrUnauthenticated)
+	}
+
+	if a.jwt.Audience != "" && !claims.VerifyAudience(a.jwt.Audience, true) {
+		return avcontrol.Principal{}, fmt.Errorf("%w: invalid audience", avcontrol.ErrUnauthenticated)
+	}
+
+	var p avcontrol.Principal
+
+	p.Name, _ = claims[a.jwt.NameClaim].(string)
+	if p.Name == "" {
+		return avcontrol.Principal{}, fmt.Errorf("%w: missing %s claim", avcontrol.ErrUnauthenticated, a.jwt.NameClaim)
+	}
+
+	switch rooms := claims[a.jwt.RoomsClaim].(type) {
+	case string:
+		p.Rooms = strings.Fields(rooms)
+	case []interface{}:
+		for _, room := range rooms {
+			if room, ok := room.(string); ok {
+				p.Rooms = append(p.Rooms, room)
+			}
+		}
+	}
+
+	p.Admin, _ = claims[a.jwt.AdminClaim].(bool)
+	return p, nil
+}
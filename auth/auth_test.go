@@ -0,0 +1,263 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/matryer/is"
+)
+
+func writeJWKS(t *testing.T, dir string, kid string, key *rsa.PublicKey) string {
+	t.Helper()
+
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kid": kid,
+				"kty": "RSA",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			},
+		},
+	}
+
+	buf, err := json.Marshal(jwks)
+	if err != nil {
+		t.Fatalf("unable to marshal jwks: %s", err)
+	}
+
+	path := filepath.Join(dir, "jwks.json")
+	if err := ioutil.WriteFile(path, buf, 0600); err != nil {
+		t.Fatalf("unable to write jwks: %s", err)
+	}
+
+	return path
+}
+
+func TestAuthenticate(t *testing.T) {
+	is := is.New(t)
+
+	dir, err := ioutil.TempDir("", "av-control-api-auth-test")
+	is.NoErr(err)
+	defer os.RemoveAll(dir)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	is.NoErr(err)
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	is.NoErr(err)
+
+	a, err := NewWithConfig(Config{
+		Keys: []Key{
+			{
+				Name:  "scheduler",
+				Key:   "secret",
+				Rooms: []string{"ITB-*"},
+			},
+		},
+		JWT: &JWTConfig{
+			JWKS:     writeJWKS(t, dir, "key1", &key.PublicKey),
+			Issuer:   "https://idp.example.com",
+			Audience: "av-control-api",
+		},
+	})
+	is.NoErr(err)
+
+	sign := func(key *rsa.PrivateKey, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "key1"
+
+		signed, err := token.SignedString(key)
+		is.NoErr(err)
+
+		return signed
+	}
+
+	valid := jwt.MapClaims{
+		"sub":   "jdoe",
+		"iss":   "https://idp.example.com",
+		"aud":   "av-control-api",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"rooms": []string{"JFSB-*", "ITB-1101"},
+	}
+
+	tests := []struct {
+		name      string
+		token     string
+		principal avcontrol.Principal
+		err       bool
+	}{
+		{
+			name:  "StaticKey",
+			token: "secret",
+			principal: avcontrol.Principal{
+				Name:  "scheduler",
+				Rooms: []string{"ITB-*"},
+			},
+		},
+		{
+			name:  "WrongStaticKey",
+			token: "not the secret",
+			err:   true,
+		},
+		{
+			name:  "Empty",
+			token: "",
+			err:   true,
+		},
+		{
+			name:  "JWT",
+			token: sign(key, valid),
+			principal: avcontrol.Principal{
+				Name:  "jdoe",
+				Rooms: []string{"JFSB-*", "ITB-1101"},
+			},
+		},
+		{
+			name:  "WrongSigningKey",
+			token: sign(other, valid),
+			err:   true,
+		},
+		{
+			name: "Expired",
+			token: sign(key, jwt.MapClaims{
+				"sub": "jdoe",
+				"iss": "https://idp.example.com",
+				"aud": "av-control-api",
+				"exp": time.Now().Add(-time.Hour).Unix(),
+			}),
+			err: true,
+		},
+		{
+			name: "WrongAudience",
+			token: sign(key, jwt.MapClaims{
+				"sub": "jdoe",
+				"iss": "https://idp.example.com",
+				"aud": "something-else",
+				"exp": time.Now().Add(time.Hour).Unix(),
+			}),
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			p, err := a.Authenticate(context.Background(), tt.token)
+			if tt.err {
+				is.True(errors.Is(err, avcontrol.ErrUnauthenticated))
+				return
+			}
+
+			is.NoErr(err)
+			is.Equal(p, tt.principal)
+		})
+	}
+}
+
+func TestNewWithConfigInvalid(t *testing.T) {
+	is := is.New(t)
+
+	_, err := NewWithConfig(Config{
+		Keys: []Key{
+			{
+				Name: "scheduler",
+			},
+		},
+	})
+	is.Equal(err.Error(), "keys[0]: scheduler: must have a key")
+
+	_, err = NewWithConfig(Config{
+		JWT: &JWTConfig{},
+	})
+	is.Equal(err.Error(), "jwt: must have a jwks path or url")
+}
+
+func TestKeySetSlowRefresh(t *testing.T) {
+	is := is.New(t)
+
+	dir, err := ioutil.TempDir("", "av-control-api-auth-test")
+	is.NoErr(err)
+	defer os.RemoveAll(dir)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	is.NoErr(err)
+
+	buf, err := ioutil.ReadFile(writeJWKS(t, dir, "key1", &key.PublicKey))
+	is.NoErr(err)
+
+	// every request after the first one waits until release is closed
+	var mu sync.Mutex
+	var requests int
+	fetching := make(chan struct{}, 1)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		first := requests == 1
+		mu.Unlock()
+
+		if !first {
+			fetching <- struct{}{}
+			<-release
+		}
+
+		_, _ = w.Write(buf)
+	}))
+	defer srv.Close()
+
+	s := &keySet{source: srv.URL}
+	is.NoErr(s.refresh(context.Background()))
+
+	s.mu.Lock()
+	s.refreshed = time.Time{}
+	s.mu.Unlock()
+
+	// unknown key ids reload the key set
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := s.key(context.Background(), "key2")
+			errs <- err
+		}()
+	}
+
+	select {
+	case <-fetching:
+	case <-time.After(time.Second):
+		t.Fatalf("key set was not reloaded")
+	}
+
+	// known keys are still served while it is being reloaded
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	got, err := s.key(ctx, "key1")
+	is.NoErr(err)
+	is.Equal(got.(*rsa.PublicKey).N, key.PublicKey.N)
+
+	close(release)
+	is.True(<-errs != nil)
+	is.True(<-errs != nil)
+
+	mu.Lock()
+	defer mu.Unlock()
+	is.Equal(requests, 2) // the unknown key ids only caused one reload
+}
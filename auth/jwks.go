@@ -0,0 +1,248 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// _minRefreshInterval limits how often an unknown key id causes the jwks to be reloaded.
+const _minRefreshInterval = time.Minute
+
+// keySet is a JSON Web Key Set, loaded from a file or url. The keys it has are still used while it is
+// being reloaded; mu is only held to read or replace them, never while the key set is being fetched.
+type keySet struct {
+	source string
+
+	keys      map[string]crypto.PublicKey
+	refreshed time.Time
+	mu        sync.Mutex
+
+	// single makes concurrent reloads share one fetch
+	single singleflight.Group
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// key returns the public key with the given id, reloading the key set if it isn't found.
+// If kid is empty and there is only one key in the set, that key is returned.
+func (s *keySet) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	key, ok := s.lookup(kid)
+	recent := time.Since(s.refreshed) < _minRefreshInterval
+	s.mu.Unlock()
+
+	switch {
+	case ok:
+		return key, nil
+	case recent:
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	key, ok = s.lookup(kid)
+	s.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	return key, nil
+}
+
+// lookup returns the key with the given id. s.mu must be held.
+func (s *keySet) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// refresh reloads the key set from its source. Calls made while a reload is in progress wait for
+// that reload instead of starting another one. The reload isn't canceled if ctx is.
+func (s *keySet) refresh(ctx context.Context) error {
+	ch := s.single.DoChan("", func() (interface{}, error) {
+		return nil, s.load(context.Background())
+	})
+
+	select {
+	case res := <-ch:
+		return res.Err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// load reloads the key set from its source, and replaces the keys in s once it has been read.
+func (s *keySet) load(ctx context.Context) error {
+	s.mu.Lock()
+	s.refreshed = time.Now()
+	s.mu.Unlock()
+
+	buf, err := s.read(ctx)
+	if err != nil {
+		return err
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	if err := json.Unmarshal(buf, &set); err != nil {
+		return fmt.Errorf("unable to parse jwks: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			return fmt.Errorf("key %q: %w", k.Kid, err)
+		}
+
+		keys[k.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return errors.New("jwks has no signing keys")
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *keySet) read(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(s.source, "http://") && !strings.HasPrefix(s.source, "https://") {
+		buf, err := ioutil.ReadFile(s.source)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read jwks: %w", err)
+		}
+
+		return buf, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build jwks request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("unable to get jwks: %s", resp.Status)
+	}
+
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read jwks: %w", err)
+	}
+
+	return buf, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	decode := func(name, s string) (*big.Int, error) {
+		buf, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", name, err)
+		}
+
+		return new(big.Int).SetBytes(buf), nil
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decode("n", k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decode("e", k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{
+			N: n,
+			E: int(e.Int64()),
+		}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := decode("x", k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decode("y", k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{
+			Curve: curve,
+			X:     x,
+			Y:     y,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
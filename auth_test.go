@@ -0,0 +1,24 @@
+package avcontrol
+
+import "testing"
+
+var canAccessTests = []struct {
+	principal Principal
+	room      string
+	ok        bool
+}{
+	{Principal{Rooms: []string{"ITB-*"}}, "ITB-1101", true},
+	{Principal{Rooms: []string{"ITB-*"}}, "JFSB-B100", false},
+	{Principal{Rooms: []string{"ITB-1101", "JFSB-*"}}, "JFSB-B100", true},
+	{Principal{Rooms: []string{"ITB-1101"}}, "ITB-1108", false},
+	{Principal{Admin: true}, "JFSB-B100", true},
+	{Principal{}, "ITB-1101", false},
+}
+
+func TestPrincipalCanAccess(t *testing.T) {
+	for _, tt := range canAccessTests {
+		if got := tt.principal.CanAccess(tt.room); got != tt.ok {
+			t.Errorf("(%+v).CanAccess(%q): expected %v, got %v", tt.principal, tt.room, tt.ok, got)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	avcontrol "github.com/byuoitav/av-control-api"
@@ -47,6 +48,10 @@ func (d *dataService) RoomConfig(ctx context.Context, id string) (avcontrol.Room
 	if err != nil {
 		config, cacheErr := d.roomConfigFromCache(ctx, id)
 		if cacheErr != nil {
+			if errors.Is(err, avcontrol.ErrRoomNotFound) {
+				return avcontrol.RoomConfig{}, err
+			}
+
 			return avcontrol.RoomConfig{}, fmt.Errorf("unable to get config from cache: %v", cacheErr)
 		}
 
@@ -6,6 +6,11 @@ import (
 
 	avcontrol "github.com/byuoitav/av-control-api"
 	"github.com/byuoitav/av-control-api/couch"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -69,3 +74,25 @@ func logger(logLevel string) (zap.Config, *zap.Logger) {
 
 	return config, log
 }
+
+// tracerProvider sets up the global tracer provider to export spans to the
+// OTLP/HTTP collector at endpoint.
+func tracerProvider(ctx context.Context, endpoint, host string) *sdktrace.TracerProvider {
+	exp, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		panic(fmt.Sprintf("unable to create trace exporter: %s", err))
+	}
+
+	res := resource.NewSchemaless(
+		attribute.String("service.name", "av-control-api"),
+		attribute.String("host.name", host),
+	)
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+	)
+
+	otel.SetTracerProvider(tp)
+	return tp
+}
@@ -5,16 +5,26 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"os"
 	"time"
 
 	"net/http"
 
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/byuoitav/av-control-api/audit"
+	"github.com/byuoitav/av-control-api/auth"
 	"github.com/byuoitav/av-control-api/cache"
 	"github.com/byuoitav/av-control-api/drivers"
+	"github.com/byuoitav/av-control-api/events"
 	"github.com/byuoitav/av-control-api/handlers"
+	"github.com/byuoitav/av-control-api/health"
+	"github.com/byuoitav/av-control-api/schedule"
 	"github.com/byuoitav/av-control-api/state"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/pflag"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -27,12 +37,32 @@ type dataServiceConfig struct {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(validateRooms(os.Args[2:]))
+	}
+
 	var (
 		port             int
 		logLevel         string
 		host             string
 		driverConfigPath string
 		cachePath        string
+		pollInterval     time.Duration
+		cacheState       bool
+		strictDrivers    bool
+		auditPath        string
+		auditRetention   time.Duration
+		schedulePath     string
+		healthRooms      []string
+		healthInterval   time.Duration
+		healthDebounce   time.Duration
+		healthWebhooks   []string
+		eventWebhooks    []string
+		eventSecret      string
+		eventPath        string
+		authConfigPath   string
+		otlpEndpoint     string
+		watchConfig      bool
 
 		dataServiceConfig dataServiceConfig
 	)
@@ -41,11 +71,27 @@ func main() {
 	pflag.StringVarP(&logLevel, "log-level", "L", "", "level to log at. refer to https://godoc.org/go.uber.org/zap/zapcore#Level for options")
 	pflag.StringVarP(&host, "host", "h", "", "host of this server. necessary to proxy requests")
 	pflag.StringVarP(&driverConfigPath, "driver-config", "c", "driver-config.yaml", "path to the driver config file")
+	pflag.BoolVar(&watchConfig, "watch-driver-config", true, "reload the driver config file when it changes. it is always reloaded on SIGHUP")
 	pflag.StringVar(&dataServiceConfig.Addr, "db-address", "", "database address")
 	pflag.StringVar(&dataServiceConfig.Username, "db-username", "", "database username")
 	pflag.StringVar(&dataServiceConfig.Password, "db-password", "", "database password")
 	pflag.BoolVar(&dataServiceConfig.Insecure, "db-insecure", false, "don't use SSL in database connection")
 	pflag.StringVar(&cachePath, "cache-path", "", "path to file for config caching")
+	pflag.BoolVar(&cacheState, "cache-state", false, "cache the last known state of each device, allowing clients to request cached state with ?maxAge")
+	pflag.BoolVar(&strictDrivers, "strict-drivers", false, "fail requests for the whole room if any of its devices' drivers aren't registered, instead of reporting an error for just those devices")
+	pflag.DurationVar(&pollInterval, "poll-interval", 5*time.Second, "how often to check for state changes in rooms being streamed")
+	pflag.StringVar(&auditPath, "audit-path", "", "path to file for the audit log of state changes. if empty, state changes aren't recorded")
+	pflag.DurationVar(&auditRetention, "audit-retention", 90*24*time.Hour, "how long to keep records in the audit log. if zero, records are kept forever")
+	pflag.StringVar(&schedulePath, "schedule-path", "", "path to file for storing schedules for the rooms this instance controls. if empty, schedules can't be created and aren't run")
+	pflag.StringSliceVar(&healthRooms, "health-rooms", nil, "rooms to check the health of in the background. if empty, health isn't monitored")
+	pflag.DurationVar(&healthInterval, "health-interval", time.Minute, "how often to check the health of --health-rooms")
+	pflag.DurationVar(&healthDebounce, "health-debounce", 2*time.Minute, "how long a device must keep its new health before a notification is sent")
+	pflag.StringSliceVar(&healthWebhooks, "health-webhook", nil, "url to POST changes to device health to. can be repeated")
+	pflag.StringSliceVar(&eventWebhooks, "event-webhook", nil, "url to POST each change to device state to. can be repeated")
+	pflag.StringVar(&eventSecret, "event-webhook-secret", "", "secret used to sign the requests sent to --event-webhook")
+	pflag.StringVar(&eventPath, "event-path", "", "path to file to append each change to device state to, as newline-delimited JSON")
+	pflag.StringVar(&authConfigPath, "auth-config", "", "path to the auth config file. if empty, requests aren't authenticated")
+	pflag.StringVar(&otlpEndpoint, "otlp-endpoint", "", "url of the OTLP/HTTP collector to send traces to (e.g. http://localhost:4318). if empty, traces aren't exported")
 	pflag.Parse()
 
 	// build a logger
@@ -57,6 +103,19 @@ func main() {
 		log.Fatal("--host is required. use --help for more details")
 	}
 
+	// propagate trace context even if traces aren't exported, so that proxied requests stay in the caller's trace
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if otlpEndpoint != "" {
+		tp := tracerProvider(context.Background(), otlpEndpoint, host)
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			_ = tp.Shutdown(ctx)
+		}()
+	}
+
 	// build the driver registry
 	registry, err := drivers.New(driverConfigPath)
 	if err != nil {
@@ -66,6 +125,16 @@ func main() {
 
 	log.Info("Registered drivers", zap.Strings("drivers", registry.List()))
 
+	if reloader, ok := registry.(avcontrol.DriverRegistryWithReload); ok {
+		reloadOnSignal(reloader, log)
+
+		if watchConfig {
+			if err := reloadOnChange(reloader, driverConfigPath, log); err != nil {
+				log.Warn("unable to watch driver config; it will only be reloaded on SIGHUP", zap.Error(err))
+			}
+		}
+	}
+
 	// ctx for setup
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -86,26 +155,124 @@ func main() {
 	gs := &state.GetSetter{
 		Logger:         log,
 		DriverRegistry: registry,
+		StrictDrivers:  strictDrivers,
+		PollInterval:   pollInterval,
+	}
+
+	if cacheState {
+		gs.Cache = state.NewCache()
+	}
+
+	var sinks events.Sinks
+	for _, url := range eventWebhooks {
+		sinks = append(sinks, &events.Webhook{
+			URL:     url,
+			Secret:  eventSecret,
+			Retries: 5,
+		})
+	}
+
+	if eventPath != "" {
+		file, err := events.NewFile(eventPath)
+		if err != nil {
+			log.Fatal("unable to open event file", zap.Error(err))
+		}
+		defer file.Close()
+
+		sinks = append(sinks, file)
+	}
+
+	if len(sinks) > 0 {
+		gs.Events = sinks
 	}
 
 	// build http stuff
 	handlers := handlers.Handlers{
-		Host:        host,
-		DataService: ds,
-		Logger:      log,
-		State:       gs,
+		Host:           host,
+		DataService:    ds,
+		Logger:         log,
+		State:          gs,
+		DriverRegistry: registry,
+	}
+
+	if auditPath != "" {
+		handlers.Audit, err = audit.New(auditPath)
+		if err != nil {
+			log.Fatal("unable to open audit log", zap.Error(err))
+		}
+
+		if auditRetention > 0 {
+			go audit.Retain(context.Background(), log, handlers.Audit.(audit.Pruner), auditRetention, time.Hour)
+		}
+	}
+
+	if authConfigPath != "" {
+		handlers.Auth, err = auth.New(authConfigPath)
+		if err != nil {
+			log.Fatal("unable to create authenticator", zap.Error(err))
+		}
+	} else {
+		log.Warn("No auth config given; requests will not be authenticated")
+	}
+
+	if schedulePath != "" {
+		store, err := schedule.NewStore(schedulePath)
+		if err != nil {
+			log.Fatal("unable to open schedule store", zap.Error(err))
+		}
+
+		scheduler := &schedule.Scheduler{
+			Host:        host,
+			DataService: ds,
+			State:       gs,
+			Store:       store,
+			Audit:       handlers.Audit,
+			Logger:      log,
+		}
+
+		if err := scheduler.Start(context.Background()); err != nil {
+			log.Fatal("unable to start scheduler", zap.Error(err))
+		}
+
+		handlers.Schedules = scheduler
+	}
+
+	if len(healthRooms) > 0 {
+		monitor := &health.Monitor{
+			Host:        host,
+			DataService: ds,
+			State:       gs,
+			Logger:      log,
+			Rooms:       healthRooms,
+			Interval:    healthInterval,
+			Debounce:    healthDebounce,
+			Notifiers: []avcontrol.HealthNotifier{
+				&health.LogNotifier{Logger: log},
+			},
+		}
+
+		for _, url := range healthWebhooks {
+			monitor.Notifiers = append(monitor.Notifiers, &health.WebhookNotifier{URL: url})
+		}
+
+		go monitor.Run(context.Background())
+		handlers.HealthMonitor = monitor
 	}
 
-	// TODO add auth
 	r := gin.New()
 	r.Use(gin.Recovery())
 
-	debug := r.Group("/debug")
-	debug.GET("/healthz", func(c *gin.Context) {
+	r.GET("/debug/healthz", func(c *gin.Context) {
 		c.String(http.StatusOK, "healthy")
 	})
-	debug.GET("/statz", handlers.Stats)
+
+	r.GET("/metrics", handlers.RequestID, handlers.Authenticate, handlers.RequireAdmin, gin.WrapH(promhttp.Handler()))
+
+	debug := r.Group("/debug", handlers.RequestID, handlers.Authenticate, handlers.RequireAdmin)
 	debug.GET("/infoz", handlers.Info)
+	debug.GET("/devices", handlers.GetCachedDevices)
+	debug.DELETE("/devices", handlers.EvictCachedDevices)
+	debug.POST("/drivers/reload", handlers.ReloadDrivers)
 	debug.GET("/logz", func(c *gin.Context) {
 		c.String(http.StatusOK, config.Level.String())
 	})
@@ -121,14 +288,33 @@ func main() {
 		c.String(http.StatusOK, config.Level.String())
 	})
 
-	api := r.Group("/api/v1", handlers.RequestID, handlers.Log)
+	api := r.Group("/api/v1", handlers.Trace, handlers.RequestID, handlers.Log, handlers.Metrics, handlers.Authenticate)
+	api.GET("/drivers", handlers.GetDrivers)
+	api.POST("/room/validate", handlers.ValidateRoom)
+
+	api.GET("/health/summary", handlers.GetHealthSummary)
+
+	schedules := api.Group("/schedules", handlers.RequireAdmin)
+	schedules.GET("", handlers.GetSchedules)
+	schedules.POST("", handlers.CreateSchedule)
+	schedules.GET("/:schedule", handlers.GetSchedule)
+	schedules.PUT("/:schedule", handlers.PutSchedule)
+	schedules.DELETE("/:schedule", handlers.DeleteSchedule)
+
+	// streams are proxied without a timeout
+	api.GET("/room/:room/state/stream", handlers.Authorize, handlers.Room, handlers.ProxyStream, handlers.StreamRoomState)
 
-	room := api.Group("/room", handlers.Room, handlers.Proxy)
+	room := api.Group("/room", handlers.Authorize, handlers.Room, handlers.Proxy)
 	room.GET("/:room", handlers.GetRoomConfiguration)
 	room.GET("/:room/state", handlers.GetRoomState)
 	room.GET("/:room/health", handlers.GetRoomHealth)
 	room.GET("/:room/info", handlers.GetRoomInfo)
+	room.GET("/:room/capabilities", handlers.GetRoomCapabilities)
+	room.GET("/:room/route", handlers.GetRoomRoutes)
+	room.GET("/:room/history", handlers.GetRoomHistory)
 	room.PUT("/:room/state", handlers.SetRoomState)
+	room.PUT("/:room/scene/:scene", handlers.SetRoomScene)
+	room.PUT("/:room/route", handlers.SetRoomRoute)
 
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
 	if err != nil {
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// editors and config map updates write the file more than once; wait for them to finish before reloading
+const _reloadDebounce = 500 * time.Millisecond
+
+func reloadDrivers(registry avcontrol.DriverRegistryWithReload, log *zap.Logger) {
+	reloaded, err := registry.Reload()
+	if err != nil {
+		log.Warn("unable to reload drivers", zap.Error(err))
+	}
+
+	log.Info("Reloaded driver config", zap.Strings("changed", reloaded))
+}
+
+// reloadOnSignal reloads the driver config every time the process gets a SIGHUP.
+func reloadOnSignal(registry avcontrol.DriverRegistryWithReload, log *zap.Logger) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP)
+
+	go func() {
+		for range sigs {
+			log.Info("Got SIGHUP, reloading driver config")
+			reloadDrivers(registry, log)
+		}
+	}()
+}
+
+// reloadOnChange reloads the driver config every time the file at path changes.
+// The directory is watched rather than the file so that files replaced by a rename
+// (like kubernetes config maps) are still picked up.
+func reloadOnChange(registry avcontrol.DriverRegistryWithReload, path string, log *zap.Logger) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	dir, file := filepath.Split(filepath.Clean(path))
+	if dir == "" {
+		dir = "."
+	}
+
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		var debounce <-chan time.Time
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				name := filepath.Base(event.Name)
+				if name != file && name != "..data" {
+					continue
+				}
+
+				debounce = time.After(_reloadDebounce)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+
+				log.Warn("error watching driver config", zap.Error(err))
+			case <-debounce:
+				debounce = nil
+
+				log.Info("Driver config changed, reloading")
+				reloadDrivers(registry, log)
+			}
+		}
+	}()
+
+	return nil
+}
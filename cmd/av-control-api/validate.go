@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/byuoitav/av-control-api/validate"
+	"github.com/spf13/pflag"
+	"go.uber.org/zap"
+)
+
+// driverNames is a DriverRegistry that only keeps track of the names drivers are registered with,
+// so that room configs can be validated without a driver config.
+type driverNames map[string]avcontrol.Driver
+
+func (d driverNames) Register(name string, driver avcontrol.Driver) error {
+	if _, ok := d[name]; ok {
+		return fmt.Errorf("%s already registered", name)
+	}
+
+	d[name] = driver
+	return nil
+}
+
+func (d driverNames) MustRegister(name string, driver avcontrol.Driver) {
+	if err := d.Register(name, driver); err != nil {
+		panic(err)
+	}
+}
+
+func (d driverNames) Get(name string) avcontrol.Driver {
+	return d[name]
+}
+
+func (d driverNames) List() []string {
+	var names []string
+	for name := range d {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// validateRooms runs the validate subcommand, which checks the room config in each file in args
+// (or stdin, if the file is "-") against the drivers this binary registers. Every problem found is
+// printed, and the returned exit code is non-zero if there were any.
+func validateRooms(args []string) int {
+	flags := pflag.NewFlagSet("validate", pflag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s validate FILE...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	names := make(driverNames)
+	registerDrivers(names, zap.NewNop())
+
+	code := 0
+	for _, path := range flags.Args() {
+		problems, err := validateFile(path, names.List())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+			code = 1
+			continue
+		}
+
+		for _, problem := range problems {
+			fmt.Printf("%s: %s\n", path, problem)
+		}
+
+		if len(problems) > 0 {
+			code = 1
+		}
+	}
+
+	return code
+}
+
+func validateFile(path string, drivers []string) ([]validate.Problem, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open: %w", err)
+		}
+		defer f.Close()
+
+		r = f
+	}
+
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read: %w", err)
+	}
+
+	var room validate.Room
+	if err := json.Unmarshal(buf, &room); err != nil {
+		return nil, fmt.Errorf("unable to parse room config: %w", err)
+	}
+
+	return validate.Validate(room, drivers), nil
+}
@@ -2,12 +2,15 @@ package avcontrol
 
 import (
 	"context"
+	"time"
 )
 
 type contextKey int
 
 const (
 	_keyRequestID contextKey = iota
+	_keyMaxAge
+	_keyVolumeScale
 )
 
 // CtxRequestID pulls a request ID from a context.Context.
@@ -24,3 +27,35 @@ func CtxRequestID(ctx context.Context) string {
 func WithRequestID(ctx context.Context, id string) context.Context {
 	return context.WithValue(ctx, _keyRequestID, id)
 }
+
+// CtxMaxAge pulls the maximum age of cached state that is acceptable to use from a context.Context.
+// Returns 0 if no max age has been set.
+func CtxMaxAge(ctx context.Context) time.Duration {
+	maxAge, ok := ctx.Value(_keyMaxAge).(time.Duration)
+	if !ok {
+		return 0
+	}
+
+	return maxAge
+}
+
+// WithMaxAge returns a new context.Context, based on ctx, with the max age set.
+func WithMaxAge(ctx context.Context, maxAge time.Duration) context.Context {
+	return context.WithValue(ctx, _keyMaxAge, maxAge)
+}
+
+// CtxVolumeScale pulls the scale volumes should be in from a context.Context.
+// Returns VolumeScaleNative if no scale has been set.
+func CtxVolumeScale(ctx context.Context) string {
+	scale, ok := ctx.Value(_keyVolumeScale).(string)
+	if !ok || scale == "" {
+		return VolumeScaleNative
+	}
+
+	return scale
+}
+
+// WithVolumeScale returns a new context.Context, based on ctx, with the volume scale set.
+func WithVolumeScale(ctx context.Context, scale string) context.Context {
+	return context.WithValue(ctx, _keyVolumeScale, scale)
+}
@@ -3,6 +3,7 @@ package avcontrol
 import (
 	"context"
 	"testing"
+	"time"
 )
 
 func TestCtxRequestID(t *testing.T) {
@@ -22,3 +23,29 @@ func TestCtxRequestIDEmpty(t *testing.T) {
 		t.Fatalf("expected an empty id, got %q", got)
 	}
 }
+
+func TestCtxMaxAge(t *testing.T) {
+	ctx := WithMaxAge(context.Background(), 5*time.Second)
+	got := CtxMaxAge(ctx)
+	if got != 5*time.Second {
+		t.Fatalf("expected %v, got %v", 5*time.Second, got)
+	}
+}
+
+func TestCtxMaxAgeEmpty(t *testing.T) {
+	got := CtxMaxAge(context.Background())
+	if got != 0 {
+		t.Fatalf("expected no max age, got %v", got)
+	}
+}
+
+func TestCtxVolumeScale(t *testing.T) {
+	if got := CtxVolumeScale(context.Background()); got != VolumeScaleNative {
+		t.Fatalf("expected %q, got %q", VolumeScaleNative, got)
+	}
+
+	ctx := WithVolumeScale(context.Background(), VolumeScalePercent)
+	if got := CtxVolumeScale(ctx); got != VolumeScalePercent {
+		t.Fatalf("expected %q, got %q", VolumeScalePercent, got)
+	}
+}
@@ -2,27 +2,42 @@ package couch
 
 import (
 	"fmt"
+	"net/http"
 	"net/url"
 
 	avcontrol "github.com/byuoitav/av-control-api"
+	kivik "github.com/go-kivik/kivik/v3"
 	"golang.org/x/net/context"
 )
 
 type room struct {
-	ID      string            `json:"_id"`
-	Proxy   string            `json:"proxy"`
-	Devices map[string]device `json:"devices"`
+	ID          string                 `json:"_id"`
+	Proxy       string                 `json:"proxy"`
+	Devices     map[string]device      `json:"devices"`
+	Scenes      map[string]scene       `json:"scenes"`
+	Connections []avcontrol.Connection `json:"connections"`
 }
 
 type device struct {
-	Address string `json:"address"`
-	Driver  string `json:"driver"`
-	Ports   []port `json:"ports"`
+	Address string                 `json:"address"`
+	Driver  string                 `json:"driver"`
+	Ports   []port                 `json:"ports"`
+	Config  map[string]interface{} `json:"config"`
 }
 
 type port struct {
-	Name string `json:"name"`
-	Type string `json:"type"`
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Label string `json:"label"`
+	Kind  string `json:"kind"`
+	Min   int    `json:"min"`
+	Max   int    `json:"max"`
+	Step  int    `json:"step"`
+	Unit  string `json:"unit"`
+}
+
+type scene struct {
+	Devices map[string]avcontrol.DeviceState `json:"devices"`
 }
 
 // RoomConfig gets a room
@@ -31,6 +46,10 @@ func (d *DataService) RoomConfig(ctx context.Context, id string) (avcontrol.Room
 
 	db := d.client.DB(ctx, d.database)
 	if err := db.Get(ctx, id).ScanDoc(&room); err != nil {
+		if kivik.StatusCode(err) == http.StatusNotFound {
+			return avcontrol.RoomConfig{}, fmt.Errorf("%s: %w", id, avcontrol.ErrRoomNotFound)
+		}
+
 		return avcontrol.RoomConfig{}, fmt.Errorf("unable to get/scan room: %w", err)
 	}
 
@@ -44,15 +63,24 @@ func (r room) convert() (avcontrol.RoomConfig, error) {
 	}
 
 	room := avcontrol.RoomConfig{
-		ID:      r.ID,
-		Proxy:   url,
-		Devices: make(map[avcontrol.DeviceID]avcontrol.DeviceConfig),
+		ID:          r.ID,
+		Proxy:       url,
+		Devices:     make(map[avcontrol.DeviceID]avcontrol.DeviceConfig),
+		Connections: r.Connections,
 	}
 
 	for id, dev := range r.Devices {
 		room.Devices[avcontrol.DeviceID(id)] = dev.convert()
 	}
 
+	if len(r.Scenes) > 0 {
+		room.Scenes = make(map[string]avcontrol.StateRequest)
+	}
+
+	for name, scene := range r.Scenes {
+		room.Scenes[name] = scene.convert()
+	}
+
 	return room, nil
 }
 
@@ -60,6 +88,7 @@ func (d device) convert() avcontrol.DeviceConfig {
 	dev := avcontrol.DeviceConfig{
 		Address: d.Address,
 		Driver:  d.Driver,
+		Config:  d.Config,
 	}
 
 	for i := range d.Ports {
@@ -71,7 +100,25 @@ func (d device) convert() avcontrol.DeviceConfig {
 
 func (p port) convert() avcontrol.PortConfig {
 	return avcontrol.PortConfig{
-		Name: p.Name,
-		Type: p.Type,
+		Name:  p.Name,
+		Type:  p.Type,
+		Label: p.Label,
+		Kind:  p.Kind,
+		Min:   p.Min,
+		Max:   p.Max,
+		Step:  p.Step,
+		Unit:  p.Unit,
 	}
 }
+
+func (s scene) convert() avcontrol.StateRequest {
+	req := avcontrol.StateRequest{
+		Devices: make(map[avcontrol.DeviceID]avcontrol.DeviceState),
+	}
+
+	for id, state := range s.Devices {
+		req.Devices[avcontrol.DeviceID(id)] = state
+	}
+
+	return req
+}
@@ -3,10 +3,12 @@ package couch
 import (
 	"context"
 	"errors"
+	"net/http"
 	"net/url"
 	"testing"
 
 	avcontrol "github.com/byuoitav/av-control-api"
+	kivik "github.com/go-kivik/kivik/v3"
 	"github.com/go-kivik/kivikmock/v3"
 	"github.com/matryer/is"
 )
@@ -48,6 +50,18 @@ func TestRoomConfig(t *testing.T) {
 					}
 				]
 			}
+		},
+		"scenes": {
+			"shutdown": {
+				"devices": {
+					"ITB-1101-D1": {
+						"poweredOn": false
+					},
+					"ITB-1101-D2": {
+						"poweredOn": false
+					}
+				}
+			}
 		}
 	}`))
 
@@ -57,6 +71,8 @@ func TestRoomConfig(t *testing.T) {
 	expectedURL, err := url.Parse("http://ITB-1101-CP1.byu.edu:17000")
 	is.NoErr(err)
 
+	poweredOff := false
+
 	is.Equal(room, avcontrol.RoomConfig{
 		ID:    "ITB-1101",
 		Proxy: expectedURL,
@@ -84,6 +100,18 @@ func TestRoomConfig(t *testing.T) {
 				},
 			},
 		},
+		Scenes: map[string]avcontrol.StateRequest{
+			"shutdown": {
+				Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+					"ITB-1101-D1": {
+						PoweredOn: &poweredOff,
+					},
+					"ITB-1101-D2": {
+						PoweredOn: &poweredOff,
+					},
+				},
+			},
+		},
 	})
 }
 
@@ -104,6 +132,23 @@ func TestBadRoom(t *testing.T) {
 	is.Equal(err.Error(), "unable to get/scan room: doc doesn't exist")
 }
 
+func TestRoomNotFound(t *testing.T) {
+	is := is.New(t)
+
+	client, mock, err := kivikmock.New()
+	is.NoErr(err)
+
+	ds, err := NewWithClient(context.Background(), client)
+	is.NoErr(err)
+
+	db := mock.NewDB()
+	mock.ExpectDB().WithName(ds.database).WillReturn(db)
+	db.ExpectGet().WithDocID("ITB-1101").WillReturnError(&kivik.Error{HTTPStatus: http.StatusNotFound, Message: "missing"})
+
+	_, err = ds.RoomConfig(context.Background(), "ITB-1101")
+	is.True(errors.Is(err, avcontrol.ErrRoomNotFound))
+}
+
 func TestRoomConvertFail(t *testing.T) {
 	is := is.New(t)
 
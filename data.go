@@ -2,12 +2,18 @@ package avcontrol
 
 import (
 	"context"
+	"errors"
 	"net/url"
 	"strings"
 )
 
+// ErrRoomNotFound is returned by a DataService when a room doesn't exist.
+var ErrRoomNotFound = errors.New("room not found")
+
 // DataService is used by to get information about rooms.
 type DataService interface {
+	// RoomConfig returns the config of the room id.
+	// If it doesn't exist, an error wrapping ErrRoomNotFound is returned.
 	RoomConfig(ctx context.Context, id string) (RoomConfig, error)
 }
 
@@ -23,6 +29,34 @@ type RoomConfig struct {
 
 	// Devices is map of devices that exist in this room.
 	Devices map[DeviceID]DeviceConfig `json:"devices"`
+
+	// Scenes are named states (e.g. "presentation" or "shutdown") that can be applied to the room.
+	Scenes map[string]StateRequest `json:"scenes,omitempty"`
+
+	// Connections are the cables between devices in the room. They are used to route a source
+	// to a display across every device in between (see RouteRequest).
+	Connections []Connection `json:"connections,omitempty"`
+}
+
+// OwnedBy returns true if the instance of the API at host is the one that controls r, so background work
+// for r (like schedules and health checks) should run there. Rooms that aren't proxied are owned by every
+// instance; if host is empty, those are the only rooms it owns.
+func (r RoomConfig) OwnedBy(host string) bool {
+	return r.Proxy == nil || r.Proxy.Host == "" || strings.EqualFold(host, r.Proxy.Host)
+}
+
+// Connection is a cable from an output on one device to an input on another. The devices don't have to be in
+// RoomConfig.Devices; devices that aren't (like a laptop or a passive extender) are passed through, but never switched.
+type Connection struct {
+	From Endpoint `json:"from"`
+	To   Endpoint `json:"to"`
+}
+
+// Endpoint is a port on a device. The port is a port name or label (see PortConfig), and can be
+// left empty for a device whose only input or output doesn't have a name, like a display.
+type Endpoint struct {
+	Device DeviceID `json:"device"`
+	Port   string   `json:"port,omitempty"`
 }
 
 // DeviceConfig contains information about a given device.
@@ -37,6 +71,12 @@ type DeviceConfig struct {
 	// Ports are logical ports that the API must know about to be able to control. For a DSP,
 	// these are control block names.
 	Ports PortConfigs `json:"ports,omitempty"`
+
+	// Config overrides settings in the driver's config for just this device, like a device specific password.
+	// Its keys are merged over the driver's section of the driver config when the device is created, so the
+	// driver must implement DriverWithDeviceConfig. Unlike the driver config, values that reference files or
+	// environment variables are used as they are, since the API's secrets aren't shared with room configs.
+	Config map[string]interface{} `json:"config,omitempty"`
 }
 
 // PortConfigs is a slice of PortConfigs
@@ -48,11 +88,81 @@ type PortConfig struct {
 	// in a QSC DSP, this is the NamedControl
 	Name string `json:"name"`
 
-	// Type is the type of port this port is. There are two used types right now:
+	// Type is the type of port this port is. The types used right now are:
 	//  - volume
 	//  - mute
-	// Each port is used when getting or setting that field on a device.
+	//  - input
+	//  - output
+	// Volume and mute ports are used when getting or setting that field on a device. Input and output
+	// ports list the inputs and outputs that can be used in DeviceState.Inputs; if a device has
+	// neither, any input or output is passed on to the device.
 	Type string `json:"type"`
+
+	// Label is a friendly name for the port (e.g. "HDMI 1") that can be used in place of Name when setting inputs.
+	// Responses and events always use Name, so that they match the state that is returned by getting the room's state.
+	Label string `json:"label,omitempty"`
+
+	// Kind is the kind of signal an input or output port carries (see PortKindAudio,
+	// PortKindVideo, and PortKindAudioVideo). An empty kind carries any signal.
+	Kind string `json:"kind,omitempty"`
+
+	// Min and Max are the lowest and highest native values a volume port accepts, and Step is the
+	// smallest change it makes (1 if it is zero). Volumes can only be given as a percent if a port has
+	// a range; without one, native volumes are passed to the driver as they are.
+	Min  int `json:"min,omitempty"`
+	Max  int `json:"max,omitempty"`
+	Step int `json:"step,omitempty"`
+
+	// Unit is the unit of a volume port's native values. If it is "dB", native values are already in dB.
+	Unit string `json:"unit,omitempty"`
+
+	// MinDB and MaxDB are the gain in dB at a volume port's Min and Max, for ports whose native values
+	// aren't in dB (e.g. a QSC control's raw gain, or a display's 0-100 volume). Volumes given in dB
+	// are converted linearly between them.
+	MinDB int `json:"minDB,omitempty"`
+	MaxDB int `json:"maxDB,omitempty"`
+}
+
+// The scales a volume can be given in (see WithVolumeScale).
+const (
+	// VolumeScaleNative is the value the driver uses.
+	VolumeScaleNative = "native"
+
+	// VolumeScalePercent is 0 at a port's Min and 100 at its Max.
+	VolumeScalePercent = "percent"
+
+	// VolumeScaleDB is decibels, for ports whose Unit is "dB" or that have a gain range (see PortConfig.MinDB).
+	VolumeScaleDB = "dB"
+)
+
+// HasRange returns true if p has a range of native volumes.
+func (p PortConfig) HasRange() bool {
+	return p.Max > p.Min
+}
+
+// HasGain returns true if p maps its range of native volumes to a range of gain in dB.
+func (p PortConfig) HasGain() bool {
+	return p.HasRange() && p.MaxDB > p.MinDB
+}
+
+// The kinds of signal an input or output port can carry. They match the fields of Input.
+const (
+	PortKindAudio      = "audio"
+	PortKindVideo      = "video"
+	PortKindAudioVideo = "audioVideo"
+)
+
+// Carries returns true if p can carry a signal of kind. A port that carries audio and video carries
+// either one on its own, but an audio or video port can't carry both.
+func (p PortConfig) Carries(kind string) bool {
+	switch p.Kind {
+	case "", kind:
+		return true
+	case PortKindAudioVideo:
+		return kind == PortKindAudio || kind == PortKindVideo
+	default:
+		return false
+	}
 }
 
 // Names returns the list of names of these ports.
@@ -78,3 +188,22 @@ func (p PortConfigs) OfType(typ string) PortConfigs {
 
 	return tp
 }
+
+// Find returns the port of type typ whose Name or Label is name.
+// Names are checked first, so a label can't hide another port's name.
+func (p PortConfigs) Find(typ, name string) (PortConfig, bool) {
+	ports := p.OfType(typ)
+	for i := range ports {
+		if ports[i].Name == name {
+			return ports[i], true
+		}
+	}
+
+	for i := range ports {
+		if ports[i].Label != "" && ports[i].Label == name {
+			return ports[i], true
+		}
+	}
+
+	return PortConfig{}, false
+}
@@ -1,6 +1,7 @@
 package avcontrol
 
 import (
+	"net/url"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -19,10 +20,10 @@ var portNamesTests = []struct {
 	{
 		name: "Normal",
 		ports: PortConfigs{
-			{"1", "audio"},
-			{"2", "video"},
-			{"3", "audioVideo"},
-			{"4", ""},
+			{Name: "1", Type: "audio"},
+			{Name: "2", Type: "video"},
+			{Name: "3", Type: "audioVideo"},
+			{Name: "4", Type: ""},
 		},
 		names: []string{"1", "2", "3", "4"},
 	},
@@ -47,27 +48,27 @@ var portTypeTests = []struct {
 	{
 		typ: "audio",
 		in: PortConfigs{
-			{"1", "audio"},
-			{"2", "video"},
-			{"3", "audioVideo"},
-			{"4", ""},
+			{Name: "1", Type: "audio"},
+			{Name: "2", Type: "video"},
+			{Name: "3", Type: "audioVideo"},
+			{Name: "4", Type: ""},
 		},
 		out: PortConfigs{
-			{"1", "audio"},
-			{"3", "audioVideo"},
+			{Name: "1", Type: "audio"},
+			{Name: "3", Type: "audioVideo"},
 		},
 	},
 	{
 		typ: "video",
 		in: PortConfigs{
-			{"1", "audio"},
-			{"2", "video"},
-			{"3", "audio-video"},
-			{"4", ""},
+			{Name: "1", Type: "audio"},
+			{Name: "2", Type: "video"},
+			{Name: "3", Type: "audio-video"},
+			{Name: "4", Type: ""},
 		},
 		out: PortConfigs{
-			{"2", "video"},
-			{"3", "audio-video"},
+			{Name: "2", Type: "video"},
+			{Name: "3", Type: "audio-video"},
 		},
 	},
 }
@@ -81,3 +82,78 @@ func TestPortTypes(t *testing.T) {
 		})
 	}
 }
+
+func TestPortFind(t *testing.T) {
+	ports := PortConfigs{
+		{Name: "1", Type: "output", Label: "Projector"},
+		{Name: "hdmi1", Type: "input", Label: "HDMI 1"},
+		{Name: "hdmi2", Type: "input", Label: "hdmi1"},
+	}
+
+	tests := []struct {
+		typ  string
+		name string
+		port PortConfig
+		ok   bool
+	}{
+		{typ: "output", name: "1", port: ports[0], ok: true},
+		{typ: "output", name: "Projector", port: ports[0], ok: true},
+		{typ: "input", name: "HDMI 1", port: ports[1], ok: true},
+		{typ: "input", name: "hdmi1", port: ports[1], ok: true},
+		{typ: "input", name: "1"},
+		{typ: "output", name: ""},
+	}
+
+	for _, tt := range tests {
+		port, ok := ports.Find(tt.typ, tt.name)
+		if ok != tt.ok || port != tt.port {
+			t.Errorf("Find(%q, %q): expected %+v, %v; got %+v, %v", tt.typ, tt.name, tt.port, tt.ok, port, ok)
+		}
+	}
+}
+
+func TestPortCarries(t *testing.T) {
+	tests := []struct {
+		port    string
+		kind    string
+		carries bool
+	}{
+		{"", PortKindAudio, true},
+		{"", PortKindAudioVideo, true},
+		{PortKindAudioVideo, PortKindAudio, true},
+		{PortKindAudioVideo, PortKindVideo, true},
+		{PortKindAudioVideo, PortKindAudioVideo, true},
+		{PortKindAudio, PortKindAudio, true},
+		{PortKindAudio, PortKindVideo, false},
+		{PortKindVideo, PortKindAudioVideo, false},
+	}
+
+	for _, tt := range tests {
+		if carries := (PortConfig{Kind: tt.port}).Carries(tt.kind); carries != tt.carries {
+			t.Errorf("(%q).Carries(%q): expected %v, got %v", tt.port, tt.kind, tt.carries, carries)
+		}
+	}
+}
+
+func TestRoomOwnedBy(t *testing.T) {
+	proxied := RoomConfig{ID: "ITB-1101", Proxy: &url.URL{Host: "itb-cp1:8080"}}
+	local := RoomConfig{ID: "JFSB-B104"}
+
+	tests := []struct {
+		room  RoomConfig
+		host  string
+		owned bool
+	}{
+		{proxied, "ITB-CP1:8080", true},
+		{proxied, "itb-cp2:8080", false},
+		{proxied, "", false},
+		{local, "ITB-CP1:8080", true},
+		{local, "", true},
+	}
+
+	for _, tt := range tests {
+		if owned := tt.room.OwnedBy(tt.host); owned != tt.owned {
+			t.Errorf("%s.OwnedBy(%q): expected %v, got %v", tt.room.ID, tt.host, tt.owned, owned)
+		}
+	}
+}
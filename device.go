@@ -2,6 +2,19 @@ package avcontrol
 
 import "context"
 
+// The capabilities a device can have. Each one matches one of the DeviceWith* interfaces.
+const (
+	CapabilityPower           = "power"
+	CapabilityBlank           = "blank"
+	CapabilityAudioInput      = "audioInput"
+	CapabilityVideoInput      = "videoInput"
+	CapabilityAudioVideoInput = "audioVideoInput"
+	CapabilityVolume          = "volume"
+	CapabilityMute            = "mute"
+	CapabilityHealth          = "health"
+	CapabilityInfo            = "info"
+)
+
 type (
 	// Device is the base device interface
 	Device interface{}
@@ -58,3 +71,46 @@ type (
 	}
 	*/
 )
+
+// Capabilities returns the capabilities of dev, based on which DeviceWith* interfaces it implements.
+func Capabilities(dev Device) []string {
+	caps := []string{}
+
+	if _, ok := dev.(DeviceWithPower); ok {
+		caps = append(caps, CapabilityPower)
+	}
+
+	if _, ok := dev.(DeviceWithBlank); ok {
+		caps = append(caps, CapabilityBlank)
+	}
+
+	if _, ok := dev.(DeviceWithAudioInput); ok {
+		caps = append(caps, CapabilityAudioInput)
+	}
+
+	if _, ok := dev.(DeviceWithVideoInput); ok {
+		caps = append(caps, CapabilityVideoInput)
+	}
+
+	if _, ok := dev.(DeviceWithAudioVideoInput); ok {
+		caps = append(caps, CapabilityAudioVideoInput)
+	}
+
+	if _, ok := dev.(DeviceWithVolume); ok {
+		caps = append(caps, CapabilityVolume)
+	}
+
+	if _, ok := dev.(DeviceWithMute); ok {
+		caps = append(caps, CapabilityMute)
+	}
+
+	if _, ok := dev.(DeviceWithHealth); ok {
+		caps = append(caps, CapabilityHealth)
+	}
+
+	if _, ok := dev.(DeviceWithInfo); ok {
+		caps = append(caps, CapabilityInfo)
+	}
+
+	return caps
+}
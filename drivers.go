@@ -2,8 +2,14 @@ package avcontrol
 
 import (
 	"context"
+	"errors"
+	"time"
 )
 
+// ErrDeviceConfigNotSupported is returned when a device has config overrides (see DeviceConfig.Config),
+// but its driver doesn't implement DriverWithDeviceConfig.
+var ErrDeviceConfigNotSupported = errors.New("driver does not support per-device config")
+
 // DriverRegistry is the interface used to register drivers with the api
 type DriverRegistry interface {
 	// Register registers a driver with the given name.
@@ -23,8 +29,129 @@ type Driver interface {
 	// CreateDevice is called whenever the API needs to get or set state on
 	// a device. Addr is the address (IP or hostname) of the device.
 	// See Device for interfaces the returned struct should implement.
+	// It shouldn't connect to the device; drivers that don't implement DriverWithCapabilities
+	// have a device created at an address that doesn't exist to find their capabilities.
 	CreateDevice(ctx context.Context, addr string) (Device, error)
 
 	// ParseConfig is called by the DriverRegistry when a driver is registered.
 	ParseConfig(map[string]interface{}) error
 }
+
+// DriverWithPowerSettle is implemented by drivers whose devices need time to
+// warm up after being powered on before they accept other commands.
+type DriverWithPowerSettle interface {
+	Driver
+
+	// PowerSettle returns how to wait for devices from this driver after they are powered on.
+	PowerSettle() PowerSettle
+}
+
+// PowerSettle describes how to wait for a device to be ready after it is powered on.
+// The zero value means the device is ready as soon as it has been powered on.
+type PowerSettle struct {
+	// Timeout is how long to wait for the device to report that it is powered on.
+	Timeout time.Duration
+
+	// Interval is how often to check the power state of the device while waiting,
+	// and how long to wait between retries.
+	Interval time.Duration
+
+	// Retries is how many times to retry input and blank commands that fail after the
+	// device has been powered on, if it didn't report that it was ready within Timeout.
+	Retries int
+}
+
+// DriverWithLimits is implemented by drivers whose devices can't handle
+// many commands at once, like devices controlled over a single serial or
+// telnet connection.
+type DriverWithLimits interface {
+	Driver
+
+	// Limits returns how commands should be sent to devices from this driver.
+	Limits() Limits
+}
+
+// Limits describes how many commands can be sent to a single device at once.
+// The zero value means the device has no limits.
+type Limits struct {
+	// MaxConcurrent is how many commands can be in progress on the device at once.
+	// Zero means there is no limit. Devices controlled over a single telnet or serial
+	// session should use 1, since they drop or interleave the responses to commands
+	// that overlap, and the response to one command can be read as the response to another.
+	MaxConcurrent int
+
+	// Spacing is the minimum time between the start of one command and the
+	// start of the next, and between the end of one command and the start of the next.
+	Spacing time.Duration
+}
+
+// DriverWithLimiter is implemented by drivers that enforce Limits for their devices.
+// The drivers returned by a DriverRegistry implement it.
+type DriverWithLimiter interface {
+	Driver
+
+	// Acquire waits until a command can be sent to the device at addr. Callers
+	// wait in the order they called Acquire. release must be called once the command
+	// is done. An error is returned if ctx is done before the command can be sent.
+	Acquire(ctx context.Context, addr string) (release func(), err error)
+}
+
+// DriverWithDeviceCache is implemented by drivers that cache the devices they create.
+// The drivers returned by a DriverRegistry implement it.
+type DriverWithDeviceCache interface {
+	Driver
+
+	// CachedDevices returns the devices that are currently cached.
+	CachedDevices() []CachedDevice
+
+	// Evict removes the device at addr from the cache, closing it if it is an io.Closer.
+	// It returns false if the device wasn't cached.
+	Evict(addr string) bool
+
+	// Report is called with the result of each command sent to the device at addr that was created
+	// with overrides (see DriverWithDeviceConfig). Devices that fail too many commands in a row are evicted.
+	Report(addr string, overrides map[string]interface{}, err error)
+}
+
+// CachedDevice describes a device in a driver's cache.
+type CachedDevice struct {
+	Address  string    `json:"address"`
+	Created  time.Time `json:"created"`
+	LastUsed time.Time `json:"lastUsed"`
+
+	// Errors is how many commands in a row have failed on the device.
+	Errors int `json:"errors"`
+}
+
+// DriverRegistryWithReload is implemented by registries that can reload their drivers' configs
+// without restarting. The registry returned by drivers.New implements it.
+type DriverRegistryWithReload interface {
+	DriverRegistry
+
+	// Reload reads the config again and gives each driver whose config changed its new config.
+	// It returns the names of the drivers that were reloaded.
+	Reload() ([]string, error)
+}
+
+// DriverWithDeviceConfig is implemented by drivers that can create devices with settings
+// that are different from the driver's config (see DeviceConfig.Config).
+// The drivers returned by a DriverRegistry implement it.
+type DriverWithDeviceConfig interface {
+	Driver
+
+	// CreateDeviceWithConfig is like CreateDevice, but with config in place of the driver's
+	// config. It must not change the driver's own config. Callers pass the device's overrides;
+	// the drivers returned by a DriverRegistry merge them over the driver's config before
+	// passing them on, so drivers get the complete config for the device.
+	CreateDeviceWithConfig(ctx context.Context, addr string, config map[string]interface{}) (Device, error)
+}
+
+// DriverWithCapabilities is implemented by drivers that can report the capabilities (see Capabilities)
+// of the devices they create without creating one. The drivers returned by a DriverRegistry implement it,
+// and ask the driver they wrap if it implements it too.
+type DriverWithCapabilities interface {
+	Driver
+
+	// Capabilities returns the capabilities of the devices the driver creates.
+	Capabilities(ctx context.Context) ([]string, error)
+}
@@ -2,42 +2,204 @@ package drivers
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
 	sync "sync"
+	"time"
 
 	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"golang.org/x/sync/singleflight"
 )
 
-var _ avcontrol.Driver = &deviceCache{}
+var (
+	cachedDevices = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "avcontrol",
+		Subsystem: "driver",
+		Name:      "cached_devices",
+		Help:      "How many devices each driver has cached.",
+	}, []string{"driver"})
+
+	evictedDevices = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "avcontrol",
+		Subsystem: "driver",
+		Name:      "evicted_devices_total",
+		Help:      "How many devices have been evicted from each driver's cache, by reason.",
+	}, []string{"driver", "reason"})
+)
+
+var (
+	_ avcontrol.DriverWithPowerSettle  = &deviceCache{}
+	_ avcontrol.DriverWithLimits       = &deviceCache{}
+	_ avcontrol.DriverWithLimiter      = &deviceCache{}
+	_ avcontrol.DriverWithDeviceCache  = &deviceCache{}
+	_ avcontrol.DriverWithDeviceConfig = &deviceCache{}
+	_ avcontrol.DriverWithCapabilities = &deviceCache{}
+)
+
+const (
+	_defaultIdleTimeout = time.Hour
+	_defaultMaxErrors   = 5
+
+	// _capabilitiesAddr is the address of the device created to find the capabilities of a driver
+	// that doesn't implement avcontrol.DriverWithCapabilities. .invalid is reserved, so it never
+	// resolves in case the driver tries to connect to it.
+	_capabilitiesAddr = "capabilities.invalid"
+)
+
+// cacheConfig controls when devices are evicted from a deviceCache.
+// A zero value for any field means that devices are never evicted for that reason.
+type cacheConfig struct {
+	// TTL is how long a device is kept after it is created.
+	TTL time.Duration
+
+	// Idle is how long a device is kept after it was last used.
+	Idle time.Duration
+
+	// MaxErrors is how many commands in a row can fail on a device before it is evicted.
+	MaxErrors int
+}
+
+func defaultCacheConfig() cacheConfig {
+	return cacheConfig{
+		Idle:      _defaultIdleTimeout,
+		MaxErrors: _defaultMaxErrors,
+	}
+}
+
+type cachedDevice struct {
+	addr     string
+	dev      avcontrol.Device
+	created  time.Time
+	lastUsed time.Time
+	errors   int
+}
 
 type deviceCache struct {
 	avcontrol.Driver
 
+	// name is the name the driver was registered with
+	name string
+
+	// driverMu is held for writing while the driver's config is reloaded,
+	// so that devices aren't created with a partially updated driver.
+	// config is the config the driver last parsed, with secrets resolved.
+	config   map[string]interface{}
+	opts     options
+	driverMu sync.RWMutex
+
+	// cache is keyed by the address of each device, and a hash of its overrides if it has any
 	single  singleflight.Group
-	cache   map[string]avcontrol.Device
+	cache   map[string]*cachedDevice
 	cacheMu sync.Mutex
+
+	limiters   map[string]*limiter
+	limitersMu sync.Mutex
+
+	// caps are the driver's capabilities, found the first time they are asked for with its current config.
+	// they are only set while driverMu is held for reading, and are cleared when the config is reloaded.
+	// capsSingle is separate from single so that finding them never shares a call with creating a real device.
+	caps       []string
+	capsFound  bool
+	capsMu     sync.Mutex
+	capsSingle singleflight.Group
 }
 
 // CreateDevice gets and returns the device from the cache.
 // If the device is not found in the cache, then a new one is created, added to the cache, and returned.
+// Devices that have expired are evicted first.
 func (c *deviceCache) CreateDevice(ctx context.Context, addr string) (avcontrol.Device, error) {
-	if dev, ok := c.get(addr); ok {
+	return c.create(addr, addr, func() (avcontrol.Device, error) {
+		return c.Driver.CreateDevice(ctx, addr)
+	})
+}
+
+// CreateDeviceWithConfig is like CreateDevice, but the device is created with overrides merged over
+// the driver's config. Overrides are used as they are, without resolving secret references (see resolveSecrets).
+// Devices with different overrides are cached separately, even if they have the same address.
+func (c *deviceCache) CreateDeviceWithConfig(ctx context.Context, addr string, overrides map[string]interface{}) (avcontrol.Device, error) {
+	if len(overrides) == 0 {
+		return c.CreateDevice(ctx, addr)
+	}
+
+	driver, ok := c.Driver.(avcontrol.DriverWithDeviceConfig)
+	if !ok {
+		return nil, avcontrol.ErrDeviceConfigNotSupported
+	}
+
+	key, err := cacheKey(addr, overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.create(key, addr, func() (avcontrol.Device, error) {
+		// overrides come from the room config, so secret references in them are not resolved
+		config := make(map[string]interface{}, len(c.config)+len(overrides))
+		for k, v := range c.config {
+			config[k] = v
+		}
+
+		for k, v := range overrides {
+			config[k] = v
+		}
+
+		return driver.CreateDeviceWithConfig(ctx, addr, config)
+	})
+}
+
+// cacheKey returns the key for the device at addr with overrides. Overrides are hashed so
+// that the secrets in them aren't kept around any longer than they need to be.
+func cacheKey(addr string, overrides map[string]interface{}) (string, error) {
+	// maps are encoded with sorted keys, so equal overrides always encode the same
+	buf, err := json.Marshal(overrides)
+	if err != nil {
+		return "", fmt.Errorf("unable to encode device config: %w", err)
+	}
+
+	sum := sha256.Sum256(buf)
+	return addr + "#" + hex.EncodeToString(sum[:]), nil
+}
+
+// create gets the device with key from the cache, or creates it with create if it isn't cached.
+// create is called with driverMu held for reading.
+func (c *deviceCache) create(key, addr string, create func() (avcontrol.Device, error)) (avcontrol.Device, error) {
+	c.evictExpired()
+
+	if dev, ok := c.get(key); ok {
 		return dev, nil
 	}
 
-	val, err, _ := c.single.Do(addr, func() (interface{}, error) {
-		if dev, ok := c.get(addr); ok {
+	val, err, _ := c.single.Do(key, func() (interface{}, error) {
+		if dev, ok := c.get(key); ok {
 			return dev, nil
 		}
 
-		dev, err := c.Driver.CreateDevice(ctx, addr)
+		// hold the lock until the device is stored, so that a reload can't miss it
+		c.driverMu.RLock()
+		defer c.driverMu.RUnlock()
+
+		dev, err := create()
 		if err != nil {
 			return nil, err
 		}
 
+		now := time.Now()
+
 		c.cacheMu.Lock()
 		defer c.cacheMu.Unlock()
-		c.cache[addr] = dev
+		c.cache[key] = &cachedDevice{
+			addr:     addr,
+			dev:      dev,
+			created:  now,
+			lastUsed: now,
+		}
+		cachedDevices.WithLabelValues(c.name).Set(float64(len(c.cache)))
 
 		return dev, nil
 	})
@@ -48,10 +210,334 @@ func (c *deviceCache) CreateDevice(ctx context.Context, addr string) (avcontrol.
 	return val.(avcontrol.Device), nil
 }
 
-func (c *deviceCache) get(addr string) (avcontrol.Device, bool) {
+func (c *deviceCache) get(key string) (avcontrol.Device, bool) {
 	c.cacheMu.Lock()
 	defer c.cacheMu.Unlock()
 
-	dev, ok := c.cache[addr]
-	return dev, ok
+	cached, ok := c.cache[key]
+	if !ok {
+		return nil, false
+	}
+
+	cached.lastUsed = time.Now()
+	return cached.dev, true
+}
+
+// PowerSettle returns the power settle config for the wrapped driver.
+func (c *deviceCache) PowerSettle() avcontrol.PowerSettle {
+	return c.options().settle
+}
+
+// Limits returns the limits for the wrapped driver's devices.
+func (c *deviceCache) Limits() avcontrol.Limits {
+	return c.options().limits
+}
+
+func (c *deviceCache) options() options {
+	c.driverMu.RLock()
+	defer c.driverMu.RUnlock()
+
+	return c.opts
+}
+
+// Acquire waits until a command can be sent to the device at addr.
+// Each address gets its own limiter, shared by every request for that device.
+func (c *deviceCache) Acquire(ctx context.Context, addr string) (func(), error) {
+	limits := c.Limits()
+	if limits == (avcontrol.Limits{}) {
+		return func() {}, nil
+	}
+
+	c.limitersMu.Lock()
+	l, ok := c.limiters[addr]
+	if !ok {
+		l = newLimiter(limits)
+		c.limiters[addr] = l
+	}
+	l.refs++
+	c.limitersMu.Unlock()
+
+	unref := func() {
+		c.limitersMu.Lock()
+		l.refs--
+		c.limitersMu.Unlock()
+	}
+
+	release, err := l.acquire(ctx)
+	if err != nil {
+		unref()
+		return nil, err
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			release()
+			unref()
+		})
+	}, nil
+}
+
+// pruneLimiter deletes the limiter for addr if there are no devices with addr in the cache,
+// and the limiter isn't being used by any commands.
+func (c *deviceCache) pruneLimiter(addr string) {
+	if len(c.keys(addr)) > 0 {
+		return
+	}
+
+	c.limitersMu.Lock()
+	defer c.limitersMu.Unlock()
+
+	if l, ok := c.limiters[addr]; ok && l.refs == 0 && l.idle() {
+		delete(c.limiters, addr)
+	}
+}
+
+// Capabilities returns the capabilities of the devices the driver creates. If the driver implements
+// avcontrol.DriverWithCapabilities, it is asked for them. Otherwise a device that isn't cached is created
+// at an address that doesn't exist; drivers create the same type of device for every address, so its
+// capabilities are the same as every other device's. They are only found the first time they are asked for,
+// and again after the driver's config is reloaded.
+func (c *deviceCache) Capabilities(ctx context.Context) ([]string, error) {
+	c.driverMu.RLock()
+	defer c.driverMu.RUnlock()
+
+	val, err, _ := c.capsSingle.Do("", func() (interface{}, error) {
+		c.capsMu.Lock()
+		caps, found := c.caps, c.capsFound
+		c.capsMu.Unlock()
+
+		if found {
+			return caps, nil
+		}
+
+		caps, err := c.capabilities(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		c.capsMu.Lock()
+		c.caps, c.capsFound = caps, true
+		c.capsMu.Unlock()
+
+		return caps, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return val.([]string), nil
+}
+
+// capabilities asks the driver for its capabilities, or creates a device to find them if it can't be asked.
+func (c *deviceCache) capabilities(ctx context.Context) ([]string, error) {
+	if driver, ok := c.Driver.(avcontrol.DriverWithCapabilities); ok {
+		return driver.Capabilities(ctx)
+	}
+
+	dev, err := c.Driver.CreateDevice(ctx, _capabilitiesAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	if closer, ok := dev.(io.Closer); ok {
+		_ = closer.Close()
+	}
+
+	return avcontrol.Capabilities(dev), nil
+}
+
+// CachedDevices returns the devices that are currently cached, sorted by address.
+func (c *deviceCache) CachedDevices() []avcontrol.CachedDevice {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+
+	devs := make([]avcontrol.CachedDevice, 0, len(c.cache))
+	for _, cached := range c.cache {
+		devs = append(devs, avcontrol.CachedDevice{
+			Address:  cached.addr,
+			Created:  cached.created,
+			LastUsed: cached.lastUsed,
+			Errors:   cached.errors,
+		})
+	}
+
+	sort.Slice(devs, func(i, j int) bool {
+		return devs[i].Address < devs[j].Address
+	})
+
+	return devs
+}
+
+// Evict removes the device at addr from the cache, including copies of it created with different overrides.
+// The next request for it creates a new device.
+func (c *deviceCache) Evict(addr string) bool {
+	evicted := false
+	for _, key := range c.keys(addr) {
+		if c.evict(key, "manual", func(*cachedDevice) bool { return true }) {
+			evicted = true
+		}
+	}
+
+	return evicted
+}
+
+// keys returns the cache keys of every device with addr.
+func (c *deviceCache) keys(addr string) []string {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+
+	var keys []string
+	for key, cached := range c.cache {
+		if cached.addr == addr {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// Report records the result of a command sent to the device at addr that was created with overrides.
+// The device is evicted once MaxErrors commands in a row have failed. Devices at the same address with
+// different overrides are counted separately, like they are cached.
+// Commands canceled by the caller don't count, since they say nothing about the device.
+func (c *deviceCache) Report(addr string, overrides map[string]interface{}, err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	key := addr
+	if len(overrides) > 0 {
+		var kerr error
+		if key, kerr = cacheKey(addr, overrides); kerr != nil {
+			return
+		}
+	}
+
+	config := c.options().cache
+
+	c.cacheMu.Lock()
+	cached, ok := c.cache[key]
+	if !ok {
+		c.cacheMu.Unlock()
+		return
+	}
+
+	cached.lastUsed = time.Now()
+	if err == nil {
+		cached.errors = 0
+	} else {
+		cached.errors++
+	}
+
+	failing := config.MaxErrors > 0 && cached.errors >= config.MaxErrors
+	c.cacheMu.Unlock()
+
+	if failing {
+		// only evict the device that failed, in case it has already been replaced
+		c.evict(key, "errors", func(cur *cachedDevice) bool { return cur == cached })
+	}
+}
+
+// evictExpired evicts every device whose TTL or idle timeout has passed.
+func (c *deviceCache) evictExpired() {
+	config := c.options().cache
+	if config.TTL <= 0 && config.Idle <= 0 {
+		return
+	}
+
+	now := time.Now()
+	expired := func(cached *cachedDevice) bool {
+		return (config.TTL > 0 && now.Sub(cached.created) >= config.TTL) ||
+			(config.Idle > 0 && now.Sub(cached.lastUsed) >= config.Idle)
+	}
+
+	c.cacheMu.Lock()
+	var keys []string
+	for key, cached := range c.cache {
+		if expired(cached) {
+			keys = append(keys, key)
+		}
+	}
+	c.cacheMu.Unlock()
+
+	for _, key := range keys {
+		c.evict(key, "expired", expired)
+	}
+}
+
+// evict removes the device with key if should returns true for it, and closes it if it is an io.Closer.
+func (c *deviceCache) evict(key, reason string, should func(*cachedDevice) bool) bool {
+	c.cacheMu.Lock()
+	cached, ok := c.cache[key]
+	if !ok || !should(cached) {
+		c.cacheMu.Unlock()
+		return false
+	}
+
+	delete(c.cache, key)
+	cachedDevices.WithLabelValues(c.name).Set(float64(len(c.cache)))
+	c.cacheMu.Unlock()
+
+	evictedDevices.WithLabelValues(c.name, reason).Inc()
+	c.pruneLimiter(cached.addr)
+
+	// close outside of the lock, closing a connection can take a while
+	if closer, ok := cached.dev.(io.Closer); ok {
+		_ = closer.Close()
+	}
+
+	return true
+}
+
+func (c *deviceCache) parsedConfig() map[string]interface{} {
+	c.driverMu.RLock()
+	defer c.driverMu.RUnlock()
+
+	return c.config
+}
+
+// reload parses config with the wrapped driver and replaces the options for it. Every cached device is evicted,
+// since they were created with the old config. If the driver fails to parse config, it is given its old config
+// again so that it keeps working with the config it had.
+func (c *deviceCache) reload(config map[string]interface{}) error {
+	opts, err := parseOptions(c.Driver, config)
+	if err != nil {
+		return err
+	}
+
+	c.driverMu.Lock()
+	defer c.driverMu.Unlock()
+
+	if err := c.Driver.ParseConfig(config); err != nil {
+		_ = c.Driver.ParseConfig(c.config)
+		return fmt.Errorf("unable to parse config: %w", err)
+	}
+
+	c.config = config
+	c.opts = opts
+
+	c.capsMu.Lock()
+	c.caps, c.capsFound = nil, false
+	c.capsMu.Unlock()
+
+	// commands in progress keep using the same limiters, so that they still count against the new limits
+	c.limitersMu.Lock()
+	for _, l := range c.limiters {
+		l.setLimits(opts.limits)
+	}
+	c.limitersMu.Unlock()
+
+	c.cacheMu.Lock()
+	keys := make([]string, 0, len(c.cache))
+	for key := range c.cache {
+		keys = append(keys, key)
+	}
+	c.cacheMu.Unlock()
+
+	for _, key := range keys {
+		c.evict(key, "reload", func(*cachedDevice) bool { return true })
+	}
+
+	return nil
 }
@@ -4,11 +4,14 @@ import (
 	"context"
 	"errors"
 	"math/rand"
+	"os"
 	sync "sync"
 	"testing"
 	"time"
 
 	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/byuoitav/av-control-api/drivers/driverstest"
+	"github.com/byuoitav/av-control-api/mock"
 	"github.com/matryer/is"
 	"golang.org/x/sync/errgroup"
 )
@@ -54,7 +57,7 @@ func TestSavingDevices(t *testing.T) {
 				return time.Duration(rand.Intn(50)) * time.Millisecond
 			},
 		},
-		cache: make(map[string]avcontrol.Device),
+		cache: make(map[string]*cachedDevice),
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -92,7 +95,7 @@ func TestSaveDevicesAtSameTime(t *testing.T) {
 				return 2 * time.Second
 			},
 		},
-		cache: make(map[string]avcontrol.Device),
+		cache: make(map[string]*cachedDevice),
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -156,7 +159,7 @@ func TestCreateDeviceError(t *testing.T) {
 				return errors.New("unable to create device")
 			},
 		},
-		cache: make(map[string]avcontrol.Device),
+		cache: make(map[string]*cachedDevice),
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
@@ -191,3 +194,316 @@ func TestCreateDeviceError(t *testing.T) {
 	is.True(err1 == err2)
 	is.True(err1 != err3)
 }
+
+// closingDevice records whether it was closed.
+type closingDevice struct {
+	closed bool
+}
+
+func (d *closingDevice) Close() error {
+	d.closed = true
+	return nil
+}
+
+func TestEvictExpired(t *testing.T) {
+	tests := []struct {
+		name   string
+		config cacheConfig
+		use    bool
+	}{
+		{
+			name:   "TTL",
+			config: cacheConfig{TTL: 20 * time.Millisecond},
+			use:    true,
+		},
+		{
+			name:   "Idle",
+			config: cacheConfig{Idle: 20 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			cache := &deviceCache{
+				Driver: &testDriver{},
+				opts:   options{cache: tt.config},
+				cache:  make(map[string]*cachedDevice),
+			}
+
+			ctx := context.Background()
+			dev, err := cache.CreateDevice(ctx, "1.1.1.1")
+			is.NoErr(err)
+
+			for i := 0; i < 4; i++ {
+				time.Sleep(10 * time.Millisecond)
+				if tt.use {
+					// using the device doesn't keep it from hitting its ttl
+					cache.Report("1.1.1.1", nil, nil)
+				}
+			}
+
+			d, err := cache.CreateDevice(ctx, "1.1.1.1")
+			is.NoErr(err)
+			is.True(d != dev)
+		})
+	}
+}
+
+func TestEvictIdleInUse(t *testing.T) {
+	is := is.New(t)
+
+	cache := &deviceCache{
+		Driver: &testDriver{},
+		opts:   options{cache: cacheConfig{Idle: 30 * time.Millisecond}},
+		cache:  make(map[string]*cachedDevice),
+	}
+
+	ctx := context.Background()
+	dev, err := cache.CreateDevice(ctx, "1.1.1.1")
+	is.NoErr(err)
+
+	for i := 0; i < 5; i++ {
+		time.Sleep(10 * time.Millisecond)
+
+		d, err := cache.CreateDevice(ctx, "1.1.1.1")
+		is.NoErr(err)
+		is.True(d == dev)
+	}
+}
+
+func TestReportEvicts(t *testing.T) {
+	is := is.New(t)
+
+	dev := &closingDevice{}
+	cache := &deviceCache{
+		Driver: &driverstest.Driver{
+			Devices: map[string]avcontrol.Device{
+				"1.1.1.1": dev,
+			},
+		},
+		opts:  options{cache: cacheConfig{MaxErrors: 3}},
+		cache: make(map[string]*cachedDevice),
+	}
+
+	_, err := cache.CreateDevice(context.Background(), "1.1.1.1")
+	is.NoErr(err)
+
+	// a success resets the count, and canceled commands don't count
+	cache.Report("1.1.1.1", nil, errors.New("timeout"))
+	cache.Report("1.1.1.1", nil, errors.New("timeout"))
+	cache.Report("1.1.1.1", nil, nil)
+	cache.Report("1.1.1.1", nil, errors.New("timeout"))
+	cache.Report("1.1.1.1", nil, context.Canceled)
+	cache.Report("1.1.1.1", nil, errors.New("timeout"))
+
+	is.Equal(len(cache.CachedDevices()), 1)
+	is.Equal(cache.CachedDevices()[0].Errors, 2)
+	is.True(!dev.closed)
+
+	cache.Report("1.1.1.1", nil, errors.New("timeout"))
+	is.Equal(len(cache.CachedDevices()), 0)
+	is.True(dev.closed)
+}
+
+func TestReportOverrides(t *testing.T) {
+	is := is.New(t)
+
+	cache := &deviceCache{
+		Driver: &configDriver{},
+		opts:   options{cache: cacheConfig{MaxErrors: 2}},
+		cache:  make(map[string]*cachedDevice),
+	}
+
+	ctx := context.Background()
+	special := map[string]interface{}{"password": "special"}
+
+	_, err := cache.CreateDevice(ctx, "1.1.1.1")
+	is.NoErr(err)
+	_, err = cache.CreateDeviceWithConfig(ctx, "1.1.1.1", special)
+	is.NoErr(err)
+
+	// failures on one config of a device don't count against the others at its address
+	cache.Report("1.1.1.1", special, errors.New("bad password"))
+	cache.Report("1.1.1.1", nil, errors.New("timeout"))
+	cache.Report("1.1.1.1", special, errors.New("bad password"))
+
+	devs := cache.CachedDevices()
+	is.Equal(len(devs), 1)
+	is.Equal(devs[0].Errors, 1)
+}
+
+func TestEvict(t *testing.T) {
+	is := is.New(t)
+
+	cache := &deviceCache{
+		Driver: &testDriver{},
+		cache:  make(map[string]*cachedDevice),
+	}
+
+	dev, err := cache.CreateDevice(context.Background(), "1.1.1.1")
+	is.NoErr(err)
+
+	is.True(cache.Evict("1.1.1.1"))
+	is.True(!cache.Evict("1.1.1.1"))
+
+	d, err := cache.CreateDevice(context.Background(), "1.1.1.1")
+	is.NoErr(err)
+	is.True(d != dev)
+}
+
+// configDriver creates devices that remember the config they were created with.
+type configDriver struct {
+	testDriver
+}
+
+type configDevice struct {
+	config map[string]interface{}
+}
+
+func (d *configDriver) CreateDeviceWithConfig(ctx context.Context, addr string, config map[string]interface{}) (avcontrol.Device, error) {
+	return &configDevice{config: config}, nil
+}
+
+func TestCreateDeviceWithConfig(t *testing.T) {
+	is := is.New(t)
+
+	r, err := NewWithConfig(map[string]map[string]interface{}{
+		"config": {
+			"username": "admin",
+			"password": "default",
+		},
+	})
+	is.NoErr(err)
+	is.NoErr(r.Register("config", &configDriver{}))
+	is.NoErr(r.Register("plain", &testDriver{}))
+
+	driver := r.Get("config").(avcontrol.DriverWithDeviceConfig)
+	ctx := context.Background()
+
+	dev, err := driver.CreateDeviceWithConfig(ctx, "1.1.1.1", map[string]interface{}{"password": "special"})
+	is.NoErr(err)
+	is.Equal(dev.(*configDevice).config, map[string]interface{}{
+		"username": "admin",
+		"password": "special",
+	})
+
+	// the same overrides get the same device
+	same, err := driver.CreateDeviceWithConfig(ctx, "1.1.1.1", map[string]interface{}{"password": "special"})
+	is.NoErr(err)
+	is.True(same == dev)
+
+	// but different overrides, or no overrides, get a different one
+	other, err := driver.CreateDeviceWithConfig(ctx, "1.1.1.1", map[string]interface{}{"password": "other"})
+	is.NoErr(err)
+	is.True(other != dev)
+
+	plain, err := driver.CreateDevice(ctx, "1.1.1.1")
+	is.NoErr(err)
+	is.True(plain != dev)
+
+	is.Equal(len(r.Get("config").(avcontrol.DriverWithDeviceCache).CachedDevices()), 3)
+
+	// evicting the address evicts every copy of it
+	is.True(r.Get("config").(avcontrol.DriverWithDeviceCache).Evict("1.1.1.1"))
+	is.Equal(len(r.Get("config").(avcontrol.DriverWithDeviceCache).CachedDevices()), 0)
+
+	_, err = r.Get("plain").(avcontrol.DriverWithDeviceConfig).CreateDeviceWithConfig(ctx, "1.1.1.1", map[string]interface{}{"password": "special"})
+	is.True(errors.Is(err, avcontrol.ErrDeviceConfigNotSupported))
+
+	// secret references in overrides are not resolved
+	os.Setenv("AVCONTROL_TEST_PASSWORD", "secret")
+	defer os.Unsetenv("AVCONTROL_TEST_PASSWORD")
+
+	dev, err = driver.CreateDeviceWithConfig(ctx, "1.1.1.1", map[string]interface{}{
+		"password": "${AVCONTROL_TEST_PASSWORD}",
+		"psk":      "file:/etc/passwd",
+	})
+	is.NoErr(err)
+	is.Equal(dev.(*configDevice).config, map[string]interface{}{
+		"username": "admin",
+		"password": "${AVCONTROL_TEST_PASSWORD}",
+		"psk":      "file:/etc/passwd",
+	})
+}
+
+// countingDriver counts the devices it creates.
+type countingDriver struct {
+	avcontrol.Driver
+	created int
+}
+
+func (d *countingDriver) CreateDevice(ctx context.Context, addr string) (avcontrol.Device, error) {
+	d.created++
+	return d.Driver.CreateDevice(ctx, addr)
+}
+
+func TestCapabilities(t *testing.T) {
+	is := is.New(t)
+
+	driver := &countingDriver{
+		Driver: &driverstest.Driver{
+			Devices: map[string]avcontrol.Device{
+				_capabilitiesAddr: mock.DSP{},
+			},
+		},
+	}
+
+	cache := &deviceCache{
+		Driver:   driver,
+		cache:    make(map[string]*cachedDevice),
+		limiters: make(map[string]*limiter),
+	}
+
+	caps, err := cache.Capabilities(context.Background())
+	is.NoErr(err)
+	is.Equal(caps, []string{"volume", "mute", "health", "info"})
+	is.Equal(len(cache.CachedDevices()), 0) // the device isn't cached
+
+	// the capabilities are only found once
+	caps, err = cache.Capabilities(context.Background())
+	is.NoErr(err)
+	is.Equal(caps, []string{"volume", "mute", "health", "info"})
+	is.Equal(driver.created, 1)
+
+	// until the config is reloaded
+	is.NoErr(cache.reload(nil))
+	_, err = cache.Capabilities(context.Background())
+	is.NoErr(err)
+	is.Equal(driver.created, 2)
+
+	cache = &deviceCache{
+		Driver: &testDriver{
+			err: func() error { return errors.New("bad config") },
+		},
+		cache: make(map[string]*cachedDevice),
+	}
+
+	_, err = cache.Capabilities(context.Background())
+	is.True(err != nil)
+}
+
+// capsDriver knows its capabilities without creating a device.
+type capsDriver struct {
+	countingDriver
+}
+
+func (d *capsDriver) Capabilities(ctx context.Context) ([]string, error) {
+	return []string{"power", "videoInput"}, nil
+}
+
+func TestCapabilitiesFromDriver(t *testing.T) {
+	is := is.New(t)
+
+	driver := &capsDriver{countingDriver{Driver: &testDriver{}}}
+	cache := &deviceCache{
+		Driver: driver,
+		cache:  make(map[string]*cachedDevice),
+	}
+
+	caps, err := cache.Capabilities(context.Background())
+	is.NoErr(err)
+	is.Equal(caps, []string{"power", "videoInput"})
+	is.Equal(driver.created, 0) // no device was created to find them
+}
@@ -35,6 +35,13 @@ func (a *Atlona4x1Driver) ParseConfig(config map[string]interface{}) error {
 	return nil
 }
 
+// Limits sends commands to the switcher one at a time (see avcontrol.Limits).
+func (a *Atlona4x1Driver) Limits() avcontrol.Limits {
+	return avcontrol.Limits{
+		MaxConcurrent: 1,
+	}
+}
+
 func (a *Atlona4x1Driver) CreateDevice(ctx context.Context, addr string) (avcontrol.Device, error) {
 	return &atjuno451hdbt.AtlonaVideoSwitcher4x1{
 		Username: a.Username,
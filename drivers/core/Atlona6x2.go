@@ -35,6 +35,13 @@ func (a *Atlona6x2Driver) ParseConfig(config map[string]interface{}) error {
 	return nil
 }
 
+// Limits sends commands to the switcher one at a time (see avcontrol.Limits).
+func (a *Atlona6x2Driver) Limits() avcontrol.Limits {
+	return avcontrol.Limits{
+		MaxConcurrent: 1,
+	}
+}
+
 func (a *Atlona6x2Driver) CreateDevice(ctx context.Context, addr string) (avcontrol.Device, error) {
 	return &atomeps62.AtlonaVideoSwitcher6x2{
 		Username: a.Username,
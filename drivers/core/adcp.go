@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"time"
 
 	avcontrol "github.com/byuoitav/av-control-api"
 	"github.com/byuoitav/sony/adcp"
@@ -16,6 +17,17 @@ func (s *SonyADCPDriver) ParseConfig(config map[string]interface{}) error {
 	return nil
 }
 
+// PowerSettle returns how long ADCP projectors take to warm up. They reject
+// input commands until they are done warming up. The timeout, plus the retries,
+// fits within the 20 second deadline on requests to set the state of a room.
+func (s *SonyADCPDriver) PowerSettle() avcontrol.PowerSettle {
+	return avcontrol.PowerSettle{
+		Timeout:  12 * time.Second,
+		Interval: 2 * time.Second,
+		Retries:  3,
+	}
+}
+
 func (s *SonyADCPDriver) CreateDevice(ctx context.Context, addr string) (avcontrol.Device, error) {
 	return &adcp.Projector{
 		Address: addr,
@@ -16,6 +16,13 @@ func (k *KramerProtocol3000Driver) ParseConfig(config map[string]interface{}) er
 	return nil
 }
 
+// Limits sends commands one at a time, since Protocol 3000 devices mix up responses to concurrent commands.
+func (k *KramerProtocol3000Driver) Limits() avcontrol.Limits {
+	return avcontrol.Limits{
+		MaxConcurrent: 1,
+	}
+}
+
 func (k *KramerProtocol3000Driver) CreateDevice(ctx context.Context, addr string) (avcontrol.Device, error) {
 	return protocol3000.New(addr, protocol3000.WithLogger(k.Log)), nil
 }
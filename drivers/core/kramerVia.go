@@ -48,3 +48,13 @@ func (k *KramerViaDriver) CreateDevice(ctx context.Context, addr string) (avcont
 		Log:      k.Log,
 	}, nil
 }
+
+// CreateDeviceWithConfig creates a VIA that uses the username and password in config, for VIAs that don't share the default login.
+func (k *KramerViaDriver) CreateDeviceWithConfig(ctx context.Context, addr string, config map[string]interface{}) (avcontrol.Device, error) {
+	d := &KramerViaDriver{Log: k.Log}
+	if err := d.ParseConfig(config); err != nil {
+		return nil, err
+	}
+
+	return d.CreateDevice(ctx, addr)
+}
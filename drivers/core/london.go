@@ -16,6 +16,13 @@ func (l *LondonDriver) ParseConfig(config map[string]interface{}) error {
 	return nil
 }
 
+// Limits sends commands to the DSP one at a time (see avcontrol.Limits).
+func (l *LondonDriver) Limits() avcontrol.Limits {
+	return avcontrol.Limits{
+		MaxConcurrent: 1,
+	}
+}
+
 func (l *LondonDriver) CreateDevice(ctx context.Context, addr string) (avcontrol.Device, error) {
 	return london.New(addr, london.WithLogger(l.Log.Sugar())), nil
 }
@@ -29,11 +29,28 @@ func (s *SonyDriver) ParseConfig(config map[string]interface{}) error {
 	return nil
 }
 
+// Limits spaces out requests to Bravia displays, which start rejecting
+// requests if they come in too quickly.
+func (s *SonyDriver) Limits() avcontrol.Limits {
+	return avcontrol.Limits{
+		Spacing: 250 * time.Millisecond,
+	}
+}
+
 func (s *SonyDriver) CreateDevice(ctx context.Context, addr string) (avcontrol.Device, error) {
 	return &bravia.Display{
 		Address:      addr,
 		PreSharedKey: s.PreSharedKey,
 		Log:          s.Log,
-		RequestDelay: 250 * time.Millisecond,
 	}, nil
 }
+
+// CreateDeviceWithConfig creates a display that uses the psk in config, for displays that don't share the default psk.
+func (s *SonyDriver) CreateDeviceWithConfig(ctx context.Context, addr string, config map[string]interface{}) (avcontrol.Device, error) {
+	d := &SonyDriver{Log: s.Log}
+	if err := d.ParseConfig(config); err != nil {
+		return nil, err
+	}
+
+	return d.CreateDevice(ctx, addr)
+}
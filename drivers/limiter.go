@@ -0,0 +1,145 @@
+package drivers
+
+import (
+	"context"
+	sync "sync"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+)
+
+// limiter enforces avcontrol.Limits for a single device. Its limits can be changed while
+// commands are in progress (see setLimits), so that a config reload doesn't reset them.
+type limiter struct {
+	mu sync.Mutex
+
+	// max is how many commands can be in progress at once. there is no limit if it is zero.
+	max     int
+	spacing time.Duration
+
+	// running is how many commands are in progress, and waiting are the calls waiting for one
+	// to finish, in the order they started waiting. a waiting call's channel is closed once it has a slot.
+	running int
+	waiting []chan struct{}
+
+	// ready is the earliest time the next command can start
+	ready time.Time
+
+	// refs is how many calls to deviceCache.Acquire are using this limiter. it is guarded by deviceCache.limitersMu.
+	refs int
+}
+
+func newLimiter(limits avcontrol.Limits) *limiter {
+	l := &limiter{}
+	l.setLimits(limits)
+	return l
+}
+
+// setLimits changes the limits of l. Commands in progress aren't interrupted; if the new max is lower,
+// waiting calls don't get a slot until enough of them have finished.
+func (l *limiter) setLimits(limits avcontrol.Limits) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.max = limits.MaxConcurrent
+	l.spacing = limits.Spacing
+	l.grant()
+}
+
+// acquire waits for a free slot and then for the spacing since the last command to pass.
+// Slots are given out in the order calls started waiting for them.
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	l.mu.Lock()
+	if len(l.waiting) == 0 && (l.max <= 0 || l.running < l.max) {
+		l.running++
+		l.mu.Unlock()
+	} else {
+		ch := make(chan struct{})
+		l.waiting = append(l.waiting, ch)
+		l.mu.Unlock()
+
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			if !l.stopWaiting(ch) {
+				// it was given a slot while ctx was being canceled
+				l.free()
+			}
+
+			return nil, ctx.Err()
+		}
+	}
+
+	for {
+		l.mu.Lock()
+		wait := time.Until(l.ready)
+		if wait <= 0 {
+			l.ready = time.Now().Add(l.spacing)
+			l.mu.Unlock()
+			break
+		}
+		l.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			l.free()
+			return nil, ctx.Err()
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			if ready := time.Now().Add(l.spacing); ready.After(l.ready) {
+				l.ready = ready
+			}
+			l.mu.Unlock()
+
+			l.free()
+		})
+	}, nil
+}
+
+// stopWaiting removes ch from the calls waiting for a slot. It returns false if ch was already given one.
+func (l *limiter) stopWaiting(ch chan struct{}) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i := range l.waiting {
+		if l.waiting[i] == ch {
+			l.waiting = append(l.waiting[:i], l.waiting[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (l *limiter) free() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.running--
+	l.grant()
+}
+
+// grant gives free slots to the calls that have been waiting the longest. l.mu must be held.
+func (l *limiter) grant() {
+	for len(l.waiting) > 0 && (l.max <= 0 || l.running < l.max) {
+		close(l.waiting[0])
+		l.waiting = l.waiting[1:]
+		l.running++
+	}
+}
+
+// idle returns true if no commands are in progress or waiting, and the spacing after the last one has passed.
+func (l *limiter) idle() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.running == 0 && len(l.waiting) == 0 && !time.Now().Before(l.ready)
+}
@@ -0,0 +1,218 @@
+package drivers
+
+import (
+	"context"
+	"errors"
+	sync "sync"
+	"testing"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/matryer/is"
+)
+
+func TestLimiterMaxConcurrent(t *testing.T) {
+	is := is.New(t)
+	l := newLimiter(avcontrol.Limits{MaxConcurrent: 2})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var mu sync.Mutex
+	var running, most int
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			release, err := l.acquire(ctx)
+			is.NoErr(err)
+			defer release()
+
+			mu.Lock()
+			running++
+			if running > most {
+				most = running
+			}
+			mu.Unlock()
+
+			time.Sleep(2 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+	is.Equal(most, 2)
+}
+
+func TestLimiterSpacing(t *testing.T) {
+	is := is.New(t)
+	l := newLimiter(avcontrol.Limits{MaxConcurrent: 1, Spacing: 20 * time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := l.acquire(ctx)
+		is.NoErr(err)
+		release()
+	}
+
+	// the first command doesn't wait
+	is.True(time.Since(start) >= 40*time.Millisecond)
+}
+
+func TestLimiterOrder(t *testing.T) {
+	is := is.New(t)
+	l := newLimiter(avcontrol.Limits{MaxConcurrent: 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	release, err := l.acquire(ctx)
+	is.NoErr(err)
+
+	var mu sync.Mutex
+	var order []int
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			release, err := l.acquire(ctx)
+			is.NoErr(err)
+			defer release()
+
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+		}(i)
+
+		// give each goroutine time to start waiting
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	release()
+	wg.Wait()
+
+	is.Equal(order, []int{0, 1, 2, 3, 4})
+}
+
+func TestLimiterCancel(t *testing.T) {
+	is := is.New(t)
+	l := newLimiter(avcontrol.Limits{MaxConcurrent: 1})
+
+	release, err := l.acquire(context.Background())
+	is.NoErr(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = l.acquire(ctx)
+	is.True(errors.Is(err, context.DeadlineExceeded))
+
+	// the canceled call shouldn't have taken a slot
+	release()
+
+	release, err = l.acquire(context.Background())
+	is.NoErr(err)
+	release()
+}
+
+func TestAcquireNoLimits(t *testing.T) {
+	is := is.New(t)
+
+	cache := &deviceCache{
+		Driver: &testDriver{},
+		cache:  make(map[string]*cachedDevice),
+	}
+
+	// nothing should block when there are no limits
+	for i := 0; i < 5; i++ {
+		_, err := cache.Acquire(context.Background(), "1.1.1.1")
+		is.NoErr(err)
+	}
+}
+
+func TestLimiterSetLimits(t *testing.T) {
+	is := is.New(t)
+	l := newLimiter(avcontrol.Limits{MaxConcurrent: 1})
+
+	first, err := l.acquire(context.Background())
+	is.NoErr(err)
+
+	// raising the limit lets a waiting call through right away
+	acquired := make(chan func())
+	go func() {
+		release, err := l.acquire(context.Background())
+		is.NoErr(err)
+		acquired <- release
+	}()
+
+	time.Sleep(5 * time.Millisecond)
+	l.setLimits(avcontrol.Limits{MaxConcurrent: 2})
+
+	var second func()
+	select {
+	case second = <-acquired:
+	case <-time.After(time.Second):
+		t.Fatalf("raising the limit didn't free a slot")
+	}
+
+	// lowering it still counts the commands in progress
+	l.setLimits(avcontrol.Limits{MaxConcurrent: 1})
+	first()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = l.acquire(ctx)
+	is.True(errors.Is(err, context.DeadlineExceeded))
+
+	second()
+	release, err := l.acquire(context.Background())
+	is.NoErr(err)
+	release()
+	is.True(l.idle())
+}
+
+func TestAcquireReload(t *testing.T) {
+	is := is.New(t)
+
+	cache := &deviceCache{
+		Driver:   &testDriver{},
+		cache:    make(map[string]*cachedDevice),
+		limiters: make(map[string]*limiter),
+		opts:     options{limits: avcontrol.Limits{MaxConcurrent: 1}},
+	}
+
+	release, err := cache.Acquire(context.Background(), "1.1.1.1")
+	is.NoErr(err)
+
+	// the command in progress still counts against the limit after a reload
+	is.NoErr(cache.reload(map[string]interface{}{
+		"limits": map[interface{}]interface{}{"maxConcurrent": 1},
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = cache.Acquire(ctx, "1.1.1.1")
+	is.True(errors.Is(err, context.DeadlineExceeded))
+
+	// the limiter isn't deleted while it is in use
+	cache.pruneLimiter("1.1.1.1")
+	is.Equal(len(cache.limiters), 1)
+
+	release()
+	cache.pruneLimiter("1.1.1.1")
+	is.Equal(len(cache.limiters), 0)
+}
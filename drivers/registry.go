@@ -4,37 +4,59 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
 	sync "sync"
+	"time"
 
 	avcontrol "github.com/byuoitav/av-control-api"
 	"gopkg.in/yaml.v2"
 )
 
+var _ avcontrol.DriverRegistryWithReload = &registry{}
+
 type registry struct {
+	// path is the config file that configs was read from, if any
+	path    string
 	configs map[string]map[string]interface{}
 
 	drivers   map[string]avcontrol.Driver
 	driversMu sync.RWMutex
+
+	reloadMu sync.Mutex
 }
 
 // New creates and returns a new DriverRegistry. configPath must be
 // a valid path to a config file.
 func New(configPath string) (avcontrol.DriverRegistry, error) {
+	configs, err := readConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
 	r := &registry{
-		configs: make(map[string]map[string]interface{}),
+		path:    configPath,
+		configs: configs,
 		drivers: make(map[string]avcontrol.Driver),
 	}
 
-	buf, err := ioutil.ReadFile(configPath)
+	return r, nil
+}
+
+func readConfig(path string) (map[string]map[string]interface{}, error) {
+	configs := make(map[string]map[string]interface{})
+
+	buf, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("unable to read file: %w", err)
 	}
 
-	if err := yaml.Unmarshal(buf, &r.configs); err != nil {
+	if err := yaml.Unmarshal(buf, &configs); err != nil {
 		return nil, fmt.Errorf("unable to parse config: %w", err)
 	}
 
-	return r, nil
+	return configs, nil
 }
 
 // NewWithConfig is like new but expects the already parsed configs as an argument.
@@ -65,14 +87,28 @@ func (r *registry) Register(name string, driver avcontrol.Driver) error {
 		return fmt.Errorf("registry/%s: already registered", name)
 	}
 
-	if err := driver.ParseConfig(r.configs[name]); err != nil {
+	config, err := resolveSecrets(r.configs[name])
+	if err != nil {
+		return fmt.Errorf("registry/%s: unable to resolve secrets: %w", name, err)
+	}
+
+	if err := driver.ParseConfig(config); err != nil {
 		return fmt.Errorf("registry/%s: unable to parse config: %w", name, err)
 	}
 
+	opts, err := parseOptions(driver, config)
+	if err != nil {
+		return fmt.Errorf("registry/%s: %w", name, err)
+	}
+
 	// wrap this driver with the deviceCache
 	r.drivers[name] = &deviceCache{
-		Driver: driver,
-		cache:  make(map[string]avcontrol.Device),
+		Driver:   driver,
+		name:     name,
+		config:   config,
+		opts:     opts,
+		cache:    make(map[string]*cachedDevice),
+		limiters: make(map[string]*limiter),
 	}
 
 	return nil
@@ -106,3 +142,225 @@ func (r *registry) List() []string {
 
 	return list
 }
+
+// options are the parts of a driver's config that the registry handles, rather than the driver.
+type options struct {
+	settle avcontrol.PowerSettle
+	limits avcontrol.Limits
+	cache  cacheConfig
+}
+
+// Reload reads the config file again and gives each driver whose section of it (or the secrets it references) changed its new config.
+// Devices created by those drivers are evicted from their caches. Drivers that fail to parse their new
+// config keep their old one, and are tried again on the next reload.
+// It returns the names of the drivers that were reloaded.
+func (r *registry) Reload() ([]string, error) {
+	if r.path == "" {
+		return nil, errors.New("registry was not created from a config file")
+	}
+
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+
+	configs, err := readConfig(r.path)
+	if err != nil {
+		return nil, err
+	}
+
+	// don't hold driversMu while drivers reload, so that Get isn't blocked
+	r.driversMu.RLock()
+	old := r.configs
+	drivers := make(map[string]*deviceCache, len(r.drivers))
+	for name, driver := range r.drivers {
+		drivers[name] = driver.(*deviceCache)
+	}
+	r.driversMu.RUnlock()
+
+	var reloaded, errs []string
+	for name, driver := range drivers {
+		// compare the resolved config so that changed secrets are picked up
+		config, err := resolveSecrets(configs[name])
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("registry/%s: unable to resolve secrets: %s", name, err))
+			configs[name] = old[name]
+			continue
+		}
+
+		if reflect.DeepEqual(driver.parsedConfig(), config) {
+			continue
+		}
+
+		if err := driver.reload(config); err != nil {
+			errs = append(errs, fmt.Sprintf("registry/%s: %s", name, err))
+			configs[name] = old[name]
+			continue
+		}
+
+		reloaded = append(reloaded, name)
+	}
+
+	r.driversMu.Lock()
+	r.configs = configs
+	r.driversMu.Unlock()
+
+	sort.Strings(reloaded)
+	sort.Strings(errs)
+
+	if len(errs) > 0 {
+		return reloaded, fmt.Errorf("unable to reload drivers: %s", strings.Join(errs, "; "))
+	}
+
+	return reloaded, nil
+}
+
+// parseOptions parses the sections of config that the registry handles,
+// using the defaults declared by driver for anything that isn't in config.
+func parseOptions(driver avcontrol.Driver, config map[string]interface{}) (options, error) {
+	var opts options
+	var err error
+
+	if d, ok := driver.(avcontrol.DriverWithPowerSettle); ok {
+		opts.settle = d.PowerSettle()
+	}
+
+	opts.settle, err = parsePowerSettle(config["powerSettle"], opts.settle)
+	if err != nil {
+		return opts, fmt.Errorf("unable to parse powerSettle: %w", err)
+	}
+
+	if d, ok := driver.(avcontrol.DriverWithLimits); ok {
+		opts.limits = d.Limits()
+	}
+
+	opts.limits, err = parseLimits(config["limits"], opts.limits)
+	if err != nil {
+		return opts, fmt.Errorf("unable to parse limits: %w", err)
+	}
+
+	opts.cache, err = parseCacheConfig(config["cache"], defaultCacheConfig())
+	if err != nil {
+		return opts, fmt.Errorf("unable to parse cache: %w", err)
+	}
+
+	return opts, nil
+}
+
+// parsePowerSettle parses the powerSettle section of a driver's config.
+// Fields that aren't in config are left as they are in settle.
+func parsePowerSettle(config interface{}, settle avcontrol.PowerSettle) (avcontrol.PowerSettle, error) {
+	fields, err := configSection(config)
+	if err != nil {
+		return settle, err
+	}
+
+	if err := parseDuration(fields, "timeout", &settle.Timeout); err != nil {
+		return settle, err
+	}
+
+	if err := parseDuration(fields, "interval", &settle.Interval); err != nil {
+		return settle, err
+	}
+
+	if err := parseCount(fields, "retries", &settle.Retries); err != nil {
+		return settle, err
+	}
+
+	return settle, nil
+}
+
+// parseLimits parses the limits section of a driver's config.
+// Fields that aren't in config are left as they are in limits.
+func parseLimits(config interface{}, limits avcontrol.Limits) (avcontrol.Limits, error) {
+	fields, err := configSection(config)
+	if err != nil {
+		return limits, err
+	}
+
+	if err := parseCount(fields, "maxConcurrent", &limits.MaxConcurrent); err != nil {
+		return limits, err
+	}
+
+	if err := parseDuration(fields, "spacing", &limits.Spacing); err != nil {
+		return limits, err
+	}
+
+	return limits, nil
+}
+
+// parseCacheConfig parses the cache section of a driver's config.
+// Fields that aren't in config are left as they are in cache.
+func parseCacheConfig(config interface{}, cache cacheConfig) (cacheConfig, error) {
+	fields, err := configSection(config)
+	if err != nil {
+		return cache, err
+	}
+
+	if err := parseDuration(fields, "ttl", &cache.TTL); err != nil {
+		return cache, err
+	}
+
+	if err := parseDuration(fields, "idle", &cache.Idle); err != nil {
+		return cache, err
+	}
+
+	if err := parseCount(fields, "maxErrors", &cache.MaxErrors); err != nil {
+		return cache, err
+	}
+
+	return cache, nil
+}
+
+// configSection returns the fields in a nested section of a driver's config.
+func configSection(config interface{}) (map[string]interface{}, error) {
+	switch config := config.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return config, nil
+	case map[interface{}]interface{}:
+		// yaml.v2 decodes nested maps with interface{} keys
+		fields := make(map[string]interface{}, len(config))
+		for k, v := range config {
+			fields[fmt.Sprintf("%v", k)] = v
+		}
+
+		return fields, nil
+	default:
+		return nil, fmt.Errorf("must be a map, got %T", config)
+	}
+}
+
+func parseDuration(fields map[string]interface{}, key string, dst *time.Duration) error {
+	val, ok := fields[key]
+	if !ok {
+		return nil
+	}
+
+	str, ok := val.(string)
+	if !ok {
+		return fmt.Errorf("%s must be a duration string, got %T", key, val)
+	}
+
+	d, err := time.ParseDuration(str)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+
+	*dst = d
+	return nil
+}
+
+func parseCount(fields map[string]interface{}, key string, dst *int) error {
+	val, ok := fields[key]
+	if !ok {
+		return nil
+	}
+
+	n, ok := val.(int)
+	if !ok || n < 0 {
+		return fmt.Errorf("%s must be a non-negative integer, got %v", key, val)
+	}
+
+	*dst = n
+	return nil
+}
@@ -1,11 +1,17 @@
 package drivers
 
 import (
+	"context"
 	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
+	avcontrol "github.com/byuoitav/av-control-api"
 	"github.com/matryer/is"
 )
 
@@ -130,3 +136,171 @@ func TestMustRegister(t *testing.T) {
 	r.MustRegister("driver/name", &testDriver{})
 
 }
+
+func TestRegisterPowerSettle(t *testing.T) {
+	is := is.New(t)
+
+	r, err := NewWithConfig(map[string]map[string]interface{}{
+		"driver/name": {
+			"powerSettle": map[interface{}]interface{}{
+				"timeout":  "30s",
+				"interval": "500ms",
+				"retries":  2,
+			},
+		},
+	})
+	is.NoErr(err)
+
+	err = r.Register("driver/name", &testDriver{})
+	is.NoErr(err)
+
+	driver, ok := r.Get("driver/name").(avcontrol.DriverWithPowerSettle)
+	is.True(ok)
+	is.Equal(driver.PowerSettle(), avcontrol.PowerSettle{
+		Timeout:  30 * time.Second,
+		Interval: 500 * time.Millisecond,
+		Retries:  2,
+	})
+}
+
+func TestRegisterPowerSettleInvalid(t *testing.T) {
+	is := is.New(t)
+
+	r, err := NewWithConfig(map[string]map[string]interface{}{
+		"driver/name": {
+			"powerSettle": map[interface{}]interface{}{
+				"timeout": 30,
+			},
+		},
+	})
+	is.NoErr(err)
+
+	err = r.Register("driver/name", &testDriver{})
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "timeout must be a duration string"))
+}
+
+func TestRegisterLimits(t *testing.T) {
+	is := is.New(t)
+
+	r, err := NewWithConfig(map[string]map[string]interface{}{
+		"driver/name": {
+			"limits": map[interface{}]interface{}{
+				"maxConcurrent": 1,
+				"spacing":       "100ms",
+			},
+		},
+	})
+	is.NoErr(err)
+
+	err = r.Register("driver/name", &testDriver{})
+	is.NoErr(err)
+
+	driver, ok := r.Get("driver/name").(avcontrol.DriverWithLimits)
+	is.True(ok)
+	is.Equal(driver.Limits(), avcontrol.Limits{
+		MaxConcurrent: 1,
+		Spacing:       100 * time.Millisecond,
+	})
+}
+
+func TestRegisterCacheConfig(t *testing.T) {
+	is := is.New(t)
+
+	r, err := NewWithConfig(map[string]map[string]interface{}{
+		"driver/name": {
+			"cache": map[interface{}]interface{}{
+				"ttl":       "24h",
+				"maxErrors": 0,
+			},
+		},
+	})
+	is.NoErr(err)
+	is.NoErr(r.Register("driver/name", &testDriver{}))
+	is.NoErr(r.Register("driver/default", &testDriver{}))
+
+	is.Equal(r.Get("driver/name").(*deviceCache).opts.cache, cacheConfig{
+		TTL:  24 * time.Hour,
+		Idle: _defaultIdleTimeout,
+	})
+	is.Equal(r.Get("driver/default").(*deviceCache).opts.cache, defaultCacheConfig())
+}
+
+// pskDriver requires a psk, like the sony driver.
+type pskDriver struct {
+	testDriver
+	psk string
+}
+
+func (d *pskDriver) ParseConfig(config map[string]interface{}) error {
+	psk, ok := config["psk"].(string)
+	if !ok || psk == "" {
+		return errors.New("no psk given")
+	}
+
+	d.psk = psk
+	return nil
+}
+
+func TestReload(t *testing.T) {
+	is := is.New(t)
+
+	dir, err := ioutil.TempDir("", "drivers")
+	is.NoErr(err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "driver-config.yaml")
+	write := func(config string) {
+		is.NoErr(ioutil.WriteFile(path, []byte(config), 0600))
+	}
+
+	write("sony: {psk: one}\nkramer: {psk: same}\n")
+
+	r, err := New(path)
+	is.NoErr(err)
+
+	sony, kramer := &pskDriver{}, &pskDriver{}
+	is.NoErr(r.Register("sony", sony))
+	is.NoErr(r.Register("kramer", kramer))
+
+	ctx := context.Background()
+	sonyDev, err := r.Get("sony").CreateDevice(ctx, "1.1.1.1")
+	is.NoErr(err)
+	kramerDev, err := r.Get("kramer").CreateDevice(ctx, "1.1.1.1")
+	is.NoErr(err)
+
+	// only drivers whose config changed are reloaded
+	write("sony: {psk: two}\nkramer: {psk: same}\n")
+
+	reloaded, err := r.(avcontrol.DriverRegistryWithReload).Reload()
+	is.NoErr(err)
+	is.Equal(reloaded, []string{"sony"})
+	is.Equal(sony.psk, "two")
+
+	dev, err := r.Get("sony").CreateDevice(ctx, "1.1.1.1")
+	is.NoErr(err)
+	is.True(dev != sonyDev)
+
+	dev, err = r.Get("kramer").CreateDevice(ctx, "1.1.1.1")
+	is.NoErr(err)
+	is.True(dev == kramerDev)
+
+	// a driver that can't parse its new config keeps its old one
+	write("sony: {psk: \"\"}\nkramer: {psk: same}\n")
+
+	reloaded, err = r.(avcontrol.DriverRegistryWithReload).Reload()
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "registry/sony: unable to parse config: no psk given"))
+	is.Equal(len(reloaded), 0)
+	is.Equal(sony.psk, "two")
+}
+
+func TestReloadWithoutFile(t *testing.T) {
+	is := is.New(t)
+
+	r, err := NewWithConfig(nil)
+	is.NoErr(err)
+
+	_, err = r.(avcontrol.DriverRegistryWithReload).Reload()
+	is.True(err != nil)
+}
@@ -0,0 +1,118 @@
+package drivers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// _filePrefix marks a config value that should be replaced with the contents of a file, like a docker or kubernetes secret.
+const _filePrefix = "file:"
+
+// envRef matches ${NAME} references to environment variables. $${NAME} is left alone, minus the first $.
+var envRef = regexp.MustCompile(`\$?\$\{([^}]*)\}`)
+
+// resolveSecrets returns a copy of config with references to secrets replaced with their values.
+// String values that start with "file:" are replaced with the contents of the file at the rest of the
+// value (without trailing newlines), and ${NAME} anywhere in a string value is replaced with the
+// environment variable NAME. It is an error for a referenced file or environment variable to not exist.
+func resolveSecrets(config map[string]interface{}) (map[string]interface{}, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	resolved, err := resolveValue("", config)
+	if err != nil {
+		return nil, err
+	}
+
+	return resolved.(map[string]interface{}), nil
+}
+
+func resolveValue(path string, val interface{}) (interface{}, error) {
+	join := func(key interface{}) string {
+		if path == "" {
+			return fmt.Sprintf("%v", key)
+		}
+
+		return fmt.Sprintf("%s.%v", path, key)
+	}
+
+	switch val := val.(type) {
+	case string:
+		return resolveString(path, val)
+	case map[string]interface{}:
+		resolved := make(map[string]interface{}, len(val))
+		for k, v := range val {
+			r, err := resolveValue(join(k), v)
+			if err != nil {
+				return nil, err
+			}
+
+			resolved[k] = r
+		}
+
+		return resolved, nil
+	case map[interface{}]interface{}:
+		resolved := make(map[interface{}]interface{}, len(val))
+		for k, v := range val {
+			r, err := resolveValue(join(k), v)
+			if err != nil {
+				return nil, err
+			}
+
+			resolved[k] = r
+		}
+
+		return resolved, nil
+	case []interface{}:
+		resolved := make([]interface{}, len(val))
+		for i, v := range val {
+			r, err := resolveValue(join(i), v)
+			if err != nil {
+				return nil, err
+			}
+
+			resolved[i] = r
+		}
+
+		return resolved, nil
+	default:
+		return val, nil
+	}
+}
+
+func resolveString(path, val string) (string, error) {
+	if strings.HasPrefix(val, _filePrefix) {
+		file := strings.TrimPrefix(val, _filePrefix)
+
+		buf, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("%s: unable to read secret file: %w", path, err)
+		}
+
+		return strings.TrimRight(string(buf), "\r\n"), nil
+	}
+
+	var err error
+	resolved := envRef.ReplaceAllStringFunc(val, func(ref string) string {
+		if strings.HasPrefix(ref, "$$") {
+			return ref[1:]
+		}
+
+		name := envRef.FindStringSubmatch(ref)[1]
+		env, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = fmt.Errorf("%s: environment variable %q is not set", path, name)
+		}
+
+		return env
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,180 @@
+package drivers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/matryer/is"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	psk := filepath.Join(dir, "sony-psk")
+	if err := ioutil.WriteFile(psk, []byte("from-a-file\n"), 0600); err != nil {
+		t.Fatalf("unable to write secret: %s", err)
+	}
+
+	os.Setenv("AVCONTROL_TEST_USER", "admin")
+	os.Setenv("AVCONTROL_TEST_EMPTY", "")
+	defer os.Unsetenv("AVCONTROL_TEST_USER")
+	defer os.Unsetenv("AVCONTROL_TEST_EMPTY")
+
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		out    map[string]interface{}
+		err    string
+	}{
+		{
+			name: "Plain",
+			config: map[string]interface{}{
+				"psk":     "plaintext",
+				"retries": 3,
+			},
+			out: map[string]interface{}{
+				"psk":     "plaintext",
+				"retries": 3,
+			},
+		},
+		{
+			name: "Env",
+			config: map[string]interface{}{
+				"username": "${AVCONTROL_TEST_USER}",
+				"password": "${AVCONTROL_TEST_EMPTY}",
+				"url":      "http://${AVCONTROL_TEST_USER}@host",
+				"literal":  "$${AVCONTROL_TEST_USER}",
+			},
+			out: map[string]interface{}{
+				"username": "admin",
+				"password": "",
+				"url":      "http://admin@host",
+				"literal":  "${AVCONTROL_TEST_USER}",
+			},
+		},
+		{
+			name: "File",
+			config: map[string]interface{}{
+				"psk": "file:" + psk,
+			},
+			out: map[string]interface{}{
+				"psk": "from-a-file",
+			},
+		},
+		{
+			name: "Nested",
+			config: map[string]interface{}{
+				"accounts": []interface{}{
+					map[interface{}]interface{}{
+						"username": "${AVCONTROL_TEST_USER}",
+						"password": "file:" + psk,
+					},
+				},
+			},
+			out: map[string]interface{}{
+				"accounts": []interface{}{
+					map[interface{}]interface{}{
+						"username": "admin",
+						"password": "from-a-file",
+					},
+				},
+			},
+		},
+		{
+			name: "MissingEnv",
+			config: map[string]interface{}{
+				"accounts": []interface{}{
+					map[interface{}]interface{}{
+						"password": "${AVCONTROL_TEST_MISSING}",
+					},
+				},
+			},
+			err: `accounts.0.password: environment variable "AVCONTROL_TEST_MISSING" is not set`,
+		},
+		{
+			name: "MissingFile",
+			config: map[string]interface{}{
+				"psk": "file:" + filepath.Join(dir, "missing"),
+			},
+			err: "psk: unable to read secret file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := resolveSecrets(tt.config)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error %q, got %v", tt.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if diff := cmp.Diff(tt.out, out); diff != "" {
+				t.Errorf("generated incorrect config (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRegisterSecrets(t *testing.T) {
+	is := is.New(t)
+
+	os.Setenv("AVCONTROL_TEST_PSK", "secret")
+	defer os.Unsetenv("AVCONTROL_TEST_PSK")
+
+	r, err := NewWithConfig(map[string]map[string]interface{}{
+		"sony":   {"psk": "${AVCONTROL_TEST_PSK}"},
+		"kramer": {"psk": "${AVCONTROL_TEST_MISSING}"},
+	})
+	is.NoErr(err)
+
+	sony := &pskDriver{}
+	is.NoErr(r.Register("sony", sony))
+	is.Equal(sony.psk, "secret")
+
+	err = r.Register("kramer", &pskDriver{})
+	is.Equal(err.Error(), `registry/kramer: unable to resolve secrets: psk: environment variable "AVCONTROL_TEST_MISSING" is not set`)
+}
+
+func TestReloadSecretFile(t *testing.T) {
+	is := is.New(t)
+
+	dir, err := ioutil.TempDir("", "secrets")
+	is.NoErr(err)
+	defer os.RemoveAll(dir)
+
+	secret := filepath.Join(dir, "sony-psk")
+	is.NoErr(ioutil.WriteFile(secret, []byte("one"), 0600))
+
+	path := filepath.Join(dir, "driver-config.yaml")
+	is.NoErr(ioutil.WriteFile(path, []byte("sony: {psk: \"file:"+secret+"\"}\n"), 0600))
+
+	r, err := New(path)
+	is.NoErr(err)
+
+	sony := &pskDriver{}
+	is.NoErr(r.Register("sony", sony))
+	is.Equal(sony.psk, "one")
+
+	// the config file is the same, but the secret it references was rotated
+	is.NoErr(ioutil.WriteFile(secret, []byte("two"), 0600))
+
+	reloaded, err := r.(*registry).Reload()
+	is.NoErr(err)
+	is.Equal(reloaded, []string{"sony"})
+	is.Equal(sony.psk, "two")
+}
@@ -0,0 +1,34 @@
+package avcontrol
+
+import (
+	"context"
+	"time"
+)
+
+// EventSink receives the changes made to the state of devices.
+type EventSink interface {
+	// Send delivers the given events, which were all caused by the same request.
+	Send(context.Context, []StateEvent) error
+}
+
+// StateEvent is a change to a single field of a device's state.
+type StateEvent struct {
+	// Time is when the change was made.
+	Time time.Time `json:"time"`
+
+	// RequestID is the ID of the request that made the change, from CtxRequestID.
+	RequestID string `json:"requestID,omitempty"`
+
+	Room   string   `json:"room"`
+	Device DeviceID `json:"device"`
+
+	// Field is the field that changed, named the same way as DeviceStateError.Field (e.g. "poweredOn" or "volumes.headphones").
+	// Inputs are named by their port names, even if they were set using port labels.
+	Field string `json:"field"`
+
+	// Old is the last known value of the field. It is nil if the value wasn't known.
+	Old interface{} `json:"old"`
+
+	// New is the value the field was changed to.
+	New interface{} `json:"new"`
+}
@@ -0,0 +1,165 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/matryer/is"
+)
+
+var testEvents = []avcontrol.StateEvent{
+	{
+		Time:      time.Date(2020, 6, 1, 9, 0, 0, 0, time.UTC),
+		RequestID: "ID",
+		Room:      "ITB-1101",
+		Device:    "ITB-1101-D1",
+		Field:     "poweredOn",
+		Old:       false,
+		New:       true,
+	},
+	{
+		Time:      time.Date(2020, 6, 1, 9, 0, 0, 0, time.UTC),
+		RequestID: "ID",
+		Room:      "ITB-1101",
+		Device:    "ITB-1101-D1",
+		Field:     "volumes.",
+		New:       float64(30), // json numbers unmarshal as float64
+	},
+}
+
+func TestWebhook(t *testing.T) {
+	is := is.New(t)
+
+	var mu sync.Mutex
+	var attempts int
+	var got []avcontrol.StateEvent
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get(SignatureHeader) != Sign("secret", body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if err := json.Unmarshal(body, &got); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	w := &Webhook{
+		URL:     server.URL,
+		Secret:  "secret",
+		Retries: 3,
+		Backoff: time.Millisecond,
+	}
+
+	is.NoErr(w.Send(context.Background(), testEvents))
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	is.Equal(attempts, 3)
+	is.Equal(got, testEvents)
+}
+
+func TestWebhookNoRetry(t *testing.T) {
+	is := is.New(t)
+
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	w := &Webhook{
+		URL:     server.URL,
+		Retries: 3,
+		Backoff: time.Millisecond,
+	}
+
+	is.True(w.Send(context.Background(), testEvents) != nil)
+	is.Equal(attempts, 1) // client errors aren't retried
+}
+
+func TestFile(t *testing.T) {
+	is := is.New(t)
+
+	dir, err := ioutil.TempDir("", "av-control-api-events-test")
+	is.NoErr(err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "events.ndjson")
+
+	f, err := NewFile(path)
+	is.NoErr(err)
+	is.NoErr(f.Send(context.Background(), testEvents[:1]))
+	is.NoErr(f.Send(context.Background(), testEvents[1:]))
+	is.NoErr(f.Close())
+
+	file, err := os.Open(path)
+	is.NoErr(err)
+	defer file.Close()
+
+	var got []avcontrol.StateEvent
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event avcontrol.StateEvent
+		is.NoErr(json.Unmarshal(scanner.Bytes(), &event))
+		got = append(got, event)
+	}
+
+	is.NoErr(scanner.Err())
+	is.Equal(got, testEvents)
+}
+
+type errSink struct{}
+
+func (errSink) Send(ctx context.Context, events []avcontrol.StateEvent) error {
+	return errors.New("unavailable")
+}
+
+func TestSinks(t *testing.T) {
+	is := is.New(t)
+
+	var calls int
+	var mu sync.Mutex
+	counter := sinkFunc(func(ctx context.Context, events []avcontrol.StateEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		calls++
+		return nil
+	})
+
+	err := Sinks{counter, errSink{}, counter}.Send(context.Background(), testEvents)
+	is.Equal(err.Error(), "unable to send events to 1 sinks: unavailable")
+	is.Equal(calls, 2) // every sink is sent the events, even if one fails
+}
+
+type sinkFunc func(context.Context, []avcontrol.StateEvent) error
+
+func (f sinkFunc) Send(ctx context.Context, events []avcontrol.StateEvent) error {
+	return f(ctx, events)
+}
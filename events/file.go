@@ -0,0 +1,63 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+)
+
+var _ avcontrol.EventSink = &File{}
+
+// File appends events to a file as newline-delimited JSON, one event per line.
+type File struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFile opens (or creates) the file at path to append events to.
+func NewFile(path string) (*File, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open event file: %w", err)
+	}
+
+	return &File{
+		file: f,
+	}, nil
+}
+
+// Send appends each of events to the file.
+func (f *File) Send(ctx context.Context, events []avcontrol.StateEvent) error {
+	var buf []byte
+	for _, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("unable to marshal event: %w", err)
+		}
+
+		buf = append(buf, line...)
+		buf = append(buf, '\n')
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// write every event at once so that events from different requests aren't interleaved
+	if _, err := f.file.Write(buf); err != nil {
+		return fmt.Errorf("unable to write events: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the file.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.file.Close()
+}
@@ -0,0 +1,45 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+)
+
+var _ avcontrol.EventSink = Sinks{}
+
+// Sinks sends events to each of the sinks in it.
+type Sinks []avcontrol.EventSink
+
+// Send sends events to each sink at the same time, and returns once they have all finished.
+func (s Sinks) Send(ctx context.Context, events []avcontrol.StateEvent) error {
+	errs := make([]error, len(s))
+
+	var wg sync.WaitGroup
+	for i := range s {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = s[i].Send(ctx, events)
+		}(i)
+	}
+
+	wg.Wait()
+
+	var msgs []string
+	for _, err := range errs {
+		if err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+
+	if len(msgs) > 0 {
+		return fmt.Errorf("unable to send events to %d sinks: %s", len(msgs), strings.Join(msgs, "; "))
+	}
+
+	return nil
+}
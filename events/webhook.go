@@ -0,0 +1,115 @@
+// Package events delivers the changes made to the state of devices to webhooks and files.
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+)
+
+// SignatureHeader is the header a Webhook puts the signature of each request's body in.
+const SignatureHeader = "X-AV-Signature-256"
+
+var _ avcontrol.EventSink = &Webhook{}
+
+// Webhook POSTs events to URL as a JSON array. If Secret is set, each request is signed with an HMAC-SHA256
+// of the body using Secret, which is put in SignatureHeader as "sha256=" followed by the hex-encoded signature.
+type Webhook struct {
+	URL    string
+	Secret string
+
+	// Client is used to send the requests. If it is nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// Retries is how many more times a request is sent if it fails. Requests that fail
+	// with a 4xx status (other than 429) aren't retried.
+	Retries int
+
+	// Backoff is how long to wait before the first retry; it doubles after each one. If it is zero, 1 second is used.
+	Backoff time.Duration
+}
+
+// Sign returns the signature of body using secret, as it is sent in SignatureHeader.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body) // nolint:errcheck
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Send POSTs events to w.URL, retrying up to w.Retries times until it gets a 2xx response.
+func (w *Webhook) Send(ctx context.Context, events []avcontrol.StateEvent) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return fmt.Errorf("unable to marshal events: %w", err)
+	}
+
+	backoff := w.Backoff
+	if backoff == 0 {
+		backoff = time.Second
+	}
+
+	for i := 0; ; i++ {
+		retry, err := w.send(ctx, body)
+		if err == nil {
+			return nil
+		}
+
+		if !retry || i >= w.Retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s (gave up retrying: %s)", err, ctx.Err())
+		case <-time.After(backoff):
+			backoff *= 2
+		}
+	}
+}
+
+// send POSTs body to w.URL once. retry is true if the request might succeed if it is sent again.
+func (w *Webhook) send(ctx context.Context, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("unable to build request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if w.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(w.Secret, body))
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("unable to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// drain the body so the connection can be reused
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode/100 == 2:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5:
+		return true, fmt.Errorf("%s responded with %s", w.URL, resp.Status)
+	default:
+		return false, fmt.Errorf("%s responded with %s", w.URL, resp.Status)
+	}
+}
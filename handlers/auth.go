@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// principalName returns the name of the principal that made the request, or "" if it isn't known.
+func principalName(c *gin.Context) string {
+	if p, ok := c.Get(_cPrincipal); ok {
+		return p.(avcontrol.Principal).Name
+	}
+
+	return ""
+}
+
+// logger returns h.Logger, with the principal that made the request added to it if it is known.
+func (h *Handlers) logger(c *gin.Context) *zap.Logger {
+	if name := principalName(c); name != "" {
+		return h.Logger.With(zap.String("principal", name))
+	}
+
+	return h.Logger
+}
+
+// Authenticate authenticates the bearer token in the Authorization header of the request
+// and sets the parameter _cPrincipal. If h.Auth is nil, every request is allowed.
+func (h *Handlers) Authenticate(c *gin.Context) {
+	if h.Auth == nil {
+		c.Next()
+		return
+	}
+
+	id := c.GetString(_cRequestID)
+	log := h.Logger
+	if len(id) > 0 {
+		log = log.With(zap.String("requestID", id))
+	}
+
+	header := c.GetHeader(_hAuthorization)
+	token := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	if header == token {
+		// there wasn't a "Bearer " prefix
+		token = ""
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	p, err := h.Auth.Authenticate(ctx, token)
+	if err != nil {
+		log.Info("unable to authenticate request", zap.String("from", c.ClientIP()), zap.Error(err))
+		c.Header("WWW-Authenticate", `Bearer realm="av-control-api"`)
+		writeErrorf(c, http.StatusUnauthorized, "unauthorized")
+		c.Abort()
+		return
+	}
+
+	c.Set(_cPrincipal, p)
+	c.Next()
+}
+
+// Authorize makes sure the principal that made the request is allowed to control the room in the http parameter "room".
+// It must come after Authenticate.
+func (h *Handlers) Authorize(c *gin.Context) {
+	if h.Auth == nil {
+		c.Next()
+		return
+	}
+
+	room := c.Param("room")
+	p := c.MustGet(_cPrincipal).(avcontrol.Principal)
+
+	if !p.CanAccess(room) {
+		h.logger(c).Info("principal is not allowed to access room", zap.String("requestID", c.GetString(_cRequestID)), zap.String("room", room))
+		writeErrorf(c, http.StatusForbidden, "%s is not allowed to access %s", p.Name, room)
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+// RequireAdmin makes sure the principal that made the request is an admin.
+// It must come after Authenticate.
+func (h *Handlers) RequireAdmin(c *gin.Context) {
+	if h.Auth == nil {
+		c.Next()
+		return
+	}
+
+	p := c.MustGet(_cPrincipal).(avcontrol.Principal)
+	if !p.Admin {
+		writeErrorf(c, http.StatusForbidden, "%s is not an admin", p.Name)
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
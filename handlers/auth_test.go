@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/gin-gonic/gin"
+)
+
+type staticAuth map[string]avcontrol.Principal
+
+func (s staticAuth) Authenticate(ctx context.Context, token string) (avcontrol.Principal, error) {
+	p, ok := s[token]
+	if !ok {
+		return avcontrol.Principal{}, avcontrol.ErrUnauthenticated
+	}
+
+	return p, nil
+}
+
+func TestAuth(t *testing.T) {
+	log := setLogger()
+	defer log.Sync()
+
+	h := Handlers{
+		Logger: log,
+		Auth: staticAuth{
+			"itb": {
+				Name:  "itb",
+				Rooms: []string{"ITB-*"},
+			},
+			"admin": {
+				Name:  "admin",
+				Admin: true,
+			},
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/room/:room", h.RequestID, h.Authenticate, h.Authorize, func(c *gin.Context) {
+		c.String(http.StatusOK, principalName(c))
+	})
+	r.GET("/debug/logz", h.RequestID, h.Authenticate, h.RequireAdmin, func(c *gin.Context) {
+		c.String(http.StatusOK, "info")
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		code   int
+	}{
+		{
+			name: "NoToken",
+			path: "/room/ITB-1101",
+			code: http.StatusUnauthorized,
+		},
+		{
+			name:   "NotBearer",
+			path:   "/room/ITB-1101",
+			header: "itb",
+			code:   http.StatusUnauthorized,
+		},
+		{
+			name:   "InvalidToken",
+			path:   "/room/ITB-1101",
+			header: "Bearer jfsb",
+			code:   http.StatusUnauthorized,
+		},
+		{
+			name:   "AllowedRoom",
+			path:   "/room/ITB-1101",
+			header: "Bearer itb",
+			code:   http.StatusOK,
+		},
+		{
+			name:   "OtherBuilding",
+			path:   "/room/JFSB-B100",
+			header: "Bearer itb",
+			code:   http.StatusForbidden,
+		},
+		{
+			name:   "AdminRoom",
+			path:   "/room/JFSB-B100",
+			header: "Bearer admin",
+			code:   http.StatusOK,
+		},
+		{
+			name:   "DebugNotAdmin",
+			path:   "/debug/logz",
+			header: "Bearer itb",
+			code:   http.StatusForbidden,
+		},
+		{
+			name:   "DebugAdmin",
+			path:   "/debug/logz",
+			header: "Bearer admin",
+			code:   http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(_hAuthorization, tt.header)
+			}
+
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			if resp.Code != tt.code {
+				t.Fatalf("expected status code %d, got %d: %s", tt.code, resp.Code, resp.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthDisabled(t *testing.T) {
+	log := setLogger()
+	defer log.Sync()
+
+	h := Handlers{
+		Logger: log,
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/room/:room", h.RequestID, h.Authenticate, h.Authorize, func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/room/ITB-1101", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", resp.Code)
+	}
+}
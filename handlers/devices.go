@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// deviceCaches returns the drivers that cache their devices, keyed by the name they were registered with.
+// If the driver query parameter is set, only that driver is returned. ok is false if an error was sent to the user.
+func (h *Handlers) deviceCaches(c *gin.Context) (map[string]avcontrol.DriverWithDeviceCache, bool) {
+	names := h.DriverRegistry.List()
+	if name := c.Query("driver"); name != "" {
+		if h.DriverRegistry.Get(name) == nil {
+			writeErrorf(c, http.StatusNotFound, "driver %q is not registered", name)
+			return nil, false
+		}
+
+		names = []string{name}
+	}
+
+	caches := make(map[string]avcontrol.DriverWithDeviceCache)
+	for _, name := range names {
+		if cache, ok := h.DriverRegistry.Get(name).(avcontrol.DriverWithDeviceCache); ok {
+			caches[name] = cache
+		}
+	}
+
+	return caches, true
+}
+
+// GetCachedDevices returns the devices each driver has cached to the user as a JSON object in the body of an http response.
+// The optional driver query parameter limits the response to a single driver.
+func (h *Handlers) GetCachedDevices(c *gin.Context) {
+	caches, ok := h.deviceCaches(c)
+	if !ok {
+		return
+	}
+
+	resp := make(map[string][]avcontrol.CachedDevice)
+	for name, cache := range caches {
+		resp[name] = cache.CachedDevices()
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// EvictCachedDevices removes devices from the driver caches, so that they are recreated the next time they are used.
+// The optional driver query parameter limits eviction to a single driver, and the optional address query parameter
+// limits it to a single device. The addresses that were evicted from each driver are returned as a JSON object.
+func (h *Handlers) EvictCachedDevices(c *gin.Context) {
+	addr := c.Query("address")
+	if addr != "" && c.Query("driver") == "" {
+		writeErrorf(c, http.StatusBadRequest, "driver is required to evict a single address")
+		return
+	}
+
+	caches, ok := h.deviceCaches(c)
+	if !ok {
+		return
+	}
+
+	log := h.logger(c)
+	evicted := make(map[string][]string)
+
+	for name, cache := range caches {
+		addrs := []string{addr}
+		if addr == "" {
+			addrs = addrs[:0]
+			for _, dev := range cache.CachedDevices() {
+				addrs = append(addrs, dev.Address)
+			}
+		}
+
+		for _, a := range addrs {
+			if cache.Evict(a) {
+				log.Info("Evicted cached device", zap.String("driver", name), zap.String("address", a))
+				evicted[name] = append(evicted[name], a)
+			}
+		}
+	}
+
+	if addr != "" && len(evicted) == 0 {
+		writeErrorf(c, http.StatusNotFound, "%q is not cached", addr)
+		return
+	}
+
+	c.JSON(http.StatusOK, evicted)
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/byuoitav/av-control-api/drivers"
+	"github.com/byuoitav/av-control-api/drivers/driverstest"
+	"github.com/byuoitav/av-control-api/mock"
+	"github.com/gin-gonic/gin"
+)
+
+func TestCachedDevices(t *testing.T) {
+	log := setLogger()
+	defer log.Sync()
+
+	registry, err := drivers.NewWithConfig(nil)
+	if err != nil {
+		t.Fatalf("unable to create registry: %s", err)
+	}
+
+	registry.MustRegister("driverstest/a", &driverstest.Driver{
+		Devices: map[string]avcontrol.Device{
+			"1.1.1.1": &mock.TV{},
+			"2.2.2.2": &mock.TV{},
+		},
+	})
+	registry.MustRegister("driverstest/b", &driverstest.Driver{})
+
+	for _, addr := range []string{"1.1.1.1", "2.2.2.2"} {
+		if _, err := registry.Get("driverstest/a").CreateDevice(context.Background(), addr); err != nil {
+			t.Fatalf("unable to create device: %s", err)
+		}
+	}
+
+	h := Handlers{
+		Logger:         log,
+		DriverRegistry: registry,
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/debug/devices", h.GetCachedDevices)
+	r.DELETE("/debug/devices", h.EvictCachedDevices)
+
+	do := func(method, url string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, nil)
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := do(http.MethodGet, "/debug/devices")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", resp.Code)
+	}
+
+	var cached map[string][]avcontrol.CachedDevice
+	if err := json.Unmarshal(resp.Body.Bytes(), &cached); err != nil {
+		t.Fatalf("unable to parse response: %s", err)
+	}
+
+	if len(cached["driverstest/a"]) != 2 || len(cached["driverstest/b"]) != 0 {
+		t.Fatalf("unexpected cached devices: %+v", cached)
+	}
+
+	if resp := do(http.MethodGet, "/debug/devices?driver=nope"); resp.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown driver, got %d", resp.Code)
+	}
+
+	if resp := do(http.MethodDelete, "/debug/devices?address=1.1.1.1"); resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for address without driver, got %d", resp.Code)
+	}
+
+	resp = do(http.MethodDelete, "/debug/devices?driver=driverstest/a&address=1.1.1.1")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", resp.Code)
+	}
+
+	if resp := do(http.MethodDelete, "/debug/devices?driver=driverstest/a&address=1.1.1.1"); resp.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for device that isn't cached, got %d", resp.Code)
+	}
+
+	devs := registry.Get("driverstest/a").(avcontrol.DriverWithDeviceCache).CachedDevices()
+	if len(devs) != 1 || devs[0].Address != "2.2.2.2" {
+		t.Fatalf("unexpected cached devices after eviction: %+v", devs)
+	}
+
+	resp = do(http.MethodDelete, "/debug/devices")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", resp.Code)
+	}
+
+	if devs := registry.Get("driverstest/a").(avcontrol.DriverWithDeviceCache).CachedDevices(); len(devs) != 0 {
+		t.Fatalf("expected every device to be evicted, got %+v", devs)
+	}
+}
+
+func TestReloadDrivers(t *testing.T) {
+	log := setLogger()
+	defer log.Sync()
+
+	dir, err := ioutil.TempDir("", "handlers")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "driver-config.yaml")
+	if err := ioutil.WriteFile(path, []byte("driverstest/a: {key: one}\n"), 0600); err != nil {
+		t.Fatalf("unable to write config: %s", err)
+	}
+
+	registry, err := drivers.New(path)
+	if err != nil {
+		t.Fatalf("unable to create registry: %s", err)
+	}
+
+	registry.MustRegister("driverstest/a", &driverstest.Driver{})
+
+	h := Handlers{
+		Logger:         log,
+		DriverRegistry: registry,
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/debug/drivers/reload", h.ReloadDrivers)
+
+	if err := ioutil.WriteFile(path, []byte("driverstest/a: {key: two}\n"), 0600); err != nil {
+		t.Fatalf("unable to write config: %s", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/debug/drivers/reload", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", resp.Code)
+	}
+
+	if resp.Body.String() != `{"reloaded":["driverstest/a"]}` {
+		t.Fatalf("unexpected body: %s", resp.Body.String())
+	}
+}
+
+func TestGetDrivers(t *testing.T) {
+	log := setLogger()
+	defer log.Sync()
+
+	registry, err := drivers.NewWithConfig(nil)
+	if err != nil {
+		t.Fatalf("unable to create registry: %s", err)
+	}
+
+	registry.MustRegister("driverstest/a", &driverstest.Driver{
+		Devices: map[string]avcontrol.Device{
+			"capabilities.invalid": mock.Projector{},
+		},
+	})
+
+	h := Handlers{
+		Logger:         log,
+		DriverRegistry: registry,
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/v1/drivers", h.GetDrivers)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/drivers", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", resp.Code)
+	}
+
+	if resp.Body.String() != `{"drivers":{"driverstest/a":{"capabilities":["power","blank","audioVideoInput","health","info"]}}}` {
+		t.Fatalf("unexpected body: %s", resp.Body.String())
+	}
+}
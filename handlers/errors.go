@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/byuoitav/av-control-api/routing"
+	"github.com/byuoitav/av-control-api/state"
+	"github.com/gin-gonic/gin"
+)
+
+// writeError sends err to the user as an avcontrol.ErrorResponse with the given status code.
+func writeError(c *gin.Context, status int, err error) {
+	c.JSON(status, avcontrol.ErrorResponse{
+		Error: err.Error(),
+		Code:  errorCode(status, err),
+	})
+}
+
+// writeErrorf is like writeError, with the error formatted from format and args.
+func writeErrorf(c *gin.Context, status int, format string, args ...interface{}) {
+	writeError(c, status, fmt.Errorf(format, args...))
+}
+
+// errorCode returns the code that describes err. If err isn't one of the errors that has its own code, the code comes from status.
+func errorCode(status int, err error) string {
+	if code := state.ErrorCode(err); code != "" {
+		return code
+	}
+
+	switch {
+	case errors.Is(err, routing.ErrNoPath):
+		return avcontrol.ErrorCodeNoPath
+	case errors.Is(err, routing.ErrInvalidRoute):
+		return avcontrol.ErrorCodeInvalidRoute
+	}
+
+	switch status {
+	case http.StatusBadRequest:
+		return avcontrol.ErrorCodeBadRequest
+	case http.StatusUnauthorized:
+		return avcontrol.ErrorCodeUnauthorized
+	case http.StatusForbidden:
+		return avcontrol.ErrorCodeForbidden
+	case http.StatusNotFound:
+		return avcontrol.ErrorCodeNotFound
+	case http.StatusNotImplemented:
+		return avcontrol.ErrorCodeNotImplemented
+	default:
+		return avcontrol.ErrorCodeInternal
+	}
+}
+
+// stateStatus returns the status code to send resp with: 200 if there weren't any errors,
+// 207 (Multi-Status) if some of the state was still gotten or set, and 500 if none of it was.
+func stateStatus(resp avcontrol.StateResponse) int {
+	if len(resp.Errors) == 0 {
+		return http.StatusOK
+	}
+
+	for _, dev := range resp.Devices {
+		if dev.PoweredOn != nil || dev.Blanked != nil || len(dev.Inputs) > 0 || len(dev.Volumes) > 0 || len(dev.Mutes) > 0 {
+			return http.StatusMultiStatus
+		}
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+)
+
+func TestStateStatus(t *testing.T) {
+	on := true
+	resp := avcontrol.StateResponse{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+			"ITB-1101-D1": {PoweredOn: &on},
+		},
+	}
+
+	if status := stateStatus(resp); status != http.StatusOK {
+		t.Fatalf("got status %d, expected %d", status, http.StatusOK)
+	}
+
+	resp.Errors = []avcontrol.DeviceStateError{
+		{ID: "ITB-1101-D2", Field: "poweredOn", Error: "timed out", Code: avcontrol.ErrorCodeTimeout},
+	}
+
+	if status := stateStatus(resp); status != http.StatusMultiStatus {
+		t.Fatalf("got status %d, expected %d", status, http.StatusMultiStatus)
+	}
+
+	resp.Devices = nil
+	if status := stateStatus(resp); status != http.StatusInternalServerError {
+		t.Fatalf("got status %d, expected %d", status, http.StatusInternalServerError)
+	}
+}
@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"time"
 
 	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/byuoitav/av-control-api/validate"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -15,11 +19,18 @@ type Handlers struct {
 	Logger         *zap.Logger
 	State          avcontrol.StateGetSetter
 	DriverRegistry avcontrol.DriverRegistry
-}
 
-// Stats returns the status of the http server.
-func (h *Handlers) Stats(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{})
+	// Audit stores a record of every state change. If it is nil, state changes aren't recorded.
+	Audit avcontrol.AuditStore
+
+	// Auth authenticates requests. If it is nil, every request is allowed.
+	Auth avcontrol.Authenticator
+
+	// Schedules stores the schedules rooms are set on. If it is nil, schedules can't be managed.
+	Schedules avcontrol.ScheduleStore
+
+	// HealthMonitor checks the health of rooms in the background. If it is nil, there is no health summary.
+	HealthMonitor avcontrol.HealthMonitor
 }
 
 // Info returns a list of the registered drivers to the user in the body of an http response.
@@ -28,3 +39,83 @@ func (h *Handlers) Info(c *gin.Context) {
 		"drivers": h.DriverRegistry.List(),
 	})
 }
+
+// GetDrivers returns the capabilities of the devices each registered driver creates to the user as a JSON object in the body of an http response.
+func (h *Handlers) GetDrivers(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp := make(map[string]avcontrol.DeviceCapabilities)
+	for _, name := range h.DriverRegistry.List() {
+		driver, ok := h.DriverRegistry.Get(name).(avcontrol.DriverWithCapabilities)
+		if !ok {
+			str := "driver can't report its capabilities"
+			resp[name] = avcontrol.DeviceCapabilities{Error: &str}
+			continue
+		}
+
+		caps, err := driver.Capabilities(ctx)
+		if err != nil {
+			str := fmt.Sprintf("unable to get capabilities: %s", err)
+			resp[name] = avcontrol.DeviceCapabilities{Error: &str}
+			continue
+		}
+
+		resp[name] = avcontrol.DeviceCapabilities{Capabilities: caps}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"drivers": resp,
+	})
+}
+
+// ReloadDrivers reloads the driver config file, and returns the list of drivers whose config changed to the user in the body of an http response.
+func (h *Handlers) ReloadDrivers(c *gin.Context) {
+	registry, ok := h.DriverRegistry.(avcontrol.DriverRegistryWithReload)
+	if !ok {
+		writeErrorf(c, http.StatusNotImplemented, "driver registry can't be reloaded")
+		return
+	}
+
+	log := h.logger(c)
+
+	reloaded, err := registry.Reload()
+	if len(reloaded) > 0 {
+		log.Info("Reloaded drivers", zap.Strings("drivers", reloaded))
+	}
+
+	if err != nil {
+		log.Warn("unable to reload drivers", zap.Error(err))
+		writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	if reloaded == nil {
+		reloaded = []string{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reloaded": reloaded,
+	})
+}
+
+// ValidateRoom checks the room config in the body of the request for problems, like devices that use
+// drivers that aren't registered. The problems are returned to the user in the body of an http response;
+// a room without any problems is valid.
+func (h *Handlers) ValidateRoom(c *gin.Context) {
+	var room validate.Room
+	if err := c.BindJSON(&room); err != nil {
+		writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	problems := validate.Validate(room, h.DriverRegistry.List())
+	if problems == nil {
+		problems = []validate.Problem{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"valid":    len(problems) == 0,
+		"problems": problems,
+	})
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"net/http"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/gin-gonic/gin"
+)
+
+// GetHealthSummary returns the health of each device being monitored, along with when it last changed,
+// to the user as a JSON object in the body of an http response. Only the rooms the principal can access are returned.
+func (h *Handlers) GetHealthSummary(c *gin.Context) {
+	if h.HealthMonitor == nil {
+		writeErrorf(c, http.StatusNotImplemented, "health monitoring is not enabled")
+		return
+	}
+
+	summary := h.HealthMonitor.Summary()
+
+	if p, ok := c.Get(_cPrincipal); ok {
+		for room := range summary.Rooms {
+			if !p.(avcontrol.Principal).CanAccess(room) {
+				delete(summary.Rooms, room)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
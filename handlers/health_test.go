@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/gin-gonic/gin"
+)
+
+type staticMonitor avcontrol.HealthSummary
+
+func (m staticMonitor) Summary() avcontrol.HealthSummary {
+	rooms := make(map[string]avcontrol.RoomHealthSummary)
+	for id, room := range m.Rooms {
+		rooms[id] = room
+	}
+
+	return avcontrol.HealthSummary{Rooms: rooms}
+}
+
+func TestGetHealthSummary(t *testing.T) {
+	log := setLogger()
+	defer log.Sync()
+
+	h := Handlers{
+		Logger: log,
+		HealthMonitor: staticMonitor{
+			Rooms: map[string]avcontrol.RoomHealthSummary{
+				"ITB-1101":  {},
+				"JFSB-B104": {},
+			},
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	resp := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(resp)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/health/summary", nil)
+	c.Set(_cPrincipal, avcontrol.Principal{Name: "itb", Rooms: []string{"ITB-*"}})
+
+	h.GetHealthSummary(c)
+
+	if resp.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", resp.Code)
+	}
+
+	var summary avcontrol.HealthSummary
+	if err := json.Unmarshal(resp.Body.Bytes(), &summary); err != nil {
+		t.Fatalf("unable to unmarshal summary: %s", err)
+	}
+
+	if _, ok := summary.Rooms["ITB-1101"]; !ok || len(summary.Rooms) != 1 {
+		t.Fatalf("expected only the rooms the principal can access, got %+v", summary.Rooms)
+	}
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	_defaultHistoryLimit = 100
+	_maxHistoryLimit     = 1000
+)
+
+// GetRoomHistory returns the audit log of state changes in the room to the user as a JSON array in the body of an http response.
+// The optional since and until query parameters (RFC 3339 timestamps) limit the time range of the records returned,
+// and the optional device query parameter (which can be repeated) limits the records to those that included the given devices.
+//
+// At most limit records are returned (100 by default, and never more than 1000); if there are more, the most recent ones are returned.
+// To get older records, pass the time of the oldest record returned as the before query parameter.
+func (h *Handlers) GetRoomHistory(c *gin.Context) {
+	room := c.MustGet(_cRoom).(avcontrol.RoomConfig)
+	id := c.GetString(_cRequestID)
+
+	if h.Audit == nil {
+		writeErrorf(c, http.StatusNotImplemented, "room history is not enabled")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	log := h.logger(c)
+	if len(id) > 0 {
+		ctx = avcontrol.WithRequestID(ctx, id)
+		log = log.With(zap.String("requestID", id))
+	}
+
+	query := avcontrol.AuditQuery{
+		Room: room.ID,
+	}
+
+	parseTime := func(param string, dst *time.Time) bool {
+		val := c.Query(param)
+		if val == "" {
+			return true
+		}
+
+		t, err := time.Parse(time.RFC3339, val)
+		if err != nil {
+			writeErrorf(c, http.StatusBadRequest, "invalid %s: %s", param, err)
+			return false
+		}
+
+		*dst = t
+		return true
+	}
+
+	if !parseTime("since", &query.Since) || !parseTime("until", &query.Until) || !parseTime("before", &query.Before) {
+		return
+	}
+
+	query.Limit = _defaultHistoryLimit
+	if val := c.Query("limit"); val != "" {
+		limit, err := strconv.Atoi(val)
+		if err != nil || limit <= 0 {
+			writeErrorf(c, http.StatusBadRequest, "invalid limit: must be a positive integer")
+			return
+		}
+
+		query.Limit = limit
+		if query.Limit > _maxHistoryLimit {
+			query.Limit = _maxHistoryLimit
+		}
+	}
+
+	for _, dev := range c.QueryArray("device") {
+		query.Devices = append(query.Devices, avcontrol.DeviceID(dev))
+	}
+
+	log.Info("Getting room history", zap.String("room", room.ID))
+
+	recs, err := h.Audit.History(ctx, query)
+	if err != nil {
+		log.Warn("failed to get room history", zap.Error(err))
+		writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	if recs == nil {
+		recs = []avcontrol.AuditRecord{}
+	}
+
+	log.Info("Got room history", zap.Int("numRecords", len(recs)))
+	c.JSON(http.StatusOK, recs)
+}
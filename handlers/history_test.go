@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/gin-gonic/gin"
+)
+
+// memAudit is an in-memory avcontrol.AuditStore.
+type memAudit struct {
+	recs  []avcontrol.AuditRecord
+	query avcontrol.AuditQuery
+}
+
+func (m *memAudit) Record(ctx context.Context, rec avcontrol.AuditRecord) error {
+	m.recs = append(m.recs, rec)
+	return nil
+}
+
+func (m *memAudit) History(ctx context.Context, q avcontrol.AuditQuery) ([]avcontrol.AuditRecord, error) {
+	m.query = q
+	return m.recs, nil
+}
+
+func TestSetRoomSceneRecorded(t *testing.T) {
+	log := setLogger()
+	defer log.Sync()
+
+	audit := &memAudit{}
+	h := Handlers{
+		Logger:      log,
+		DataService: &sceneDS{},
+		State:       &echoGS{},
+		Audit:       audit,
+	}
+
+	gin.SetMode(gin.TestMode)
+	resp := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(resp)
+	c.Request, _ = http.NewRequest(http.MethodPut, "/room/:room/scene/:scene", nil)
+	c.Request.Header.Set(_hRequestID, "ID")
+	c.Params = gin.Params{
+		{
+			Key:   "room",
+			Value: "ITB-1101",
+		},
+		{
+			Key:   "scene",
+			Value: "shutdown",
+		},
+	}
+
+	h.RequestID(c)
+	h.Room(c)
+	h.SetRoomScene(c)
+
+	if resp.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", resp.Code)
+	}
+
+	if len(audit.recs) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(audit.recs))
+	}
+
+	rec := audit.recs[0]
+	if rec.RequestID != "ID" || rec.Room != "ITB-1101" || rec.Scene != "shutdown" || rec.Time.IsZero() {
+		t.Fatalf("unexpected record: %+v", rec)
+	}
+
+	if _, ok := rec.Response.Devices["ITB-1101-D1"]; !ok {
+		t.Fatalf("response was not recorded: %+v", rec)
+	}
+}
+
+func TestGetRoomHistory(t *testing.T) {
+	log := setLogger()
+	defer log.Sync()
+
+	audit := &memAudit{
+		recs: []avcontrol.AuditRecord{
+			{
+				RequestID: "ID",
+				Room:      "ITB-1101",
+			},
+		},
+	}
+
+	h := Handlers{
+		Logger:      log,
+		DataService: &sceneDS{},
+		Audit:       audit,
+	}
+
+	gin.SetMode(gin.TestMode)
+	resp := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(resp)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/room/:room/history?since=2020-06-01T09:00:00Z&device=ITB-1101-D1&device=ITB-1101-D2", nil)
+	c.Params = gin.Params{
+		{
+			Key:   "room",
+			Value: "ITB-1101",
+		},
+	}
+
+	h.RequestID(c)
+	h.Room(c)
+	h.GetRoomHistory(c)
+
+	if resp.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", resp.Code)
+	}
+
+	var recs []avcontrol.AuditRecord
+	if err := json.Unmarshal(resp.Body.Bytes(), &recs); err != nil {
+		t.Fatalf("error unmarshaling json: %s", err)
+	}
+
+	if len(recs) != 1 || recs[0].RequestID != "ID" {
+		t.Fatalf("unexpected history: %s", resp.Body.String())
+	}
+
+	since := time.Date(2020, 6, 1, 9, 0, 0, 0, time.UTC)
+	if audit.query.Room != "ITB-1101" || !audit.query.Since.Equal(since) || !audit.query.Until.IsZero() || len(audit.query.Devices) != 2 {
+		t.Fatalf("unexpected query: %+v", audit.query)
+	}
+
+	if audit.query.Limit != _defaultHistoryLimit {
+		t.Fatalf("got limit %d, expected the default of %d", audit.query.Limit, _defaultHistoryLimit)
+	}
+}
+
+func TestGetRoomHistoryLimit(t *testing.T) {
+	log := setLogger()
+	defer log.Sync()
+
+	tests := []struct {
+		query  string
+		status int
+		limit  int
+		before time.Time
+	}{
+		{query: "limit=10&before=2020-06-01T09:00:00.5Z", status: http.StatusOK, limit: 10, before: time.Date(2020, 6, 1, 9, 0, 0, 500000000, time.UTC)},
+		{query: "limit=5000", status: http.StatusOK, limit: _maxHistoryLimit},
+		{query: "limit=0", status: http.StatusBadRequest},
+		{query: "limit=ten", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			audit := &memAudit{}
+			h := Handlers{
+				Logger:      log,
+				DataService: &sceneDS{},
+				Audit:       audit,
+			}
+
+			gin.SetMode(gin.TestMode)
+			resp := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(resp)
+			c.Request, _ = http.NewRequest(http.MethodGet, "/room/:room/history?"+tt.query, nil)
+			c.Params = gin.Params{
+				{
+					Key:   "room",
+					Value: "ITB-1101",
+				},
+			}
+
+			h.RequestID(c)
+			h.Room(c)
+			h.GetRoomHistory(c)
+
+			if resp.Code != tt.status {
+				t.Fatalf("unexpected status code: %d", resp.Code)
+			}
+
+			if tt.status == http.StatusOK && audit.query.Limit != tt.limit {
+				t.Fatalf("got limit %d, expected %d", audit.query.Limit, tt.limit)
+			}
+
+			if !audit.query.Before.Equal(tt.before) {
+				t.Fatalf("got before %s, expected %s", audit.query.Before, tt.before)
+			}
+		})
+	}
+
+}
+
+func TestGetRoomHistoryInvalidTime(t *testing.T) {
+	log := setLogger()
+	defer log.Sync()
+
+	h := Handlers{
+		Logger:      log,
+		DataService: &sceneDS{},
+		Audit:       &memAudit{},
+	}
+
+	gin.SetMode(gin.TestMode)
+	resp := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(resp)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/room/:room/history?until=yesterday", nil)
+	c.Params = gin.Params{
+		{
+			Key:   "room",
+			Value: "ITB-1101",
+		},
+	}
+
+	h.RequestID(c)
+	h.Room(c)
+	h.GetRoomHistory(c)
+
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status code: %d", resp.Code)
+	}
+}
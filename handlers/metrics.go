@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "avcontrol",
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "How many http requests have been handled, by route and status code.",
+	}, []string{"method", "route", "code"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "avcontrol",
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "How long http requests took to handle, by route.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 20},
+	}, []string{"method", "route"})
+
+	roomRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "avcontrol",
+		Subsystem: "http",
+		Name:      "room_request_duration_seconds",
+		Help:      "How long http requests for each room took to handle, by route.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 20},
+	}, []string{"method", "route", "room"})
+
+	proxyRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "avcontrol",
+		Subsystem: "proxy",
+		Name:      "requests_total",
+		Help:      "How many requests have been proxied to another instance of the API, by host and status code.",
+	}, []string{"host", "code"})
+)
+
+// Metrics records the count and latency of client requests.
+func (h *Handlers) Metrics(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	// use the route instead of the path to keep the number of labels down
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+
+	took := time.Since(start).Seconds()
+
+	requestsTotal.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+	requestDuration.WithLabelValues(c.Request.Method, route).Observe(took)
+
+	// only rooms that exist are recorded, so that requests for made up rooms can't create new labels
+	if room, ok := c.Get(_cRoom); ok {
+		roomRequestDuration.WithLabelValues(c.Request.Method, route, room.(avcontrol.RoomConfig).ID).Observe(took)
+	}
+}
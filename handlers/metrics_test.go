@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	log := setLogger()
+	defer log.Sync()
+
+	h := Handlers{
+		Logger:      log,
+		DataService: &sceneDS{},
+		State:       &echoGS{},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/room/:room/health", h.Metrics, h.RequestID, h.Room, h.GetRoomHealth)
+
+	before := testutil.ToFloat64(requestsTotal.WithLabelValues(http.MethodGet, "/room/:room/health", "200"))
+
+	req, _ := http.NewRequest(http.MethodGet, "/room/ITB-1101/health", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", resp.Code)
+	}
+
+	after := testutil.ToFloat64(requestsTotal.WithLabelValues(http.MethodGet, "/room/:room/health", "200"))
+	if after-before != 1 {
+		t.Fatalf("expected request count to go up by 1, went from %v to %v", before, after)
+	}
+
+	if n := testutil.CollectAndCount(roomRequestDuration); n != 1 {
+		t.Fatalf("expected 1 room to be recorded, got %d", n)
+	}
+}
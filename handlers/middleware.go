@@ -13,12 +13,14 @@ import (
 const (
 	_cRequestID = "requestID"
 	_cRoom      = "room"
+	_cPrincipal = "principal"
 )
 
 const (
-	_hRequestID    = "X-Request-ID"
-	_hForwardedFor = "X-Forwarded-For"
-	_hContentType  = "Content-Type"
+	_hRequestID     = "X-Request-ID"
+	_hForwardedFor  = "X-Forwarded-For"
+	_hContentType   = "Content-Type"
+	_hAuthorization = "Authorization"
 )
 
 // RequestID requests that the client provides an id to be used in log statements.
@@ -29,7 +31,7 @@ func (h *Handlers) RequestID(c *gin.Context) {
 	} else {
 		uid, err := ksuid.NewRandom()
 		if err != nil {
-			c.String(http.StatusInternalServerError, err.Error())
+			writeError(c, http.StatusInternalServerError, err)
 			c.Abort()
 			return
 		}
@@ -43,7 +45,7 @@ func (h *Handlers) RequestID(c *gin.Context) {
 // Log logs the start and end of client requests.
 func (h *Handlers) Log(c *gin.Context) {
 	id := c.GetString(_cRequestID)
-	log := h.Logger
+	log := h.logger(c)
 	if len(id) > 0 {
 		log = log.With(zap.String("requestID", id))
 	}
@@ -51,6 +53,12 @@ func (h *Handlers) Log(c *gin.Context) {
 	start := time.Now()
 	log.Info("Starting request", zap.String("from", c.ClientIP()), zap.String("method", c.Request.Method), zap.String("path", c.Request.URL.Path))
 	c.Next()
+
+	// the principal isn't known until the request has been authenticated
+	if name := principalName(c); name != "" {
+		log = log.With(zap.String("principal", name))
+	}
+
 	log.Info("Finished request", zap.Int("statusCode", c.Writer.Status()), zap.Duration("took", time.Since(start)))
 }
 
@@ -58,13 +66,13 @@ func (h *Handlers) Log(c *gin.Context) {
 func (h *Handlers) Room(c *gin.Context) {
 	roomID := c.Param("room")
 	if roomID == "" {
-		c.String(http.StatusBadRequest, "must include room")
+		writeErrorf(c, http.StatusBadRequest, "must include room")
 		c.Abort()
 		return
 	}
 
 	id := c.GetString(_cRequestID)
-	log := h.Logger
+	log := h.logger(c)
 	if len(id) > 0 {
 		log = log.With(zap.String("requestID", id))
 	}
@@ -77,7 +85,7 @@ func (h *Handlers) Room(c *gin.Context) {
 	room, err := h.DataService.RoomConfig(ctx, roomID)
 	switch {
 	case err != nil:
-		c.String(http.StatusInternalServerError, "unable to get room %s", err)
+		writeErrorf(c, http.StatusInternalServerError, "unable to get room %s", err)
 		c.Abort()
 		return
 	}
@@ -137,7 +137,7 @@ func TestRoomWithRoomError(t *testing.T) {
 		t.Fatalf("correctly got room when it shouldn't have")
 	}
 
-	if string(body) != "unable to get room no room" {
+	if string(body) != `{"error":"unable to get room no room","code":"internal"}` {
 		t.Fatalf("wrong error generated: %s", body)
 	}
 }
@@ -165,7 +165,7 @@ func TestRoomWithoutRoom(t *testing.T) {
 		t.Fatalf("correctly got room when it shouldn't have")
 	}
 
-	if string(body) != "must include room" {
+	if string(body) != `{"error":"must include room","code":"bad_request"}` {
 		t.Fatalf("wrong error generated: %s", body)
 	}
 }
@@ -2,22 +2,42 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net"
 	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	avcontrol "github.com/byuoitav/av-control-api"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
-func (h *Handlers) Proxy(c *gin.Context) {
+// proxyRequest is a request that is being proxied to the instance of the API that controls its room.
+type proxyRequest struct {
+	url url.URL
+	ip  string
+	id  string
+	log *zap.Logger
+}
+
+// proxyTarget returns where to send c if its room is controlled by another instance of the API.
+// If this instance controls the room, c.Next is called and false is returned. If c has already been
+// proxied through this instance, an error is written to c and false is returned.
+func (h *Handlers) proxyTarget(c *gin.Context) (proxyRequest, bool) {
 	room := c.MustGet(_cRoom).(avcontrol.RoomConfig)
 
 	if room.Proxy.Host == "" || h.Host == "" || strings.EqualFold(h.Host, room.Proxy.Host) {
 		c.Next()
-		return
+		return proxyRequest{}, false
 	}
 
 	c.Abort()
@@ -25,52 +45,152 @@ func (h *Handlers) Proxy(c *gin.Context) {
 	// make sure there is no cycle
 	ip, _, _ := net.SplitHostPort(strings.TrimSpace(c.Request.RemoteAddr))
 	if strings.Contains(c.GetHeader(_hForwardedFor), ip) {
-		c.String(http.StatusBadRequest, "detected proxy cycle. please try again")
-		return
+		writeErrorf(c, http.StatusBadRequest, "detected proxy cycle. please try again")
+		return proxyRequest{}, false
 	}
 
 	id := c.GetString(_cRequestID)
-	log := h.Logger
+	log := h.logger(c)
 	if len(id) > 0 {
 		log = log.With(zap.String("requestID", id))
 	}
 
-	// proxy the request
 	url := *room.Proxy
 	url.Path = c.Request.URL.Path
-	log.Info("Proxying request", zap.String("url", url.String()))
+	url.RawQuery = c.Request.URL.RawQuery
 
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 25*time.Second)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(ctx, c.Request.Method, url.String(), c.Request.Body)
-	if err != nil {
-		c.String(http.StatusInternalServerError, "unable to build proxy request: %s", err)
-		return
-	}
+	return proxyRequest{
+		url: url,
+		ip:  ip,
+		id:  id,
+		log: log,
+	}, true
+}
 
-	req.Header = c.Request.Header.Clone()
+// startSpan starts the span that traces sending p to the other instance.
+func (p proxyRequest) startSpan(ctx context.Context) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "proxy",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("server.address", p.url.Host),
+			attribute.String("url.full", p.url.String()),
+		),
+	)
+}
 
+// setHeaders sets the headers that are added to every proxied request on header.
+// The trace in ctx is continued on the other instance.
+func (p proxyRequest) setHeaders(ctx context.Context, header http.Header) {
 	// set X-Forwarded-For
-	fwdFor := req.Header.Get(_hForwardedFor)
+	fwdFor := header.Get(_hForwardedFor)
 	if fwdFor == "" {
-		req.Header.Set(_hForwardedFor, ip)
+		header.Set(_hForwardedFor, p.ip)
 	} else {
-		req.Header.Set(_hForwardedFor, fwdFor+", "+ip)
+		header.Set(_hForwardedFor, fwdFor+", "+p.ip)
 	}
 
 	// set X-Request-ID
-	if req.Header.Get(_hRequestID) == "" {
-		req.Header.Set(_hRequestID, id)
+	if header.Get(_hRequestID) == "" {
+		header.Set(_hRequestID, p.id)
+	}
+
+	// continue this trace on the other instance
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+func (h *Handlers) Proxy(c *gin.Context) {
+	p, ok := h.proxyTarget(c)
+	if !ok {
+		return
 	}
 
+	// proxy the request
+	url := p.url
+	p.log.Info("Proxying request", zap.String("url", url.String()))
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 25*time.Second)
+	defer cancel()
+
+	ctx, span := p.startSpan(ctx)
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, c.Request.Method, url.String(), c.Request.Body)
+	if err != nil {
+		writeErrorf(c, http.StatusInternalServerError, "unable to build proxy request: %s", err)
+		return
+	}
+
+	req.Header = c.Request.Header.Clone()
+	p.setHeaders(ctx, req.Header)
+
 	// send the request
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		c.String(http.StatusInternalServerError, "unable to make proxy request: %s", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		proxyRequestsTotal.WithLabelValues(url.Host, "error").Inc()
+		writeErrorf(c, http.StatusInternalServerError, "unable to make proxy request: %s", err)
 		return
 	}
 	defer resp.Body.Close()
 
+	proxyRequestsTotal.WithLabelValues(url.Host, strconv.Itoa(resp.StatusCode)).Inc()
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+
 	c.DataFromReader(resp.StatusCode, resp.ContentLength, resp.Header.Get(_hContentType), resp.Body, nil)
 }
+
+// ProxyStream is like Proxy, but the response from the other instance is sent to the user as it is written,
+// with no timeout. It is used for routes that stream server-sent events (see StreamRoomState).
+func (h *Handlers) ProxyStream(c *gin.Context) {
+	p, ok := h.proxyTarget(c)
+	if !ok {
+		return
+	}
+
+	url := p.url
+	p.log.Info("Proxying stream", zap.String("url", url.String()))
+
+	ctx, span := p.startSpan(c.Request.Context())
+	defer span.End()
+
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = url.Scheme
+			req.URL.Host = url.Host
+			req.URL.Path = url.Path
+			req.URL.RawQuery = url.RawQuery
+			req.Host = url.Host
+
+			p.setHeaders(req.Context(), req.Header)
+		},
+		// flush each event as soon as it is received
+		FlushInterval: -1,
+		ModifyResponse: func(resp *http.Response) error {
+			proxyRequestsTotal.WithLabelValues(url.Host, strconv.Itoa(resp.StatusCode)).Inc()
+			span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			if errors.Is(err, context.Canceled) {
+				// the user stopped streaming
+				return
+			}
+
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			proxyRequestsTotal.WithLabelValues(url.Host, "error").Inc()
+
+			if !c.Writer.Written() {
+				writeErrorf(c, http.StatusInternalServerError, "unable to make proxy request: %s", err)
+			}
+		},
+	}
+
+	// X-Forwarded-For is set by setHeaders, so the ReverseProxy isn't given the
+	// remote address; otherwise it would add the client to the header again.
+	req := c.Request.WithContext(ctx)
+	req.RemoteAddr = ""
+
+	proxy.ServeHTTP(c.Writer, req)
+}
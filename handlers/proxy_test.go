@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"bufio"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -77,7 +81,7 @@ func TestProxyWithCycle(t *testing.T) {
 		t.Fatalf("error reading resp body: %s", err)
 	}
 
-	if string(body) != "detected proxy cycle. please try again" {
+	if string(body) != `{"error":"detected proxy cycle. please try again","code":"bad_request"}` {
 		t.Fatalf("did not error on cycle")
 	}
 }
@@ -185,3 +189,126 @@ func TestProxyWithoutHost(t *testing.T) {
 	h.Room(c)
 	h.Proxy(c)
 }
+
+func TestProxyQuery(t *testing.T) {
+	log := setLogger()
+	defer log.Sync()
+
+	queries := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries <- r.URL.RawQuery
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("unable to parse server url: %s", err)
+	}
+
+	h := Handlers{
+		Logger:      log,
+		DataService: &proxyDS{url: u},
+		Host:        "other.byu.edu",
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.PUT("/room/:room/state", h.RequestID, h.Room, h.Proxy)
+
+	tests := []string{
+		"volumeScale=percent",
+		"volumeScale=dB",
+		"maxAge=30s",
+		"maxAge=1m&volumeScale=percent",
+	}
+
+	for _, query := range tests {
+		t.Run(query, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPut, "/room/ITB-1101/state?"+query, nil)
+			req.RemoteAddr = "10.0.0.1:1234"
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			if resp.Code != http.StatusOK {
+				t.Fatalf("got status %d: %s", resp.Code, resp.Body.String())
+			}
+
+			if got := <-queries; got != query {
+				t.Fatalf("got query %q, expected %q", got, query)
+			}
+		})
+	}
+}
+
+func TestProxyStream(t *testing.T) {
+	log := setLogger()
+	defer log.Sync()
+
+	// the other instance sends one event and keeps the stream open
+	done := make(chan struct{})
+	fwdFor := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fwdFor <- r.Header.Get(_hForwardedFor)
+
+		w.Header().Set(_hContentType, "text/event-stream")
+		fmt.Fprintf(w, "event:state\ndata:%s\n\n", r.URL.RawQuery)
+		w.(http.Flusher).Flush()
+
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer upstream.Close()
+	defer close(done)
+
+	u, err := url.Parse(upstream.URL)
+	if err != nil {
+		t.Fatalf("unable to parse server url: %s", err)
+	}
+
+	h := Handlers{
+		Logger:      log,
+		DataService: &proxyDS{url: u},
+		Host:        "other.byu.edu",
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/room/:room/state/stream", h.RequestID, h.Room, h.ProxyStream)
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(server.URL + "/room/ITB-1101/state/stream?volumeScale=percent")
+	if err != nil {
+		t.Fatalf("unable to stream: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d", resp.StatusCode)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 2 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("unable to read event: %s", err)
+		}
+
+		lines = append(lines, strings.TrimSpace(line))
+	}
+
+	if lines[0] != "event:state" || lines[1] != "data:volumeScale=percent" {
+		t.Fatalf("got unexpected event: %q", lines)
+	}
+
+	// the client is only added to X-Forwarded-For once
+	if got := <-fwdFor; got != "127.0.0.1" {
+		t.Fatalf("got unexpected %s: %q", _hForwardedFor, got)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/byuoitav/av-control-api/audit"
+	"github.com/byuoitav/av-control-api/routing"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -13,10 +15,22 @@ import (
 // GetRoomConfiguration gets the RoomConfig and returns it to the user as a JSON object in the body of an http response.
 func (h *Handlers) GetRoomConfiguration(c *gin.Context) {
 	room := c.MustGet(_cRoom).(avcontrol.RoomConfig)
+
+	// device config overrides are usually credentials, so they aren't returned
+	devices := make(map[avcontrol.DeviceID]avcontrol.DeviceConfig, len(room.Devices))
+	for id, dev := range room.Devices {
+		dev.Config = nil
+		devices[id] = dev
+	}
+
+	room.Devices = devices
 	c.JSON(http.StatusOK, room)
 }
 
 // GetRoomState gets the state of the devices in the room and returns it to the user as a JSON object in the body of an http response.
+// If the state of some devices couldn't be gotten, the response is sent with 207 (Multi-Status), or 500 if none of it could be.
+// The optional maxAge query parameter (e.g. 5s) allows fields that have been cached within that duration to be returned,
+// and the optional volumeScale query parameter (native, percent, or dB) sets the scale volumes are returned on.
 func (h *Handlers) GetRoomState(c *gin.Context) {
 	room := c.MustGet(_cRoom).(avcontrol.RoomConfig)
 	id := c.GetString(_cRequestID)
@@ -24,29 +38,90 @@ func (h *Handlers) GetRoomState(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 20*time.Second)
 	defer cancel()
 
-	log := h.Logger
+	log := h.logger(c)
 	if len(id) > 0 {
 		ctx = avcontrol.WithRequestID(ctx, id)
 		log = log.With(zap.String("requestID", id))
 	}
 
+	if maxAge := c.Query("maxAge"); maxAge != "" {
+		d, err := time.ParseDuration(maxAge)
+		if err != nil {
+			writeErrorf(c, http.StatusBadRequest, "invalid maxAge: %s", err)
+			return
+		}
+
+		ctx = avcontrol.WithMaxAge(ctx, d)
+	}
+
+	ctx, ok := withVolumeScale(ctx, c)
+	if !ok {
+		return
+	}
+
 	log.Info("Getting room state", zap.String("room", room.ID))
 
 	resp, err := h.State.Get(ctx, room)
 	if err != nil {
 		log.Warn("failed to get room state", zap.Error(err))
-		c.String(http.StatusInternalServerError, err.Error())
+		writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	log.Info("Got room state", zap.Int("numErrors", len(resp.Errors)))
+	c.JSON(stateStatus(resp), resp)
+}
+
+// StreamRoomState streams changes to the state of the devices in the room to the user as server-sent events.
+// The first event contains the full state of the room, and each event after that only contains the fields that changed.
+// Like GetRoomState, volumes are sent on the scale given by the optional volumeScale query parameter.
+func (h *Handlers) StreamRoomState(c *gin.Context) {
+	room := c.MustGet(_cRoom).(avcontrol.RoomConfig)
+	id := c.GetString(_cRequestID)
+
+	streamer, ok := h.State.(avcontrol.StateStreamer)
+	if !ok {
+		writeErrorf(c, http.StatusNotImplemented, "streaming room state is not supported")
 		return
 	}
 
-	if len(resp.Errors) > 0 {
-		log.Info("Got room state", zap.Int("numErrors", len(resp.Errors)))
-		c.JSON(http.StatusInternalServerError, resp)
+	ctx := c.Request.Context()
+
+	log := h.logger(c)
+	if len(id) > 0 {
+		ctx = avcontrol.WithRequestID(ctx, id)
+		log = log.With(zap.String("requestID", id))
+	}
+
+	ctx, ok = withVolumeScale(ctx, c)
+	if !ok {
 		return
 	}
 
-	log.Info("Got room state")
-	c.JSON(http.StatusOK, resp)
+	changes, err := streamer.Stream(ctx, room)
+	if err != nil {
+		log.Warn("failed to stream room state", zap.Error(err))
+		writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	log.Info("Streaming room state", zap.String("room", room.ID))
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("Finished streaming room state")
+			return
+		case resp, ok := <-changes:
+			if !ok {
+				log.Info("Finished streaming room state")
+				return
+			}
+
+			c.SSEvent("state", resp)
+			c.Writer.Flush()
+		}
+	}
 }
 
 // GetRoomHealth gets the health status of all the devices in the room and returns it to the user as a JSON object in the body of an http response.
@@ -57,7 +132,7 @@ func (h *Handlers) GetRoomHealth(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
 
-	log := h.Logger
+	log := h.logger(c)
 	if len(id) > 0 {
 		ctx = avcontrol.WithRequestID(ctx, id)
 		log = log.With(zap.String("requestID", id))
@@ -68,7 +143,7 @@ func (h *Handlers) GetRoomHealth(c *gin.Context) {
 	resp, err := h.State.GetHealth(ctx, room)
 	if err != nil {
 		log.Warn("failed to get room health", zap.Error(err))
-		c.String(http.StatusInternalServerError, err.Error())
+		writeError(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -84,7 +159,7 @@ func (h *Handlers) GetRoomInfo(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
 
-	log := h.Logger
+	log := h.logger(c)
 	if len(id) > 0 {
 		ctx = avcontrol.WithRequestID(ctx, id)
 		log = log.With(zap.String("requestID", id))
@@ -95,7 +170,7 @@ func (h *Handlers) GetRoomInfo(c *gin.Context) {
 	resp, err := h.State.GetInfo(ctx, room)
 	if err != nil {
 		log.Warn("failed to get room info", zap.Error(err))
-		c.String(http.StatusInternalServerError, err.Error())
+		writeError(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -103,42 +178,175 @@ func (h *Handlers) GetRoomInfo(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+// GetRoomCapabilities gets the capabilities of all the devices in the room and returns them to the user as a JSON object in the body of an http response.
+func (h *Handlers) GetRoomCapabilities(c *gin.Context) {
+	room := c.MustGet(_cRoom).(avcontrol.RoomConfig)
+	id := c.GetString(_cRequestID)
+
+	getter, ok := h.State.(avcontrol.StateCapabilities)
+	if !ok {
+		writeErrorf(c, http.StatusNotImplemented, "getting device capabilities is not supported")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	log := h.logger(c)
+	if len(id) > 0 {
+		ctx = avcontrol.WithRequestID(ctx, id)
+		log = log.With(zap.String("requestID", id))
+	}
+
+	log.Info("Getting room capabilities", zap.String("room", room.ID))
+
+	resp, err := getter.GetCapabilities(ctx, room)
+	if err != nil {
+		log.Warn("failed to get room capabilities", zap.Error(err))
+		writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	log.Info("Got room capabilities")
+	c.JSON(http.StatusOK, resp)
+}
+
 // SetRoomState parses a new room state from the user's http request and sets the room state accordingly.
-// It returns an http response to the user with the status of the action.
+// It returns an http response to the user with the status of the action: 200 if every field was set, 207 (Multi-Status)
+// if only some of them were, and 500 if none of them were.
+// The optional volumeScale query parameter (native, percent, or dB) sets the scale volumes are given and returned on.
 func (h *Handlers) SetRoomState(c *gin.Context) {
 	var stateReq avcontrol.StateRequest
 	if err := c.Bind(&stateReq); err != nil {
-		c.String(http.StatusBadRequest, err.Error())
+		writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	h.setRoomState(c, stateReq, "")
+}
+
+// SetRoomScene sets the room state to match the scene named by the http parameter "scene".
+// It returns the same response as SetRoomState.
+func (h *Handlers) SetRoomScene(c *gin.Context) {
+	room := c.MustGet(_cRoom).(avcontrol.RoomConfig)
+	name := c.Param("scene")
+
+	scene, ok := room.Scenes[name]
+	if !ok {
+		writeErrorf(c, http.StatusNotFound, "scene %q does not exist in %s", name, room.ID)
+		return
+	}
+
+	h.setRoomState(c, scene, name)
+}
+
+// SetRoomRoute parses a route from the user's http request and switches every device between the source and the destination
+// so that the source is shown on the destination. It returns the same response as SetRoomState.
+func (h *Handlers) SetRoomRoute(c *gin.Context) {
+	room := c.MustGet(_cRoom).(avcontrol.RoomConfig)
+
+	var routeReq avcontrol.RouteRequest
+	if err := c.Bind(&routeReq); err != nil {
+		writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	stateReq, err := routing.Plan(room, routeReq)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, err)
 		return
 	}
 
+	h.setRoomState(c, stateReq, "")
+}
+
+// GetRoomRoutes gets the state of the devices in the room, and returns the source each display is showing
+// to the user as a JSON object in the body of an http response. Like GetRoomState, the status is 207 if
+// some devices failed to respond, and their errors are returned alongside the sources.
+func (h *Handlers) GetRoomRoutes(c *gin.Context) {
 	room := c.MustGet(_cRoom).(avcontrol.RoomConfig)
 	id := c.GetString(_cRequestID)
 
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 20*time.Second)
 	defer cancel()
 
-	log := h.Logger
+	log := h.logger(c)
 	if len(id) > 0 {
 		ctx = avcontrol.WithRequestID(ctx, id)
 		log = log.With(zap.String("requestID", id))
 	}
 
+	log.Info("Getting room routes", zap.String("room", room.ID))
+
+	state, err := h.State.Get(ctx, room)
+	if err != nil {
+		log.Warn("failed to get room state", zap.Error(err))
+		writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	routes := routing.Sources(room, state)
+	routes.Errors = state.Errors
+
+	log.Info("Got room routes", zap.Int("numErrors", len(routes.Errors)))
+	c.JSON(stateStatus(state), routes)
+}
+
+// setRoomState sets the room state to stateReq and records the result in the audit log.
+// scene is the name of the scene stateReq came from, if any.
+func (h *Handlers) setRoomState(c *gin.Context, stateReq avcontrol.StateRequest, scene string) {
+	room := c.MustGet(_cRoom).(avcontrol.RoomConfig)
+	id := c.GetString(_cRequestID)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 20*time.Second)
+	defer cancel()
+
+	log := h.logger(c)
+	if len(id) > 0 {
+		ctx = avcontrol.WithRequestID(ctx, id)
+		log = log.With(zap.String("requestID", id))
+	}
+
+	ctx, ok := withVolumeScale(ctx, c)
+	if !ok {
+		return
+	}
+
 	log.Info("Setting room state", zap.String("room", room.ID))
 
 	resp, err := h.State.Set(ctx, room, stateReq)
+	audit.Record(ctx, log, h.Audit, avcontrol.AuditRecord{
+		RequestID: id,
+		ClientIP:  c.ClientIP(),
+		Principal: principalName(c),
+		Room:      room.ID,
+		Scene:     scene,
+		Request:   stateReq,
+		Response:  resp,
+	}, err)
+
 	if err != nil {
 		log.Warn("failed to set room state", zap.Error(err))
-		c.String(http.StatusInternalServerError, err.Error())
+		writeError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	if len(resp.Errors) > 0 {
-		log.Info("Set room state", zap.Int("numErrors", len(resp.Errors)))
-		c.JSON(http.StatusInternalServerError, resp)
-		return
-	}
+	log.Info("Set room state", zap.Int("numErrors", len(resp.Errors)))
+	c.JSON(stateStatus(resp), resp)
+}
 
-	log.Info("Set room state")
-	c.JSON(http.StatusOK, resp)
+// withVolumeScale returns ctx with the volume scale from the optional volumeScale query parameter (e.g. percent).
+// ok is false if an error was sent to the user.
+func withVolumeScale(ctx context.Context, c *gin.Context) (context.Context, bool) {
+	scale := c.Query("volumeScale")
+	switch scale {
+	case "":
+		return ctx, true
+	case avcontrol.VolumeScaleNative, avcontrol.VolumeScalePercent, avcontrol.VolumeScaleDB:
+		return avcontrol.WithVolumeScale(ctx, scale), true
+	default:
+		writeErrorf(c, http.StatusBadRequest, "invalid volumeScale %q: must be one of %s, %s, or %s", scale,
+			avcontrol.VolumeScaleNative, avcontrol.VolumeScalePercent, avcontrol.VolumeScaleDB)
+		return ctx, false
+	}
 }
@@ -8,9 +8,13 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 
 	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/byuoitav/av-control-api/drivers"
+	"github.com/byuoitav/av-control-api/drivers/driverstest"
 	"github.com/gin-gonic/gin"
 )
 
@@ -121,6 +125,53 @@ func TestGetRoomConfiguration(t *testing.T) {
 	}
 }
 
+type deviceConfigDS struct{}
+
+func (d *deviceConfigDS) RoomConfig(ctx context.Context, id string) (avcontrol.RoomConfig, error) {
+	return avcontrol.RoomConfig{
+		ID: id,
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceConfig{
+			"ITB-1101-D1": {
+				Address: "ITB-1101-D1.byu.edu",
+				Driver:  "sony/bravia",
+				Config: map[string]interface{}{
+					"psk": "secret",
+				},
+			},
+		},
+	}, nil
+}
+
+func TestGetRoomConfigurationHidesDeviceConfig(t *testing.T) {
+	log := setLogger()
+	defer log.Sync()
+
+	h := Handlers{
+		Logger:      log,
+		DataService: &deviceConfigDS{},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/room/:room", h.Room, h.GetRoomConfiguration)
+
+	req, _ := http.NewRequest(http.MethodGet, "/room/ITB-1101", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", resp.Code)
+	}
+
+	if strings.Contains(resp.Body.String(), "secret") {
+		t.Fatalf("device config was returned: %s", resp.Body.String())
+	}
+
+	if !strings.Contains(resp.Body.String(), "ITB-1101-D1.byu.edu") {
+		t.Fatalf("device was not returned: %s", resp.Body.String())
+	}
+}
+
 func TestRoomStatePass(t *testing.T) {
 	log := setLogger()
 	defer log.Sync()
@@ -194,7 +245,7 @@ func TestRoomStateFail(t *testing.T) {
 		t.Fatalf("error reading resp body: %s", err)
 	}
 
-	if string(body) != "no room to get" {
+	if string(body) != `{"error":"no room to get","code":"internal"}` {
 		t.Fatalf("unexpected response: %s", string(body))
 	}
 }
@@ -271,7 +322,7 @@ func TestSetRoomStateFail(t *testing.T) {
 		t.Fatalf("error reading resp body: %s", err)
 	}
 
-	if string(body) != "no room to set" {
+	if string(body) != `{"error":"no room to set","code":"internal"}` {
 		t.Fatalf("wrong body received: %s", string(body))
 	}
 }
@@ -351,7 +402,7 @@ func TestRoomHealthFail(t *testing.T) {
 		t.Fatalf("error reading resp body: %s", err)
 	}
 
-	if string(body) != "can't get health" {
+	if string(body) != `{"error":"can't get health","code":"internal"}` {
 		t.Fatalf("unexpected response: %s", string(body))
 	}
 }
@@ -429,7 +480,443 @@ func TestRoomInfoFail(t *testing.T) {
 		t.Fatalf("error reading resp body: %s", err)
 	}
 
-	if string(body) != "can't get info" {
+	if string(body) != `{"error":"can't get info","code":"internal"}` {
 		t.Fatalf("unexpected response: %s", string(body))
 	}
 }
+
+type streamGS struct {
+	goodGS
+	changes []avcontrol.StateResponse
+}
+
+func (g *streamGS) Stream(ctx context.Context, room avcontrol.RoomConfig) (<-chan avcontrol.StateResponse, error) {
+	ch := make(chan avcontrol.StateResponse, len(g.changes))
+	for _, change := range g.changes {
+		ch <- change
+	}
+
+	close(ch)
+	return ch, nil
+}
+
+func TestStreamRoomState(t *testing.T) {
+	log := setLogger()
+	defer log.Sync()
+
+	d := goodDS{}
+	h := Handlers{
+		Logger:      log,
+		DataService: &d,
+		Host:        "http://byu.edu",
+		State: &streamGS{
+			changes: []avcontrol.StateResponse{
+				{
+					Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+						"ITB-1101-D1": {
+							PoweredOn: boolP(true),
+						},
+					},
+				},
+				{
+					Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+						"ITB-1101-D1": {
+							Blanked: boolP(false),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	resp := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(resp)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/room/:room/state/stream", nil)
+	c.Params = gin.Params{
+		{
+			Key:   "room",
+			Value: "ITB-1101",
+		},
+	}
+
+	h.RequestID(c)
+	h.Room(c)
+	h.StreamRoomState(c)
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("error reading resp body: %s", err)
+	}
+
+	expected := "event:state\ndata:{\"devices\":{\"ITB-1101-D1\":{\"poweredOn\":true}}}\n\n" +
+		"event:state\ndata:{\"devices\":{\"ITB-1101-D1\":{\"blanked\":false}}}\n\n"
+	if string(body) != expected {
+		t.Fatalf("unexpected response: %q", string(body))
+	}
+}
+
+func TestStreamRoomStateNotSupported(t *testing.T) {
+	log := setLogger()
+	defer log.Sync()
+
+	d := goodDS{}
+	h := Handlers{
+		Logger:      log,
+		DataService: &d,
+		Host:        "http://byu.edu",
+		State:       &goodGS{},
+	}
+
+	gin.SetMode(gin.TestMode)
+	resp := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(resp)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/room/:room/state/stream", nil)
+	c.Params = gin.Params{
+		{
+			Key:   "room",
+			Value: "ITB-1101",
+		},
+	}
+
+	h.RequestID(c)
+	h.Room(c)
+	h.StreamRoomState(c)
+
+	if resp.Code != http.StatusNotImplemented {
+		t.Fatalf("unexpected status code: %d", resp.Code)
+	}
+}
+
+func TestStreamRoomStateInvalidVolumeScale(t *testing.T) {
+	log := setLogger()
+	defer log.Sync()
+
+	d := goodDS{}
+	h := Handlers{
+		Logger:      log,
+		DataService: &d,
+		Host:        "http://byu.edu",
+		State:       &streamGS{},
+	}
+
+	gin.SetMode(gin.TestMode)
+	resp := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(resp)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/room/:room/state/stream?volumeScale=loudness", nil)
+	c.Params = gin.Params{
+		{
+			Key:   "room",
+			Value: "ITB-1101",
+		},
+	}
+
+	h.RequestID(c)
+	h.Room(c)
+	h.StreamRoomState(c)
+
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status code: %d", resp.Code)
+	}
+}
+
+func TestRoomStateInvalidMaxAge(t *testing.T) {
+	log := setLogger()
+	defer log.Sync()
+
+	d := goodDS{}
+	h := Handlers{
+		Logger:      log,
+		DataService: &d,
+		Host:        "http://byu.edu",
+		State:       &goodGS{},
+	}
+
+	gin.SetMode(gin.TestMode)
+	resp := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(resp)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/room/:room/state?maxAge=five", nil)
+	c.Params = gin.Params{
+		{
+			Key:   "room",
+			Value: "ITB-1101",
+		},
+	}
+
+	h.RequestID(c)
+	h.Room(c)
+	h.GetRoomState(c)
+
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status code: %d", resp.Code)
+	}
+}
+
+type sceneDS struct{}
+
+func (d *sceneDS) RoomConfig(ctx context.Context, id string) (avcontrol.RoomConfig, error) {
+	return avcontrol.RoomConfig{
+		ID:    "ITB-1101",
+		Proxy: &url.URL{},
+		Scenes: map[string]avcontrol.StateRequest{
+			"shutdown": {
+				Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+					"ITB-1101-D1": {
+						PoweredOn: boolP(false),
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+type echoGS struct {
+	goodGS
+}
+
+func (g *echoGS) Set(ctx context.Context, room avcontrol.RoomConfig, req avcontrol.StateRequest) (avcontrol.StateResponse, error) {
+	return avcontrol.StateResponse{
+		Devices: req.Devices,
+	}, nil
+}
+
+func TestSetRoomScene(t *testing.T) {
+	log := setLogger()
+	defer log.Sync()
+
+	h := Handlers{
+		Logger:      log,
+		DataService: &sceneDS{},
+		State:       &echoGS{},
+	}
+
+	gin.SetMode(gin.TestMode)
+	resp := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(resp)
+	c.Request, _ = http.NewRequest(http.MethodPut, "/room/:room/scene/:scene", nil)
+	c.Params = gin.Params{
+		{
+			Key:   "room",
+			Value: "ITB-1101",
+		},
+		{
+			Key:   "scene",
+			Value: "shutdown",
+		},
+	}
+
+	h.RequestID(c)
+	h.Room(c)
+	h.SetRoomScene(c)
+
+	if resp.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", resp.Code)
+	}
+
+	var res avcontrol.StateResponse
+	if err := json.Unmarshal(resp.Body.Bytes(), &res); err != nil {
+		t.Fatalf("error unmarshaling json: %s", err)
+	}
+
+	state := res.Devices["ITB-1101-D1"]
+	if state.PoweredOn == nil || *state.PoweredOn {
+		t.Fatalf("scene was not applied: %s", resp.Body.String())
+	}
+}
+
+func TestSetRoomSceneNotFound(t *testing.T) {
+	log := setLogger()
+	defer log.Sync()
+
+	h := Handlers{
+		Logger:      log,
+		DataService: &sceneDS{},
+		State:       &echoGS{},
+	}
+
+	gin.SetMode(gin.TestMode)
+	resp := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(resp)
+	c.Request, _ = http.NewRequest(http.MethodPut, "/room/:room/scene/:scene", nil)
+	c.Params = gin.Params{
+		{
+			Key:   "room",
+			Value: "ITB-1101",
+		},
+		{
+			Key:   "scene",
+			Value: "party",
+		},
+	}
+
+	h.RequestID(c)
+	h.Room(c)
+	h.SetRoomScene(c)
+
+	if resp.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status code: %d", resp.Code)
+	}
+}
+
+func TestValidateRoom(t *testing.T) {
+	log := setLogger()
+	defer log.Sync()
+
+	registry, err := drivers.NewWithConfig(nil)
+	if err != nil {
+		t.Fatalf("unable to create registry: %s", err)
+	}
+
+	registry.MustRegister("driverstest/a", &driverstest.Driver{})
+
+	h := Handlers{
+		Logger:         log,
+		DriverRegistry: registry,
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/v1/room/validate", h.ValidateRoom)
+
+	tests := []struct {
+		name string
+		body string
+		code int
+		resp string
+	}{
+		{
+			name: "Valid",
+			body: `{"id": "ITB-1101", "proxy": "http://localhost", "devices": {"ITB-1101-D1": {"address": "itb-1101-d1.byu.edu", "driver": "driverstest/a"}}}`,
+			code: http.StatusOK,
+			resp: `{"problems":[],"valid":true}`,
+		},
+		{
+			name: "Invalid",
+			body: `{"id": "ITB-1101", "devices": {"ITB-1101-D1": {"address": "itb-1101-d1.byu.edu", "driver": "sony/adcp"}}}`,
+			code: http.StatusOK,
+			resp: `{"problems":[{"field":"devices.ITB-1101-D1.driver","message":"driver \"sony/adcp\" is not registered"}],"valid":false}`,
+		},
+		{
+			name: "BadJSON",
+			body: `{"id": `,
+			code: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "/api/v1/room/validate", strings.NewReader(tt.body))
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			if resp.Code != tt.code {
+				t.Fatalf("expected status code %d, got %d: %s", tt.code, resp.Code, resp.Body.String())
+			}
+
+			if tt.resp != "" && resp.Body.String() != tt.resp {
+				t.Fatalf("unexpected body: %s", resp.Body.String())
+			}
+		})
+	}
+}
+
+func TestSetRoomRouteNoPath(t *testing.T) {
+	log := setLogger()
+	defer log.Sync()
+
+	d := goodDS{}
+	h := Handlers{
+		Logger:      log,
+		DataService: &d,
+		Host:        "http://byu.edu",
+		State:       &goodGS{},
+	}
+
+	gin.SetMode(gin.TestMode)
+	resp := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(resp)
+	c.Request, _ = http.NewRequest(http.MethodPut, "/room/:room/route", strings.NewReader(`{"source": "ITB-1101-LAPTOP", "destination": "ITB-1101-D1"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{
+		{
+			Key:   "room",
+			Value: "ITB-1101",
+		},
+	}
+
+	h.RequestID(c)
+	h.Room(c)
+	h.SetRoomRoute(c)
+
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected status code %d, got %d", http.StatusBadRequest, resp.Code)
+	}
+
+	if !strings.Contains(resp.Body.String(), `"code":"no_path"`) {
+		t.Fatalf("wrong body received: %s", resp.Body.String())
+	}
+}
+
+// partialGS returns the state of one device, and an error from another.
+type partialGS struct {
+	goodGS
+}
+
+func (g *partialGS) Get(ctx context.Context, room avcontrol.RoomConfig) (avcontrol.StateResponse, error) {
+	return avcontrol.StateResponse{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+			"ITB-1101-D1": {PoweredOn: boolP(true)},
+		},
+		Errors: []avcontrol.DeviceStateError{
+			{ID: "ITB-1101-SW1", Error: "timed out"},
+		},
+	}, nil
+}
+
+func TestGetRoomRoutesErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		state avcontrol.StateGetSetter
+		code  int
+	}{
+		{name: "Partial", state: &partialGS{}, code: http.StatusMultiStatus},
+		{name: "AllFailed", state: &goodGS{}, code: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := setLogger()
+			defer log.Sync()
+
+			h := Handlers{
+				Logger:      log,
+				DataService: &goodDS{},
+				Host:        "http://byu.edu",
+				State:       tt.state,
+			}
+
+			gin.SetMode(gin.TestMode)
+			resp := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(resp)
+			c.Request, _ = http.NewRequest(http.MethodGet, "/room/:room/routes", nil)
+			c.Params = gin.Params{{Key: "room", Value: "ITB-1101"}}
+
+			h.RequestID(c)
+			h.Room(c)
+			h.GetRoomRoutes(c)
+
+			if resp.Code != tt.code {
+				t.Fatalf("expected status code %d, got %d: %s", tt.code, resp.Code, resp.Body.String())
+			}
+
+			var routes avcontrol.RoomRoutes
+			if err := json.Unmarshal(resp.Body.Bytes(), &routes); err != nil {
+				t.Fatalf("unable to decode body: %s", err)
+			}
+
+			if len(routes.Errors) != 1 {
+				t.Fatalf("expected the device errors to be returned, got %+v", routes.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// scheduleError sends err to the user with the status code that matches it.
+func scheduleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, avcontrol.ErrScheduleNotFound):
+		writeError(c, http.StatusNotFound, err)
+	case errors.Is(err, avcontrol.ErrInvalidSchedule):
+		writeError(c, http.StatusBadRequest, err)
+	default:
+		writeError(c, http.StatusInternalServerError, err)
+	}
+}
+
+// GetSchedules returns every schedule to the user as a JSON array in the body of an http response.
+// The optional room query parameter limits the schedules returned to those for the given room.
+func (h *Handlers) GetSchedules(c *gin.Context) {
+	if h.Schedules == nil {
+		writeErrorf(c, http.StatusNotImplemented, "schedules are not enabled")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	scheds, err := h.Schedules.Schedules(ctx)
+	if err != nil {
+		h.logger(c).Warn("failed to get schedules", zap.Error(err))
+		scheduleError(c, err)
+		return
+	}
+
+	resp := []avcontrol.Schedule{}
+	for _, sched := range scheds {
+		if room := c.Query("room"); room == "" || room == sched.Room {
+			resp = append(resp, sched)
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetSchedule returns the schedule with the ID in the http parameter "schedule" to the user as a JSON object in the body of an http response.
+func (h *Handlers) GetSchedule(c *gin.Context) {
+	if h.Schedules == nil {
+		writeErrorf(c, http.StatusNotImplemented, "schedules are not enabled")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	sched, err := h.Schedules.Schedule(ctx, c.Param("schedule"))
+	if err != nil {
+		scheduleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sched)
+}
+
+// CreateSchedule parses a new schedule from the user's http request and stores it with a new ID.
+// The stored schedule is returned to the user as a JSON object in the body of an http response.
+func (h *Handlers) CreateSchedule(c *gin.Context) {
+	var sched avcontrol.Schedule
+	if err := c.Bind(&sched); err != nil {
+		writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	sched.ID = ""
+	h.putSchedule(c, sched, http.StatusCreated)
+}
+
+// PutSchedule parses a schedule from the user's http request and stores it with the ID in the http parameter "schedule",
+// replacing the schedule with that ID if it exists. The stored schedule is returned to the user as a JSON object in the body of an http response.
+func (h *Handlers) PutSchedule(c *gin.Context) {
+	var sched avcontrol.Schedule
+	if err := c.Bind(&sched); err != nil {
+		writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	sched.ID = c.Param("schedule")
+	h.putSchedule(c, sched, http.StatusOK)
+}
+
+func (h *Handlers) putSchedule(c *gin.Context, sched avcontrol.Schedule, status int) {
+	if h.Schedules == nil {
+		writeErrorf(c, http.StatusNotImplemented, "schedules are not enabled")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	log := h.logger(c)
+	if id := c.GetString(_cRequestID); len(id) > 0 {
+		log = log.With(zap.String("requestID", id))
+	}
+
+	sched, err := h.Schedules.PutSchedule(ctx, sched)
+	if err != nil {
+		log.Warn("failed to store schedule", zap.Error(err))
+		scheduleError(c, err)
+		return
+	}
+
+	log.Info("Stored schedule", zap.String("schedule", sched.ID), zap.String("room", sched.Room), zap.String("spec", sched.Spec))
+	c.JSON(status, sched)
+}
+
+// DeleteSchedule deletes the schedule with the ID in the http parameter "schedule".
+func (h *Handlers) DeleteSchedule(c *gin.Context) {
+	if h.Schedules == nil {
+		writeErrorf(c, http.StatusNotImplemented, "schedules are not enabled")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	log := h.logger(c)
+	if id := c.GetString(_cRequestID); len(id) > 0 {
+		log = log.With(zap.String("requestID", id))
+	}
+
+	id := c.Param("schedule")
+	if err := h.Schedules.DeleteSchedule(ctx, id); err != nil {
+		log.Warn("failed to delete schedule", zap.Error(err))
+		scheduleError(c, err)
+		return
+	}
+
+	log.Info("Deleted schedule", zap.String("schedule", id))
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/gin-gonic/gin"
+)
+
+// memSchedules is an in-memory avcontrol.ScheduleStore.
+type memSchedules map[string]avcontrol.Schedule
+
+func (m memSchedules) Schedules(ctx context.Context) ([]avcontrol.Schedule, error) {
+	var scheds []avcontrol.Schedule
+	for _, sched := range m {
+		scheds = append(scheds, sched)
+	}
+
+	return scheds, nil
+}
+
+func (m memSchedules) Schedule(ctx context.Context, id string) (avcontrol.Schedule, error) {
+	sched, ok := m[id]
+	if !ok {
+		return avcontrol.Schedule{}, fmt.Errorf("%w: %q", avcontrol.ErrScheduleNotFound, id)
+	}
+
+	return sched, nil
+}
+
+func (m memSchedules) PutSchedule(ctx context.Context, sched avcontrol.Schedule) (avcontrol.Schedule, error) {
+	if sched.Room == "" {
+		return avcontrol.Schedule{}, fmt.Errorf("%w: room is required", avcontrol.ErrInvalidSchedule)
+	}
+
+	if sched.ID == "" {
+		sched.ID = fmt.Sprintf("%d", len(m)+1)
+	}
+
+	m[sched.ID] = sched
+	return sched, nil
+}
+
+func (m memSchedules) DeleteSchedule(ctx context.Context, id string) error {
+	if _, ok := m[id]; !ok {
+		return fmt.Errorf("%w: %q", avcontrol.ErrScheduleNotFound, id)
+	}
+
+	delete(m, id)
+	return nil
+}
+
+func TestSchedules(t *testing.T) {
+	log := setLogger()
+	defer log.Sync()
+
+	scheds := memSchedules{}
+	h := Handlers{
+		Logger:    log,
+		Schedules: scheds,
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/schedules", h.GetSchedules)
+	r.POST("/schedules", h.CreateSchedule)
+	r.GET("/schedules/:schedule", h.GetSchedule)
+	r.PUT("/schedules/:schedule", h.PutSchedule)
+	r.DELETE("/schedules/:schedule", h.DeleteSchedule)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := do(http.MethodPost, "/schedules", `{"id": "ignored", "room": "JFSB-B104", "spec": "0 23 * * *", "scene": "shutdown"}`)
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("unexpected status code: %d: %s", resp.Code, resp.Body.String())
+	}
+
+	var sched avcontrol.Schedule
+	if err := json.Unmarshal(resp.Body.Bytes(), &sched); err != nil {
+		t.Fatalf("unable to unmarshal schedule: %s", err)
+	}
+
+	if sched.ID != "1" || sched.Room != "JFSB-B104" {
+		t.Fatalf("unexpected schedule: %+v", sched)
+	}
+
+	if resp := do(http.MethodPost, "/schedules", `{"spec": "0 23 * * *"}`); resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected invalid schedule to fail with %d, got %d", http.StatusBadRequest, resp.Code)
+	}
+
+	if resp := do(http.MethodPut, "/schedules/warmup", `{"room": "ITB-1101", "spec": "45 7 * * MON-FRI", "scene": "warmup"}`); resp.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d: %s", resp.Code, resp.Body.String())
+	}
+
+	resp = do(http.MethodGet, "/schedules?room=ITB-1101", "")
+	var list []avcontrol.Schedule
+	if err := json.Unmarshal(resp.Body.Bytes(), &list); err != nil {
+		t.Fatalf("unable to unmarshal schedules: %s", err)
+	}
+
+	if len(list) != 1 || list[0].ID != "warmup" {
+		t.Fatalf("unexpected schedules: %+v", list)
+	}
+
+	if resp := do(http.MethodDelete, "/schedules/1", ""); resp.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status code: %d: %s", resp.Code, resp.Body.String())
+	}
+
+	if resp := do(http.MethodGet, "/schedules/1", ""); resp.Code != http.StatusNotFound {
+		t.Fatalf("expected deleted schedule to be %d, got %d", http.StatusNotFound, resp.Code)
+	}
+}
+
+func TestSchedulesNotEnabled(t *testing.T) {
+	log := setLogger()
+	defer log.Sync()
+
+	h := Handlers{
+		Logger: log,
+	}
+
+	gin.SetMode(gin.TestMode)
+	resp := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(resp)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/schedules", nil)
+
+	h.GetSchedules(c)
+
+	if resp.Code != http.StatusNotImplemented {
+		t.Fatalf("unexpected status code: %d", resp.Code)
+	}
+}
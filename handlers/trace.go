@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/byuoitav/av-control-api/handlers")
+
+// Trace starts a span for each client request. If the request came from another
+// instance of the API (see Proxy), the span continues that instance's trace.
+func (h *Handlers) Trace(c *gin.Context) {
+	ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+
+	ctx, span := tracer.Start(ctx, c.Request.Method+" "+route,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", c.Request.Method),
+			attribute.String("http.route", route),
+			attribute.String("client.address", c.ClientIP()),
+		),
+	)
+	defer span.End()
+
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+
+	status := c.Writer.Status()
+	span.SetAttributes(attribute.Int("http.response.status_code", status))
+
+	if id := c.GetString(_cRequestID); id != "" {
+		span.SetAttributes(attribute.String("avcontrol.request_id", id))
+	}
+
+	if room := c.Param("room"); room != "" {
+		span.SetAttributes(attribute.String("avcontrol.room", room))
+	}
+
+	if name := principalName(c); name != "" {
+		span.SetAttributes(attribute.String("avcontrol.principal", name))
+	}
+
+	if status >= 500 {
+		span.SetStatus(codes.Error, "")
+	}
+}
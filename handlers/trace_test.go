@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type proxyDS struct {
+	url *url.URL
+}
+
+func (d *proxyDS) RoomConfig(ctx context.Context, id string) (avcontrol.RoomConfig, error) {
+	return avcontrol.RoomConfig{
+		ID:    id,
+		Proxy: d.url,
+	}, nil
+}
+
+func TestTraceProxy(t *testing.T) {
+	log := setLogger()
+	defer log.Sync()
+
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	// the trace context the other instance receives
+	var got trace.SpanContext
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = trace.SpanContextFromContext(otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header)))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	h := Handlers{
+		Logger:      log,
+		DataService: &proxyDS{url: u},
+		Host:        "http://byu.edu",
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/room/:room/state", h.Trace, h.RequestID, h.Room, h.Proxy)
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	req, _ := http.NewRequest(http.MethodGet, "/room/ITB-1101/state", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", resp.Code)
+	}
+
+	if got.TraceID().String() != traceID {
+		t.Fatalf("proxied request was not part of the caller's trace: got trace %q", got.TraceID())
+	}
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	// the proxy span ends before the server span
+	proxy, server := spans[0], spans[1]
+	if server.Name() != "GET /room/:room/state" {
+		t.Fatalf("unexpected server span name: %q", server.Name())
+	}
+
+	if proxy.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Fatalf("proxy span is not a child of the server span")
+	}
+
+	if got.SpanID() != proxy.SpanContext().SpanID() {
+		t.Fatalf("proxied request was not sent as part of the proxy span")
+	}
+}
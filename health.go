@@ -0,0 +1,57 @@
+package avcontrol
+
+import (
+	"context"
+	"time"
+)
+
+// HealthMonitor checks the health of devices in the background.
+type HealthMonitor interface {
+	// Summary returns the current health of every device being monitored.
+	Summary() HealthSummary
+}
+
+// HealthNotifier is told when the health of a device being monitored changes.
+type HealthNotifier interface {
+	// Notify is called with each change to the health of a device.
+	Notify(context.Context, HealthTransition) error
+}
+
+// HealthSummary maps room id to the health of the devices in each room being monitored.
+type HealthSummary struct {
+	Rooms map[string]RoomHealthSummary `json:"rooms"`
+}
+
+// RoomHealthSummary contains the current health of each device in a room being monitored.
+type RoomHealthSummary struct {
+	// LastChecked is when the health of the room was last checked.
+	LastChecked time.Time `json:"lastChecked"`
+
+	// Error is set if the last check failed entirely. The devices keep the health they had before it.
+	Error *string `json:"error,omitempty"`
+
+	Devices map[DeviceID]DeviceHealthSummary `json:"devices"`
+}
+
+// DeviceHealthSummary contains the current health of a device, and when it last changed.
+type DeviceHealthSummary struct {
+	Healthy bool    `json:"healthy"`
+	Error   *string `json:"error,omitempty"`
+
+	// LastChanged is when the device was first seen with its current health.
+	LastChanged time.Time `json:"lastChanged"`
+}
+
+// HealthTransition is a change to the health of a device.
+type HealthTransition struct {
+	Room    string   `json:"room"`
+	Device  DeviceID `json:"device"`
+	Healthy bool     `json:"healthy"`
+	Error   *string  `json:"error,omitempty"`
+
+	// Time is when the device was first seen with its new health.
+	Time time.Time `json:"time"`
+
+	// Since is when the device was first seen with its old health.
+	Since time.Time `json:"since"`
+}
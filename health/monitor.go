@@ -0,0 +1,272 @@
+// Package health checks the health of the devices in rooms in the background, and sends notifications when it changes.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"go.uber.org/zap"
+)
+
+var _ avcontrol.HealthMonitor = &Monitor{}
+
+// Monitor checks the health of the devices in Rooms every Interval, and tells each of Notifiers when the health of a device changes.
+// Like the scheduler, each instance of the API only checks the rooms it owns (see RoomConfig.OwnedBy).
+type Monitor struct {
+	// Host is the host of this instance of the API. If it is empty, only rooms that aren't proxied are checked.
+	Host        string
+	DataService avcontrol.DataService
+	State       avcontrol.StateGetSetter
+	Logger      *zap.Logger
+
+	// Rooms is the list of room IDs to check.
+	Rooms []string
+
+	// Interval is how often the rooms are checked. If it is zero, they are checked every minute.
+	Interval time.Duration
+
+	// Debounce is how long a device must keep its new health before it is considered changed,
+	// so that a device dropping a single check doesn't send a notification.
+	Debounce time.Duration
+
+	// Timeout is how long each room has to report its health. If it is zero, 10 seconds is used.
+	Timeout time.Duration
+
+	Notifiers []avcontrol.HealthNotifier
+
+	mu    sync.Mutex
+	rooms map[string]*roomStatus
+}
+
+type roomStatus struct {
+	checked time.Time
+	err     *string
+	devices map[avcontrol.DeviceID]*deviceStatus
+}
+
+type deviceStatus struct {
+	healthy bool
+	err     *string
+	changed time.Time
+
+	// pendingSince is when the device was first seen with the opposite health, if it hasn't been debounced yet
+	pendingSince time.Time
+}
+
+// Run checks the health of m.Rooms every m.Interval until ctx is done.
+func (m *Monitor) Run(ctx context.Context) {
+	interval := m.Interval
+	if interval == 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	m.Logger.Info("Monitoring room health", zap.Strings("rooms", m.Rooms), zap.Duration("interval", interval))
+	m.check(ctx, time.Now())
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.check(ctx, now)
+		}
+	}
+}
+
+// Summary returns the health of each device as of the last check.
+func (m *Monitor) Summary() avcontrol.HealthSummary {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	summary := avcontrol.HealthSummary{
+		Rooms: make(map[string]avcontrol.RoomHealthSummary, len(m.rooms)),
+	}
+
+	for id, room := range m.rooms {
+		rs := avcontrol.RoomHealthSummary{
+			LastChecked: room.checked,
+			Error:       room.err,
+			Devices:     make(map[avcontrol.DeviceID]avcontrol.DeviceHealthSummary, len(room.devices)),
+		}
+
+		for devID, dev := range room.devices {
+			rs.Devices[devID] = avcontrol.DeviceHealthSummary{
+				Healthy:     dev.healthy,
+				Error:       dev.err,
+				LastChanged: dev.changed,
+			}
+		}
+
+		summary.Rooms[id] = rs
+	}
+
+	return summary
+}
+
+// check checks the health of every room at the same time, and returns once they have all finished.
+func (m *Monitor) check(ctx context.Context, now time.Time) {
+	var wg sync.WaitGroup
+	for _, id := range m.Rooms {
+		wg.Add(1)
+
+		go func(id string) {
+			defer wg.Done()
+			m.checkRoom(ctx, id, now)
+		}(id)
+	}
+
+	wg.Wait()
+}
+
+func (m *Monitor) checkRoom(ctx context.Context, id string, now time.Time) {
+	timeout := m.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	log := m.Logger.With(zap.String("room", id))
+
+	room, err := m.DataService.RoomConfig(ctx, id)
+	if err != nil {
+		log.Warn("unable to get room config to check health", zap.Error(err))
+		m.failed(id, now, err)
+		return
+	}
+
+	if !room.OwnedBy(m.Host) {
+		log.Debug("Skipping health check for room owned by another instance", zap.String("owner", room.Proxy.Host))
+		m.forget(id)
+		return
+	}
+
+	health, err := m.State.GetHealth(ctx, room)
+	if err != nil {
+		log.Warn("unable to check room health", zap.Error(err))
+		m.failed(id, now, err)
+		return
+	}
+
+	for _, t := range m.update(id, health, now) {
+		m.notify(log, t)
+	}
+}
+
+// failed records that checking the health of the room id failed entirely.
+func (m *Monitor) failed(id string, now time.Time, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	room := m.room(id)
+	str := err.Error()
+	room.checked = now
+	room.err = &str
+}
+
+// forget removes the room id from the summary, so that health from before it moved to another instance isn't reported.
+func (m *Monitor) forget(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.rooms, id)
+}
+
+// room returns the status of the room id, creating it if it doesn't exist. m.mu must be held.
+func (m *Monitor) room(id string) *roomStatus {
+	if m.rooms == nil {
+		m.rooms = make(map[string]*roomStatus)
+	}
+
+	room, ok := m.rooms[id]
+	if !ok {
+		room = &roomStatus{
+			devices: make(map[avcontrol.DeviceID]*deviceStatus),
+		}
+		m.rooms[id] = room
+	}
+
+	return room
+}
+
+// update records the health of each device in the room id, and returns the devices whose health has changed.
+// The first health seen for a device isn't a change.
+func (m *Monitor) update(id string, health avcontrol.RoomHealth, now time.Time) []avcontrol.HealthTransition {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rs := m.room(id)
+	rs.checked = now
+	rs.err = nil
+
+	// forget about devices that have been removed from the room
+	for devID := range rs.devices {
+		if _, ok := health.Devices[devID]; !ok {
+			delete(rs.devices, devID)
+		}
+	}
+
+	var transitions []avcontrol.HealthTransition
+	for devID, dh := range health.Devices {
+		healthy := dh.Healthy != nil && *dh.Healthy && dh.Error == nil
+
+		dev, ok := rs.devices[devID]
+		switch {
+		case !ok:
+			rs.devices[devID] = &deviceStatus{
+				healthy: healthy,
+				err:     dh.Error,
+				changed: now,
+			}
+			continue
+		case healthy == dev.healthy:
+			dev.err = dh.Error
+			dev.pendingSince = time.Time{}
+			continue
+		case dev.pendingSince.IsZero():
+			dev.pendingSince = now
+		}
+
+		if now.Sub(dev.pendingSince) < m.Debounce {
+			continue
+		}
+
+		transitions = append(transitions, avcontrol.HealthTransition{
+			Room:    id,
+			Device:  devID,
+			Healthy: healthy,
+			Error:   dh.Error,
+			Time:    dev.pendingSince,
+			Since:   dev.changed,
+		})
+
+		dev.healthy = healthy
+		dev.err = dh.Error
+		dev.changed = dev.pendingSince
+		dev.pendingSince = time.Time{}
+	}
+
+	return transitions
+}
+
+// notify sends t to each of m.Notifiers in the background.
+func (m *Monitor) notify(log *zap.Logger, t avcontrol.HealthTransition) {
+	log = log.With(zap.String("deviceID", string(t.Device)))
+
+	for _, n := range m.Notifiers {
+		go func(n avcontrol.HealthNotifier) {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			if err := n.Notify(ctx, t); err != nil {
+				log.Warn("unable to send health notification", zap.Error(err))
+			}
+		}(n)
+	}
+}
@@ -0,0 +1,197 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/matryer/is"
+	"go.uber.org/zap"
+)
+
+func boolP(b bool) *bool {
+	return &b
+}
+
+func stringP(s string) *string {
+	return &s
+}
+
+type roomDS struct{}
+
+func (roomDS) RoomConfig(ctx context.Context, id string) (avcontrol.RoomConfig, error) {
+	return avcontrol.RoomConfig{
+		ID:    id,
+		Proxy: &url.URL{Host: "itb-cp1:8080"},
+	}, nil
+}
+
+// healthGS returns whatever health it is set to.
+type healthGS struct {
+	avcontrol.StateGetSetter
+	health avcontrol.RoomHealth
+}
+
+func (gs *healthGS) GetHealth(ctx context.Context, room avcontrol.RoomConfig) (avcontrol.RoomHealth, error) {
+	return gs.health, nil
+}
+
+// chanNotifier sends each transition it is notified of to a channel.
+type chanNotifier chan avcontrol.HealthTransition
+
+func (n chanNotifier) Notify(ctx context.Context, t avcontrol.HealthTransition) error {
+	n <- t
+	return nil
+}
+
+func TestMonitor(t *testing.T) {
+	is := is.New(t)
+
+	healthy := avcontrol.RoomHealth{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceHealth{
+			"ITB-1101-D1": {Healthy: boolP(true)},
+		},
+	}
+
+	unhealthy := avcontrol.RoomHealth{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceHealth{
+			"ITB-1101-D1": {Error: stringP("connection refused")},
+		},
+	}
+
+	gs := &healthGS{health: healthy}
+	notifications := make(chanNotifier, 10)
+	m := &Monitor{
+		Host:        "itb-cp1:8080",
+		DataService: roomDS{},
+		State:       gs,
+		Logger:      zap.NewNop(),
+		Rooms:       []string{"ITB-1101"},
+		Debounce:    2 * time.Minute,
+		Notifiers:   []avcontrol.HealthNotifier{notifications},
+	}
+
+	start := time.Date(2020, 6, 1, 9, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	m.check(ctx, start)
+
+	// a single failed check isn't a change
+	gs.health = unhealthy
+	m.check(ctx, start.Add(time.Minute))
+	gs.health = healthy
+	m.check(ctx, start.Add(2*time.Minute))
+
+	gs.health = unhealthy
+	m.check(ctx, start.Add(3*time.Minute))
+	m.check(ctx, start.Add(4*time.Minute))
+
+	dev := m.Summary().Rooms["ITB-1101"].Devices["ITB-1101-D1"]
+	is.True(dev.Healthy) // still debouncing
+	is.Equal(dev.LastChanged, start)
+
+	m.check(ctx, start.Add(5*time.Minute))
+
+	select {
+	case tr := <-notifications:
+		is.Equal(tr, avcontrol.HealthTransition{
+			Room:   "ITB-1101",
+			Device: "ITB-1101-D1",
+			Error:  stringP("connection refused"),
+			Time:   start.Add(3 * time.Minute),
+			Since:  start,
+		})
+	case <-time.After(time.Second):
+		t.Fatalf("no notification was sent")
+	}
+
+	summary := m.Summary().Rooms["ITB-1101"]
+	is.Equal(summary.LastChecked, start.Add(5*time.Minute))
+	is.Equal(summary.Devices["ITB-1101-D1"], avcontrol.DeviceHealthSummary{
+		Healthy:     false,
+		Error:       stringP("connection refused"),
+		LastChanged: start.Add(3 * time.Minute),
+	})
+
+	select {
+	case tr := <-notifications:
+		t.Fatalf("unexpected notification: %+v", tr)
+	default:
+	}
+}
+
+func TestMonitorOtherInstance(t *testing.T) {
+	is := is.New(t)
+
+	m := &Monitor{
+		Host:        "itb-cp2:8080",
+		DataService: roomDS{},
+		State:       &healthGS{},
+		Logger:      zap.NewNop(),
+		Rooms:       []string{"ITB-1101"},
+	}
+
+	m.check(context.Background(), time.Now())
+	is.Equal(len(m.Summary().Rooms), 0)
+
+	// rooms that move to another instance are removed from the summary
+	m.Host = "itb-cp1:8080"
+	m.State = &healthGS{health: avcontrol.RoomHealth{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceHealth{
+			"ITB-1101-D1": {Healthy: boolP(true)},
+		},
+	}}
+
+	m.check(context.Background(), time.Now())
+	is.Equal(len(m.Summary().Rooms), 1)
+
+	m.Host = ""
+	m.check(context.Background(), time.Now())
+	is.Equal(len(m.Summary().Rooms), 0)
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	is := is.New(t)
+
+	var mu sync.Mutex
+	var got avcontrol.HealthTransition
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	tr := avcontrol.HealthTransition{
+		Room:    "ITB-1101",
+		Device:  "ITB-1101-D1",
+		Healthy: true,
+		Time:    time.Date(2020, 6, 1, 9, 0, 0, 0, time.UTC),
+		Since:   time.Date(2020, 6, 1, 8, 0, 0, 0, time.UTC),
+	}
+
+	n := &WebhookNotifier{URL: server.URL + "/"}
+	is.NoErr(n.Notify(context.Background(), tr))
+
+	mu.Lock()
+	defer mu.Unlock()
+	is.Equal(got, tr)
+
+	n.URL = server.URL + "/missing"
+	is.True(n.Notify(context.Background(), tr) != nil) // non-2xx responses are an error
+}
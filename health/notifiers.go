@@ -0,0 +1,83 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"go.uber.org/zap"
+)
+
+var (
+	_ avcontrol.HealthNotifier = &LogNotifier{}
+	_ avcontrol.HealthNotifier = &WebhookNotifier{}
+)
+
+// LogNotifier logs each change to the health of a device; devices becoming unhealthy are logged as warnings.
+type LogNotifier struct {
+	Logger *zap.Logger
+}
+
+// Notify logs t.
+func (n *LogNotifier) Notify(ctx context.Context, t avcontrol.HealthTransition) error {
+	fields := []zap.Field{
+		zap.String("room", t.Room),
+		zap.String("deviceID", string(t.Device)),
+		zap.Time("since", t.Since),
+	}
+
+	if t.Error != nil {
+		fields = append(fields, zap.String("error", *t.Error))
+	}
+
+	if t.Healthy {
+		n.Logger.Info("Device is healthy", fields...)
+	} else {
+		n.Logger.Warn("Device is unhealthy", fields...)
+	}
+
+	return nil
+}
+
+// WebhookNotifier POSTs each change to the health of a device to URL as a JSON object.
+type WebhookNotifier struct {
+	URL string
+
+	// Client is used to send the requests. If it is nil, http.DefaultClient is used.
+	Client *http.Client
+}
+
+// Notify POSTs t to n.URL, and returns an error if the response isn't a 2xx.
+func (n *WebhookNotifier) Notify(ctx context.Context, t avcontrol.HealthTransition) error {
+	body, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("unable to marshal transition: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to build request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s responded with %s", n.URL, resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,240 @@
+// Package routing finds the paths signals take across the connections between devices in a room.
+package routing
+
+import (
+	"errors"
+	"fmt"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+)
+
+var (
+	ErrInvalidRoute = errors.New("invalid route")
+	ErrNoPath       = errors.New("no path from source to destination")
+)
+
+// Plan returns the state request that routes req.Source to req.Destination. The shortest path through the room's
+// connections is used, and every device in RoomConfig.Devices along it is switched to the input the signal comes in on.
+func Plan(room avcontrol.RoomConfig, req avcontrol.RouteRequest) (avcontrol.StateRequest, error) {
+	kind := req.Kind
+	switch kind {
+	case "":
+		kind = avcontrol.PortKindAudioVideo
+	case avcontrol.PortKindAudio, avcontrol.PortKindVideo, avcontrol.PortKindAudioVideo:
+	default:
+		return avcontrol.StateRequest{}, fmt.Errorf("%w: unknown kind %q", ErrInvalidRoute, req.Kind)
+	}
+
+	switch {
+	case req.Source == "" || req.Destination == "":
+		return avcontrol.StateRequest{}, fmt.Errorf("%w: source and destination are required", ErrInvalidRoute)
+	case req.Source == req.Destination:
+		return avcontrol.StateRequest{}, fmt.Errorf("%w: source and destination are the same", ErrInvalidRoute)
+	}
+
+	path := shortestPath(room.Connections, req.Source, req.Destination)
+	if path == nil {
+		return avcontrol.StateRequest{}, fmt.Errorf("%w: %s to %s", ErrNoPath, req.Source, req.Destination)
+	}
+
+	state := avcontrol.StateRequest{
+		Devices: make(map[avcontrol.DeviceID]avcontrol.DeviceState),
+	}
+
+	for i, conn := range path {
+		id := conn.To.Device
+		if _, ok := room.Devices[id]; !ok {
+			continue
+		}
+
+		// switch the output the signal leaves on to the input it came in on
+		output := req.Output
+		if i+1 < len(path) {
+			output = path[i+1].From.Port
+		}
+
+		input := conn.To.Port
+
+		var in avcontrol.Input
+		switch kind {
+		case avcontrol.PortKindAudio:
+			in.Audio = &input
+		case avcontrol.PortKindVideo:
+			in.Video = &input
+		case avcontrol.PortKindAudioVideo:
+			in.AudioVideo = &input
+		}
+
+		dev := state.Devices[id]
+		if dev.Inputs == nil {
+			dev.Inputs = make(map[string]avcontrol.Input)
+		}
+
+		dev.Inputs[output] = in
+		state.Devices[id] = dev
+	}
+
+	return state, nil
+}
+
+// shortestPath returns the connections from src to dst that pass through the fewest devices, or nil if there aren't any.
+// Connections are tried in the order they are given, so the same path is always found.
+func shortestPath(conns []avcontrol.Connection, src, dst avcontrol.DeviceID) []avcontrol.Connection {
+	// prev is the connection used to first reach each device
+	prev := map[avcontrol.DeviceID]int{
+		src: -1,
+	}
+
+	queue := []avcontrol.DeviceID{src}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		if cur == dst {
+			break
+		}
+
+		for i, conn := range conns {
+			if conn.From.Device != cur {
+				continue
+			}
+
+			if _, ok := prev[conn.To.Device]; ok {
+				continue
+			}
+
+			prev[conn.To.Device] = i
+			queue = append(queue, conn.To.Device)
+		}
+	}
+
+	if _, ok := prev[dst]; !ok {
+		return nil
+	}
+
+	var path []avcontrol.Connection
+	for cur := dst; prev[cur] != -1; cur = conns[prev[cur]].From.Device {
+		path = append([]avcontrol.Connection{conns[prev[cur]]}, path...)
+	}
+
+	return path
+}
+
+// Sources returns the source each display in room is showing, given the current state of the room.
+// Each display's current video input is followed back through the room's connections until it reaches
+// a device that nothing is connected to.
+func Sources(room avcontrol.RoomConfig, state avcontrol.StateResponse) avcontrol.RoomRoutes {
+	routes := avcontrol.RoomRoutes{
+		Displays: make(map[avcontrol.DeviceID]avcontrol.DisplayRoute),
+	}
+
+	for _, id := range displays(room) {
+		src, err := source(room, state, id)
+		if err != nil {
+			str := err.Error()
+			routes.Displays[id] = avcontrol.DisplayRoute{Error: &str}
+			continue
+		}
+
+		routes.Displays[id] = avcontrol.DisplayRoute{Source: src}
+	}
+
+	return routes
+}
+
+// displays returns the devices in room that connections go to, but not from.
+func displays(room avcontrol.RoomConfig) []avcontrol.DeviceID {
+	from := make(map[avcontrol.DeviceID]bool)
+	for _, conn := range room.Connections {
+		from[conn.From.Device] = true
+	}
+
+	var ids []avcontrol.DeviceID
+	seen := make(map[avcontrol.DeviceID]bool)
+
+	for _, conn := range room.Connections {
+		id := conn.To.Device
+		if _, ok := room.Devices[id]; !ok || from[id] || seen[id] {
+			continue
+		}
+
+		seen[id] = true
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+func source(room avcontrol.RoomConfig, state avcontrol.StateResponse, display avcontrol.DeviceID) (avcontrol.DeviceID, error) {
+	id, output := display, ""
+	visited := make(map[avcontrol.DeviceID]bool)
+
+	for {
+		if visited[id] {
+			return "", fmt.Errorf("%s is routed back to itself", id)
+		}
+		visited[id] = true
+
+		var in []avcontrol.Connection
+		for _, conn := range room.Connections {
+			if conn.To.Device == id {
+				in = append(in, conn)
+			}
+		}
+
+		if len(in) == 0 {
+			return id, nil
+		}
+
+		conn, err := incoming(room, state, id, output, in)
+		if err != nil {
+			return "", err
+		}
+
+		id = conn.From.Device
+		output = portName(room, id, "output", conn.From.Port)
+	}
+}
+
+// incoming returns the connection that output on the device id is currently showing, out of the connections in to it.
+func incoming(room avcontrol.RoomConfig, state avcontrol.StateResponse, id avcontrol.DeviceID, output string, in []avcontrol.Connection) (avcontrol.Connection, error) {
+	if _, ok := room.Devices[id]; !ok {
+		// a device that isn't controlled can't be switched, so it only makes sense with one input
+		if len(in) > 1 {
+			return avcontrol.Connection{}, fmt.Errorf("unable to tell which input %s is using", id)
+		}
+
+		return in[0], nil
+	}
+
+	cur := state.Devices[id].Inputs[output]
+
+	var input *string
+	switch {
+	case cur.AudioVideo != nil:
+		input = cur.AudioVideo
+	case cur.Video != nil:
+		input = cur.Video
+	case len(in) == 1:
+		return in[0], nil
+	default:
+		return avcontrol.Connection{}, fmt.Errorf("unable to get the current input of %s", id)
+	}
+
+	for _, conn := range in {
+		if portName(room, id, "input", conn.To.Port) == *input {
+			return conn, nil
+		}
+	}
+
+	return avcontrol.Connection{}, fmt.Errorf("%s is on input %q, which isn't connected to anything", id, *input)
+}
+
+// portName returns the name the driver uses for the port of type typ on the device id, which may be a label.
+func portName(room avcontrol.RoomConfig, id avcontrol.DeviceID, typ, name string) string {
+	if port, ok := room.Devices[id].Ports.Find(typ, name); ok {
+		return port.Name
+	}
+
+	return name
+}
@@ -0,0 +1,182 @@
+package routing
+
+import (
+	"errors"
+	"testing"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/google/go-cmp/cmp"
+)
+
+func stringP(s string) *string {
+	return &s
+}
+
+// room is a laptop and a document camera going into a switcher, which goes to a projector
+// through a passive extender, and to a confidence monitor.
+var room = avcontrol.RoomConfig{
+	ID: "ITB-1101",
+	Devices: map[avcontrol.DeviceID]avcontrol.DeviceConfig{
+		"ITB-1101-SW1": {
+			Ports: avcontrol.PortConfigs{
+				{Name: "in1", Type: "input", Label: "Laptop"},
+				{Name: "in2", Type: "input"},
+				{Name: "out1", Type: "output"},
+				{Name: "out2", Type: "output"},
+			},
+		},
+		"ITB-1101-D1":  {},
+		"ITB-1101-D2":  {},
+		"ITB-1101-DC1": {},
+	},
+	Connections: []avcontrol.Connection{
+		{
+			From: avcontrol.Endpoint{Device: "ITB-1101-LAPTOP"},
+			To:   avcontrol.Endpoint{Device: "ITB-1101-SW1", Port: "Laptop"},
+		},
+		{
+			From: avcontrol.Endpoint{Device: "ITB-1101-DC1"},
+			To:   avcontrol.Endpoint{Device: "ITB-1101-SW1", Port: "in2"},
+		},
+		{
+			From: avcontrol.Endpoint{Device: "ITB-1101-SW1", Port: "out1"},
+			To:   avcontrol.Endpoint{Device: "ITB-1101-HDBT1"},
+		},
+		{
+			From: avcontrol.Endpoint{Device: "ITB-1101-HDBT1"},
+			To:   avcontrol.Endpoint{Device: "ITB-1101-D1", Port: "hdmi1"},
+		},
+		{
+			From: avcontrol.Endpoint{Device: "ITB-1101-SW1", Port: "out2"},
+			To:   avcontrol.Endpoint{Device: "ITB-1101-D2", Port: "hdmi2"},
+		},
+	},
+}
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name  string
+		req   avcontrol.RouteRequest
+		state avcontrol.StateRequest
+		err   error
+	}{
+		{
+			name: "MultiHop",
+			req: avcontrol.RouteRequest{
+				Source:      "ITB-1101-LAPTOP",
+				Destination: "ITB-1101-D1",
+			},
+			state: avcontrol.StateRequest{
+				Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+					"ITB-1101-SW1": {
+						Inputs: map[string]avcontrol.Input{
+							"out1": {AudioVideo: stringP("Laptop")},
+						},
+					},
+					"ITB-1101-D1": {
+						Inputs: map[string]avcontrol.Input{
+							"": {AudioVideo: stringP("hdmi1")},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Video",
+			req: avcontrol.RouteRequest{
+				Source:      "ITB-1101-DC1",
+				Destination: "ITB-1101-SW1",
+				Output:      "out2",
+				Kind:        avcontrol.PortKindVideo,
+			},
+			state: avcontrol.StateRequest{
+				Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+					"ITB-1101-SW1": {
+						Inputs: map[string]avcontrol.Input{
+							"out2": {Video: stringP("in2")},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "NoPath",
+			req: avcontrol.RouteRequest{
+				Source:      "ITB-1101-D1",
+				Destination: "ITB-1101-LAPTOP",
+			},
+			err: ErrNoPath,
+		},
+		{
+			name: "SameDevice",
+			req: avcontrol.RouteRequest{
+				Source:      "ITB-1101-D1",
+				Destination: "ITB-1101-D1",
+			},
+			err: ErrInvalidRoute,
+		},
+		{
+			name: "UnknownKind",
+			req: avcontrol.RouteRequest{
+				Source:      "ITB-1101-LAPTOP",
+				Destination: "ITB-1101-D1",
+				Kind:        "hdmi",
+			},
+			err: ErrInvalidRoute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := Plan(room, tt.req)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected %q, got %v", tt.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unable to plan route: %s", err)
+			}
+
+			if diff := cmp.Diff(tt.state, state); diff != "" {
+				t.Errorf("generated incorrect state (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSources(t *testing.T) {
+	state := avcontrol.StateResponse{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+			"ITB-1101-SW1": {
+				Inputs: map[string]avcontrol.Input{
+					"out1": {AudioVideo: stringP("in1")},
+					"out2": {Video: stringP("in3")},
+				},
+			},
+			"ITB-1101-D1": {
+				Inputs: map[string]avcontrol.Input{
+					"": {AudioVideo: stringP("hdmi1")},
+				},
+			},
+		},
+	}
+
+	expected := avcontrol.RoomRoutes{
+		Displays: map[avcontrol.DeviceID]avcontrol.DisplayRoute{
+			"ITB-1101-D1": {
+				Source: "ITB-1101-LAPTOP",
+			},
+			"ITB-1101-D2": {
+				Error: stringP(`ITB-1101-SW1 is on input "in3", which isn't connected to anything`),
+			},
+		},
+	}
+
+	if diff := cmp.Diff(expected, Sources(room, state)); diff != "" {
+		t.Errorf("generated incorrect routes (-want, +got):\n%s", diff)
+	}
+}
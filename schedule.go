@@ -0,0 +1,58 @@
+package avcontrol
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrScheduleNotFound is returned by a ScheduleStore when a schedule doesn't exist.
+	ErrScheduleNotFound = errors.New("schedule not found")
+
+	// ErrInvalidSchedule is returned by a ScheduleStore when a schedule can't be stored because it isn't valid.
+	ErrInvalidSchedule = errors.New("invalid schedule")
+)
+
+// ScheduleStore stores the schedules that are run against rooms.
+type ScheduleStore interface {
+	// Schedules returns every stored schedule.
+	Schedules(context.Context) ([]Schedule, error)
+
+	// Schedule returns the schedule with the given ID.
+	// If it doesn't exist, an error wrapping ErrScheduleNotFound is returned.
+	Schedule(context.Context, string) (Schedule, error)
+
+	// PutSchedule creates or replaces the given Schedule, and returns it as it was stored.
+	// If the schedule doesn't have an ID, one is generated.
+	PutSchedule(context.Context, Schedule) (Schedule, error)
+
+	// DeleteSchedule deletes the schedule with the given ID.
+	// If it doesn't exist, an error wrapping ErrScheduleNotFound is returned.
+	DeleteSchedule(context.Context, string) error
+}
+
+// Schedule sets the state of a room at the times given by a cron spec,
+// either by applying one of the room's scenes or by setting a StateRequest.
+type Schedule struct {
+	// ID uniquely identifies the schedule.
+	ID string `json:"id"`
+
+	// Name is a human-readable description of the schedule (e.g. "Nightly shutdown").
+	Name string `json:"name,omitempty"`
+
+	// Room is the ID of the room the schedule sets the state of.
+	Room string `json:"room"`
+
+	// Spec is a standard five-field cron spec (e.g. "45 7 * * MON-FRI"), or a descriptor like "@daily".
+	// It is in the server's local time zone unless it starts with a time zone like "CRON_TZ=America/Denver ".
+	Spec string `json:"spec"`
+
+	// Scene is the name of the scene in the room to apply. Exactly one of Scene and Request must be set.
+	Scene string `json:"scene,omitempty"`
+
+	// Request is the state to set the room to. Exactly one of Scene and Request must be set.
+	Request *StateRequest `json:"request,omitempty"`
+
+	// Disabled keeps the schedule from running without deleting it.
+	Disabled bool `json:"disabled,omitempty"`
+}
@@ -0,0 +1,229 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/matryer/is"
+	"go.uber.org/zap"
+)
+
+func boolP(b bool) *bool {
+	return &b
+}
+
+func newStore(t *testing.T) avcontrol.ScheduleStore {
+	dir, err := ioutil.TempDir("", "av-control-api-schedule-test")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %s", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	s, err := NewStore(filepath.Join(dir, "schedules.db"))
+	if err != nil {
+		t.Fatalf("unable to open store: %s", err)
+	}
+
+	return s
+}
+
+func TestStore(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	s := newStore(t)
+
+	sched, err := s.PutSchedule(ctx, avcontrol.Schedule{
+		Name:  "Nightly shutdown",
+		Room:  "JFSB-B104",
+		Spec:  "0 23 * * *",
+		Scene: "shutdown",
+	})
+	is.NoErr(err)
+	is.True(sched.ID != "") // id was generated
+
+	sched.Disabled = true
+	_, err = s.PutSchedule(ctx, sched)
+	is.NoErr(err)
+
+	got, err := s.Schedule(ctx, sched.ID)
+	is.NoErr(err)
+	is.Equal(got, sched)
+
+	scheds, err := s.Schedules(ctx)
+	is.NoErr(err)
+	is.Equal(len(scheds), 1)
+
+	is.NoErr(s.DeleteSchedule(ctx, sched.ID))
+	_, err = s.Schedule(ctx, sched.ID)
+	is.True(errors.Is(err, avcontrol.ErrScheduleNotFound))
+	is.True(errors.Is(s.DeleteSchedule(ctx, sched.ID), avcontrol.ErrScheduleNotFound))
+}
+
+func TestStoreInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		sched avcontrol.Schedule
+	}{
+		{
+			name:  "NoRoom",
+			sched: avcontrol.Schedule{Spec: "0 23 * * *", Scene: "shutdown"},
+		},
+		{
+			name:  "NoState",
+			sched: avcontrol.Schedule{Room: "JFSB-B104", Spec: "0 23 * * *"},
+		},
+		{
+			name:  "SceneAndRequest",
+			sched: avcontrol.Schedule{Room: "JFSB-B104", Spec: "0 23 * * *", Scene: "shutdown", Request: &avcontrol.StateRequest{}},
+		},
+		{
+			name:  "BadSpec",
+			sched: avcontrol.Schedule{Room: "JFSB-B104", Spec: "at 11pm", Scene: "shutdown"},
+		},
+	}
+
+	s := newStore(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.PutSchedule(context.Background(), tt.sched)
+			if !errors.Is(err, avcontrol.ErrInvalidSchedule) {
+				t.Fatalf("expected %q, got %v", avcontrol.ErrInvalidSchedule, err)
+			}
+		})
+	}
+}
+
+type roomDS map[string]avcontrol.RoomConfig
+
+func (ds roomDS) RoomConfig(ctx context.Context, id string) (avcontrol.RoomConfig, error) {
+	room, ok := ds[id]
+	if !ok {
+		return avcontrol.RoomConfig{}, fmt.Errorf("%s: %w", id, avcontrol.ErrRoomNotFound)
+	}
+
+	return room, nil
+}
+
+// setGS records the requests it is asked to set.
+type setGS struct {
+	avcontrol.StateGetSetter
+	reqs []avcontrol.StateRequest
+}
+
+func (gs *setGS) Set(ctx context.Context, room avcontrol.RoomConfig, req avcontrol.StateRequest) (avcontrol.StateResponse, error) {
+	gs.reqs = append(gs.reqs, req)
+	return avcontrol.StateResponse{Devices: req.Devices}, nil
+}
+
+type memAudit []avcontrol.AuditRecord
+
+func (m *memAudit) Record(ctx context.Context, rec avcontrol.AuditRecord) error {
+	*m = append(*m, rec)
+	return nil
+}
+
+func (m *memAudit) History(ctx context.Context, q avcontrol.AuditQuery) ([]avcontrol.AuditRecord, error) {
+	return *m, nil
+}
+
+func TestRun(t *testing.T) {
+	is := is.New(t)
+
+	shutdown := avcontrol.StateRequest{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+			"JFSB-B104-D1": {PoweredOn: boolP(false)},
+		},
+	}
+
+	ds := roomDS{
+		"JFSB-B104": {
+			ID:     "JFSB-B104",
+			Proxy:  &url.URL{Host: "jfsb-cp1:8080"},
+			Scenes: map[string]avcontrol.StateRequest{"shutdown": shutdown},
+		},
+		"ITB-1101": {
+			ID:    "ITB-1101",
+			Proxy: &url.URL{Host: "itb-cp1:8080"},
+		},
+	}
+
+	gs := &setGS{}
+	audit := &memAudit{}
+	s := &Scheduler{
+		Host:        "JFSB-CP1:8080",
+		DataService: ds,
+		State:       gs,
+		Store:       newStore(t),
+		Audit:       audit,
+		Logger:      zap.NewNop(),
+	}
+
+	s.run(avcontrol.Schedule{ID: "1", Room: "JFSB-B104", Spec: "0 23 * * *", Scene: "shutdown"})
+	s.run(avcontrol.Schedule{ID: "2", Room: "JFSB-B104", Spec: "0 23 * * *", Scene: "missing"})
+	s.run(avcontrol.Schedule{ID: "3", Room: "ITB-1101", Spec: "0 23 * * *", Request: &shutdown}) // owned by another instance
+
+	is.Equal(gs.reqs, []avcontrol.StateRequest{shutdown})
+	is.Equal(len(*audit), 2)
+
+	rec := (*audit)[0]
+	is.Equal(rec.Schedule, "1")
+	is.Equal(rec.Scene, "shutdown")
+	is.Equal(rec.Request, shutdown)
+	is.Equal(rec.Response.Devices, shutdown.Devices)
+	is.Equal(rec.Error, "")
+
+	rec = (*audit)[1]
+	is.Equal(rec.Schedule, "2")
+	is.Equal(rec.Error, `scene "missing" does not exist in JFSB-B104`)
+}
+
+func TestPutSchedule(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := &Scheduler{
+		Host: "jfsb-cp1:8080",
+		DataService: roomDS{
+			"JFSB-B104": {ID: "JFSB-B104", Proxy: &url.URL{Host: "jfsb-cp1:8080"}},
+			"ITB-1101":  {ID: "ITB-1101", Proxy: &url.URL{Host: "itb-cp1:8080"}},
+		},
+		Store:  newStore(t),
+		Logger: zap.NewNop(),
+	}
+
+	// this instance would never run schedules for rooms owned by another one
+	_, err := s.PutSchedule(ctx, avcontrol.Schedule{Room: "ITB-1101", Spec: "45 7 * * MON-FRI", Scene: "warmup"})
+	is.True(errors.Is(err, avcontrol.ErrInvalidSchedule))
+
+	// or for rooms that don't exist
+	_, err = s.PutSchedule(ctx, avcontrol.Schedule{Room: "ITB-9999", Spec: "45 7 * * MON-FRI", Scene: "warmup"})
+	is.True(errors.Is(err, avcontrol.ErrInvalidSchedule))
+
+	scheds, err := s.Schedules(ctx)
+	is.NoErr(err)
+	is.Equal(len(scheds), 0)
+
+	sched, err := s.PutSchedule(ctx, avcontrol.Schedule{Room: "JFSB-B104", Spec: "45 7 * * MON-FRI", Scene: "warmup"})
+	is.NoErr(err)
+	is.Equal(len(s.cron.Entries()), 1)
+
+	sched.Disabled = true
+	_, err = s.PutSchedule(ctx, sched)
+	is.NoErr(err)
+	is.Equal(len(s.cron.Entries()), 0) // disabled schedules aren't run
+
+	sched.Disabled = false
+	_, err = s.PutSchedule(ctx, sched)
+	is.NoErr(err)
+	is.Equal(len(s.cron.Entries()), 1)
+
+	is.NoErr(s.DeleteSchedule(ctx, sched.ID))
+	is.Equal(len(s.cron.Entries()), 0)
+}
@@ -0,0 +1,228 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/byuoitav/av-control-api/audit"
+	"github.com/robfig/cron/v3"
+	"github.com/segmentio/ksuid"
+	"go.uber.org/zap"
+)
+
+var _ avcontrol.ScheduleStore = &Scheduler{}
+
+// Scheduler sets the state of rooms at the times given by the schedules in Store. Each instance of the API has its
+// own Store, so a Scheduler only accepts schedules for rooms that it owns (see RoomConfig.Proxy); schedules for
+// other rooms must be created on the instance that owns them. Schedules for rooms that have since moved to
+// another instance are skipped.
+//
+// Scheduler is also an avcontrol.ScheduleStore, so that changes made to schedules through it take effect right away.
+type Scheduler struct {
+	// Host is the host of this instance of the API. If it is empty, only rooms that aren't proxied are owned.
+	Host        string
+	DataService avcontrol.DataService
+	State       avcontrol.StateGetSetter
+	Store       avcontrol.ScheduleStore
+	Logger      *zap.Logger
+
+	// Audit stores the result of each schedule that is run. If it is nil, results are only logged.
+	Audit avcontrol.AuditStore
+
+	// Timeout is how long a schedule has to set the state of its room. If it is zero, 30 seconds is used.
+	Timeout time.Duration
+
+	once    sync.Once
+	mu      sync.Mutex
+	cron    *cron.Cron
+	entries map[string]cron.EntryID
+}
+
+func (s *Scheduler) init() {
+	s.once.Do(func() {
+		s.cron = cron.New()
+		s.entries = make(map[string]cron.EntryID)
+	})
+}
+
+// Start schedules every schedule in s.Store and starts running them. They stop running once ctx is done.
+func (s *Scheduler) Start(ctx context.Context) error {
+	s.init()
+
+	scheds, err := s.Store.Schedules(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get schedules: %w", err)
+	}
+
+	for _, sched := range scheds {
+		if err := s.schedule(sched); err != nil {
+			s.Logger.Warn("unable to schedule", zap.String("schedule", sched.ID), zap.Error(err))
+		}
+	}
+
+	s.cron.Start()
+	s.Logger.Info("Started scheduler", zap.Int("numSchedules", len(scheds)))
+
+	go func() {
+		<-ctx.Done()
+		<-s.cron.Stop().Done()
+	}()
+
+	return nil
+}
+
+// Schedules returns every schedule in s.Store.
+func (s *Scheduler) Schedules(ctx context.Context) ([]avcontrol.Schedule, error) {
+	return s.Store.Schedules(ctx)
+}
+
+// Schedule returns the schedule in s.Store with the given id.
+func (s *Scheduler) Schedule(ctx context.Context, id string) (avcontrol.Schedule, error) {
+	return s.Store.Schedule(ctx, id)
+}
+
+// PutSchedule stores sched in s.Store, and replaces the schedule with the same ID if it was running.
+// If sched.Room doesn't exist or is owned by another instance, an error wrapping avcontrol.ErrInvalidSchedule
+// is returned, since this instance would never run it.
+func (s *Scheduler) PutSchedule(ctx context.Context, sched avcontrol.Schedule) (avcontrol.Schedule, error) {
+	if sched.Room != "" {
+		room, err := s.DataService.RoomConfig(ctx, sched.Room)
+		switch {
+		case errors.Is(err, avcontrol.ErrRoomNotFound):
+			return avcontrol.Schedule{}, fmt.Errorf("%s doesn't exist: %w", sched.Room, avcontrol.ErrInvalidSchedule)
+		case err != nil:
+			return avcontrol.Schedule{}, fmt.Errorf("unable to get room config: %w", err)
+		}
+
+		if !room.OwnedBy(s.Host) {
+			return avcontrol.Schedule{}, fmt.Errorf("%s is controlled by %s; create its schedules there: %w", room.ID, room.Proxy.Host, avcontrol.ErrInvalidSchedule)
+		}
+	}
+
+	sched, err := s.Store.PutSchedule(ctx, sched)
+	if err != nil {
+		return avcontrol.Schedule{}, err
+	}
+
+	s.init()
+	if err := s.schedule(sched); err != nil {
+		return sched, fmt.Errorf("unable to schedule: %w", err)
+	}
+
+	return sched, nil
+}
+
+// DeleteSchedule deletes the schedule with the given id from s.Store, and stops running it.
+func (s *Scheduler) DeleteSchedule(ctx context.Context, id string) error {
+	if err := s.Store.DeleteSchedule(ctx, id); err != nil {
+		return err
+	}
+
+	s.init()
+	s.unschedule(id)
+	return nil
+}
+
+// schedule starts running sched, replacing the entry for the schedule with the same ID.
+func (s *Scheduler) schedule(sched avcontrol.Schedule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id, ok := s.entries[sched.ID]; ok {
+		s.cron.Remove(id)
+		delete(s.entries, sched.ID)
+	}
+
+	if sched.Disabled {
+		return nil
+	}
+
+	id, err := s.cron.AddFunc(sched.Spec, func() {
+		s.run(sched)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.entries[sched.ID] = id
+	return nil
+}
+
+// unschedule stops running the schedule with the given id.
+func (s *Scheduler) unschedule(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.entries[id]; ok {
+		s.cron.Remove(entry)
+		delete(s.entries, id)
+	}
+}
+
+// run sets the state of sched.Room, if this instance owns it, and records the result.
+func (s *Scheduler) run(sched avcontrol.Schedule) {
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	reqID := ksuid.New().String()
+	ctx = avcontrol.WithRequestID(ctx, reqID)
+	log := s.Logger.With(zap.String("requestID", reqID), zap.String("schedule", sched.ID), zap.String("room", sched.Room))
+
+	room, err := s.DataService.RoomConfig(ctx, sched.Room)
+	if err != nil {
+		log.Warn("unable to get room config to run schedule", zap.Error(err))
+		return
+	}
+
+	if !room.OwnedBy(s.Host) {
+		log.Warn("Skipping schedule for a room controlled by another instance", zap.String("owner", room.Proxy.Host))
+		return
+	}
+
+	rec := avcontrol.AuditRecord{
+		RequestID: reqID,
+		Room:      room.ID,
+		Scene:     sched.Scene,
+		Schedule:  sched.ID,
+	}
+
+	var resp avcontrol.StateResponse
+	switch {
+	case sched.Request != nil:
+		rec.Request = *sched.Request
+	default:
+		scene, ok := room.Scenes[sched.Scene]
+		if !ok {
+			err = fmt.Errorf("scene %q does not exist in %s", sched.Scene, room.ID)
+			break
+		}
+
+		rec.Request = scene
+	}
+
+	if err == nil {
+		log.Info("Running schedule", zap.String("name", sched.Name))
+		resp, err = s.State.Set(ctx, room, rec.Request)
+	}
+
+	rec.Response = resp
+	audit.Record(ctx, log, s.Audit, rec, err)
+
+	switch {
+	case err != nil:
+		log.Warn("failed to run schedule", zap.Error(err))
+	case len(resp.Errors) > 0:
+		log.Info("Ran schedule", zap.Int("numErrors", len(resp.Errors)))
+	default:
+		log.Info("Ran schedule")
+	}
+}
@@ -0,0 +1,147 @@
+// Package schedule stores schedules, and sets the state of rooms at the times they give.
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/robfig/cron/v3"
+	"github.com/segmentio/ksuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+const _schedulesBucket = "schedules"
+
+var _ avcontrol.ScheduleStore = &store{}
+
+// store is an avcontrol.ScheduleStore backed by bbolt. Schedules are stored as JSON, keyed by their ID.
+type store struct {
+	db *bolt.DB
+}
+
+// NewStore opens (or creates) the schedule store at path and returns a ScheduleStore backed by it.
+func NewStore(path string) (avcontrol.ScheduleStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("unable to open schedule store: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(_schedulesBucket))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize schedule store: %w", err)
+	}
+
+	return &store{
+		db: db,
+	}, nil
+}
+
+// Schedules returns every schedule in the store, ordered by ID.
+func (s *store) Schedules(ctx context.Context) ([]avcontrol.Schedule, error) {
+	var scheds []avcontrol.Schedule
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(_schedulesBucket)).ForEach(func(k, v []byte) error {
+			var sched avcontrol.Schedule
+			if err := json.Unmarshal(v, &sched); err != nil {
+				return fmt.Errorf("unable to unmarshal schedule %q: %w", k, err)
+			}
+
+			scheds = append(scheds, sched)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get schedules: %w", err)
+	}
+
+	return scheds, nil
+}
+
+// Schedule returns the schedule with the given id.
+func (s *store) Schedule(ctx context.Context, id string) (avcontrol.Schedule, error) {
+	var sched avcontrol.Schedule
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		buf := tx.Bucket([]byte(_schedulesBucket)).Get([]byte(id))
+		if buf == nil {
+			return fmt.Errorf("%w: %q", avcontrol.ErrScheduleNotFound, id)
+		}
+
+		if err := json.Unmarshal(buf, &sched); err != nil {
+			return fmt.Errorf("unable to unmarshal schedule: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return avcontrol.Schedule{}, err
+	}
+
+	return sched, nil
+}
+
+// PutSchedule validates sched and stores it, generating an ID for it if it doesn't have one.
+func (s *store) PutSchedule(ctx context.Context, sched avcontrol.Schedule) (avcontrol.Schedule, error) {
+	if err := validate(sched); err != nil {
+		return avcontrol.Schedule{}, err
+	}
+
+	if sched.ID == "" {
+		sched.ID = ksuid.New().String()
+	}
+
+	buf, err := json.Marshal(sched)
+	if err != nil {
+		return avcontrol.Schedule{}, fmt.Errorf("unable to marshal schedule: %w", err)
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(_schedulesBucket)).Put([]byte(sched.ID), buf)
+	})
+	if err != nil {
+		return avcontrol.Schedule{}, fmt.Errorf("unable to store schedule: %w", err)
+	}
+
+	return sched, nil
+}
+
+// DeleteSchedule deletes the schedule with the given id.
+func (s *store) DeleteSchedule(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(_schedulesBucket))
+		if b.Get([]byte(id)) == nil {
+			return fmt.Errorf("%w: %q", avcontrol.ErrScheduleNotFound, id)
+		}
+
+		if err := b.Delete([]byte(id)); err != nil {
+			return fmt.Errorf("unable to delete schedule: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// validate returns an error wrapping avcontrol.ErrInvalidSchedule if sched can't be run.
+func validate(sched avcontrol.Schedule) error {
+	switch {
+	case sched.Room == "":
+		return fmt.Errorf("%w: room is required", avcontrol.ErrInvalidSchedule)
+	case sched.Scene == "" && sched.Request == nil:
+		return fmt.Errorf("%w: scene or request is required", avcontrol.ErrInvalidSchedule)
+	case sched.Scene != "" && sched.Request != nil:
+		return fmt.Errorf("%w: only one of scene and request can be set", avcontrol.ErrInvalidSchedule)
+	}
+
+	if _, err := cron.ParseStandard(sched.Spec); err != nil {
+		return fmt.Errorf("%w: invalid spec: %s", avcontrol.ErrInvalidSchedule, err)
+	}
+
+	return nil
+}
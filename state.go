@@ -20,3 +20,17 @@ type StateGetSetter interface {
 	// TODO change to `RoomInfo`
 	GetInfo(context.Context, RoomConfig) (RoomInfo, error)
 }
+
+// StateStreamer represents something that can stream changes to device state.
+type StateStreamer interface {
+	// Stream returns a channel that receives a StateResponse containing only the fields
+	// that changed whenever the state of a device in the given Room changes. The channel
+	// is closed once the context is done.
+	Stream(context.Context, RoomConfig) (<-chan StateResponse, error)
+}
+
+// StateCapabilities represents something that can report what the devices in a room can do.
+type StateCapabilities interface {
+	// GetCapabilities returns the capabilities of each device in the given Room.
+	GetCapabilities(context.Context, RoomConfig) (RoomCapabilities, error)
+}
@@ -0,0 +1,402 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"go.uber.org/zap"
+)
+
+type adjustDeviceRequest struct {
+	id     avcontrol.DeviceID
+	device avcontrol.DeviceConfig
+	adjust avcontrol.DeviceAdjustment
+	driver avcontrol.Driver
+	log    *zap.Logger
+	cache  *Cache
+	scale  string
+}
+
+// adjustKey is a field of a device that can be adjusted, like "volumes.Mic1Gain".
+type adjustKey struct {
+	id    avcontrol.DeviceID
+	field string
+}
+
+// adjustDevices applies each of the adjustments in adjusts at the same time,
+// and returns once every device has finished.
+func (gs *GetSetter) adjustDevices(ctx context.Context, log *zap.Logger, room avcontrol.RoomConfig, adjusts map[avcontrol.DeviceID]avcontrol.DeviceAdjustment) []*setDeviceStateResponse {
+	var resps []*setDeviceStateResponse
+	respCh := make(chan *setDeviceStateResponse)
+	defer close(respCh)
+
+	for id, adjust := range adjusts {
+		dev := room.Devices[id]
+		req := adjustDeviceRequest{
+			id:     id,
+			device: dev,
+			adjust: adjust,
+			driver: gs.DriverRegistry.Get(dev.Driver),
+			log:    log.With(zap.String("deviceID", string(id))),
+			cache:  gs.Cache,
+			scale:  avcontrol.CtxVolumeScale(ctx),
+		}
+
+		go func() {
+			respCh <- gs.adjust(ctx, req)
+		}()
+	}
+
+	for len(resps) < len(adjusts) {
+		resps = append(resps, <-respCh)
+	}
+
+	return resps
+}
+
+// adjust reads the fields req changes from the device, and sets them to their new values.
+// Adjustments to the same field of a device are run one at a time, so that they don't read the same value.
+func (gs *GetSetter) adjust(ctx context.Context, req adjustDeviceRequest) *setDeviceStateResponse {
+	unlock, err := gs.lockFields(ctx, req.id, req.fields())
+	if err != nil {
+		return &setDeviceStateResponse{
+			id: req.id,
+			errors: []avcontrol.DeviceStateError{{
+				ID:    req.id,
+				Error: fmt.Sprintf("unable to wait for other adjustments: %s", err),
+				Code:  deviceErrorCode(err),
+			}},
+		}
+	}
+	defer unlock()
+
+	state, errs := req.read(ctx)
+	if state.PoweredOn == nil && state.Blanked == nil && len(state.Volumes) == 0 && len(state.Mutes) == 0 {
+		return &setDeviceStateResponse{
+			id:     req.id,
+			errors: errs,
+		}
+	}
+
+	// volumes were already converted to native values by read
+	set := setDeviceStateRequest{
+		id:     req.id,
+		device: req.device,
+		state:  state,
+		driver: req.driver,
+		log:    req.log,
+		cache:  req.cache,
+		scale:  avcontrol.VolumeScaleNative,
+	}
+
+	resp := set.do(ctx)
+	resp.errors = append(errs, resp.errors...)
+	return resp
+}
+
+// fields returns the name of each field req.adjust changes, sorted so that their locks are always taken in the same order.
+func (req *adjustDeviceRequest) fields() []string {
+	var fields []string
+	if req.adjust.TogglePower {
+		fields = append(fields, "poweredOn")
+	}
+
+	if req.adjust.ToggleBlank {
+		fields = append(fields, "blanked")
+	}
+
+	for block := range req.adjust.Volumes {
+		fields = append(fields, fmt.Sprintf("volumes.%s", block))
+	}
+
+	for _, block := range req.adjust.ToggleMutes {
+		fields = append(fields, fmt.Sprintf("mutes.%s", block))
+	}
+
+	sort.Strings(fields)
+	return fields
+}
+
+// lockFields waits until no other adjustments to fields on id are running. fields must be sorted (see fields).
+// The returned function must be called once the adjustment is done.
+func (gs *GetSetter) lockFields(ctx context.Context, id avcontrol.DeviceID, fields []string) (func(), error) {
+	var held []chan struct{}
+	unlock := func() {
+		for _, lock := range held {
+			<-lock
+		}
+	}
+
+	for i, field := range fields {
+		if i > 0 && field == fields[i-1] {
+			// the same block was given more than once
+			continue
+		}
+
+		key := adjustKey{id: id, field: field}
+
+		gs.adjustingMu.Lock()
+		if gs.adjusting == nil {
+			gs.adjusting = make(map[adjustKey]chan struct{})
+		}
+
+		lock, ok := gs.adjusting[key]
+		if !ok {
+			lock = make(chan struct{}, 1)
+			gs.adjusting[key] = lock
+		}
+		gs.adjustingMu.Unlock()
+
+		select {
+		case lock <- struct{}{}:
+			held = append(held, lock)
+		case <-ctx.Done():
+			unlock()
+			return nil, ctx.Err()
+		}
+	}
+
+	return unlock, nil
+}
+
+// read gets the current value of each field in req.adjust from the device, and returns the state with their new values.
+func (req *adjustDeviceRequest) read(ctx context.Context) (avcontrol.DeviceState, []avcontrol.DeviceStateError) {
+	var state avcontrol.DeviceState
+	var errs []avcontrol.DeviceStateError
+
+	handleErr := func(field string, value interface{}, err error) {
+		req.log.Warn("unable to adjust "+field, zap.Error(err))
+		errs = append(errs, avcontrol.DeviceStateError{
+			ID:    req.id,
+			Field: field,
+			Value: value,
+			Error: err.Error(),
+			Code:  deviceErrorCode(err),
+		})
+	}
+
+	opCtx, done := startOp(ctx, req.id, req.device, "createDevice", "")
+	dev, err := createDevice(opCtx, req.driver, req.device)
+	done(err)
+	if err != nil {
+		req.log.Warn("unable to get device", zap.Error(err))
+		handleErr("", nil, fmt.Errorf("unable to get device: %w", err))
+		return state, errs
+	}
+
+	req.log.Info("Adjusting state")
+
+	if req.adjust.TogglePower {
+		if dev, ok := dev.(avcontrol.DeviceWithPower); ok {
+			opCtx, done := startOp(ctx, req.id, req.device, "power", "poweredOn")
+			var power bool
+			err := limit(opCtx, req.driver, req.device, func() (err error) {
+				power, err = dev.Power(opCtx)
+				return err
+			})
+			done(err)
+
+			if err != nil {
+				handleErr("poweredOn", "toggle", err)
+			} else {
+				power = !power
+				state.PoweredOn = &power
+			}
+		} else {
+			handleErr("poweredOn", "toggle", ErrNotCapable)
+		}
+	}
+
+	if req.adjust.ToggleBlank {
+		if dev, ok := dev.(avcontrol.DeviceWithBlank); ok {
+			opCtx, done := startOp(ctx, req.id, req.device, "blank", "blanked")
+			var blanked bool
+			err := limit(opCtx, req.driver, req.device, func() (err error) {
+				blanked, err = dev.Blank(opCtx)
+				return err
+			})
+			done(err)
+
+			if err != nil {
+				handleErr("blanked", "toggle", err)
+			} else {
+				blanked = !blanked
+				state.Blanked = &blanked
+			}
+		} else {
+			handleErr("blanked", "toggle", ErrNotCapable)
+		}
+	}
+
+	if len(req.adjust.Volumes) > 0 {
+		if dev, ok := dev.(avcontrol.DeviceWithVolume); ok {
+			state.Volumes = req.adjustVolumes(ctx, dev, handleErr)
+		} else {
+			handleErr("volumes", req.adjust.Volumes, ErrNotCapable)
+		}
+	}
+
+	if len(req.adjust.ToggleMutes) > 0 {
+		if dev, ok := dev.(avcontrol.DeviceWithMute); ok {
+			state.Mutes = req.toggleMutes(ctx, dev, handleErr)
+		} else {
+			handleErr("mutes", "toggle", ErrNotCapable)
+		}
+	}
+
+	return state, errs
+}
+
+// adjustVolumes returns the new native volume of each block in req.adjust.Volumes. Deltas are on req.scale;
+// if one is too small to change the native volume once it is rounded to the port's step, the volume is moved
+// one step in its direction instead, so that the adjustment isn't silently dropped.
+func (req *adjustDeviceRequest) adjustVolumes(ctx context.Context, dev avcontrol.DeviceWithVolume, handleErr func(string, interface{}, error)) map[string]int {
+	validBlocks := req.device.Ports.OfType("volume").Names()
+
+	var blocks []string
+	for block, delta := range req.adjust.Volumes {
+		if !containsString(validBlocks, block) {
+			handleErr(fmt.Sprintf("volumes.%s", block), delta, ErrInvalidBlock)
+			continue
+		}
+
+		blocks = append(blocks, block)
+	}
+
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	opCtx, done := startOp(ctx, req.id, req.device, "volumes", "volumes")
+	var cur map[string]int
+	err := limit(opCtx, req.driver, req.device, func() (err error) {
+		cur, err = dev.Volumes(opCtx, blocks)
+		return err
+	})
+	done(err)
+
+	if err != nil {
+		handleErr("volumes", req.adjust.Volumes, err)
+		return nil
+	}
+
+	vols := make(map[string]int)
+	for _, block := range blocks {
+		field := fmt.Sprintf("volumes.%s", block)
+		delta := req.adjust.Volumes[block]
+
+		native, ok := cur[block]
+		if !ok {
+			handleErr(field, delta, errors.New("device didn't return the current volume"))
+			continue
+		}
+
+		port, _ := req.device.Ports.Find("volume", block)
+		vol, err := fromNative(port, req.scale, native)
+		if err != nil {
+			handleErr(field, delta, err)
+			continue
+		}
+
+		vol += delta
+
+		// pressing volume up at the top of the range isn't an error, so keep it in range here
+		if port.HasRange() {
+			lo, _ := fromNative(port, req.scale, port.Min)
+			hi, _ := fromNative(port, req.scale, port.Max)
+
+			switch {
+			case vol < lo:
+				vol = lo
+			case vol > hi:
+				vol = hi
+			}
+		}
+
+		next, err := toNative(port, req.scale, vol)
+		if err != nil && !errors.Is(err, ErrOutOfRange) {
+			handleErr(field, delta, err)
+			continue
+		}
+
+		if next == native && delta != 0 {
+			next = nudge(port, native, delta)
+		}
+
+		vols[block] = next
+	}
+
+	return vols
+}
+
+// nudge returns native moved one of port's steps in the direction of delta, kept within port's range.
+func nudge(port avcontrol.PortConfig, native, delta int) int {
+	step := port.Step
+	if step <= 0 {
+		step = 1
+	}
+
+	if delta < 0 {
+		step = -step
+	}
+
+	next := native + step
+	if port.HasRange() {
+		switch {
+		case next < port.Min:
+			next = port.Min
+		case next > port.Max:
+			next = port.Max
+		}
+	}
+
+	return next
+}
+
+// toggleMutes returns the opposite of the current mute state of each block in req.adjust.ToggleMutes.
+func (req *adjustDeviceRequest) toggleMutes(ctx context.Context, dev avcontrol.DeviceWithMute, handleErr func(string, interface{}, error)) map[string]bool {
+	validBlocks := req.device.Ports.OfType("mute").Names()
+
+	var blocks []string
+	for _, block := range req.adjust.ToggleMutes {
+		if !containsString(validBlocks, block) {
+			handleErr(fmt.Sprintf("mutes.%s", block), "toggle", ErrInvalidBlock)
+			continue
+		}
+
+		blocks = append(blocks, block)
+	}
+
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	opCtx, done := startOp(ctx, req.id, req.device, "mutes", "mutes")
+	var cur map[string]bool
+	err := limit(opCtx, req.driver, req.device, func() (err error) {
+		cur, err = dev.Mutes(opCtx, blocks)
+		return err
+	})
+	done(err)
+
+	if err != nil {
+		handleErr("mutes", "toggle", err)
+		return nil
+	}
+
+	mutes := make(map[string]bool)
+	for _, block := range blocks {
+		muted, ok := cur[block]
+		if !ok {
+			handleErr(fmt.Sprintf("mutes.%s", block), "toggle", errors.New("device didn't return the current mute state"))
+			continue
+		}
+
+		mutes[block] = !muted
+	}
+
+	return mutes
+}
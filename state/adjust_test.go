@@ -0,0 +1,186 @@
+package state
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/byuoitav/av-control-api/drivers"
+	"github.com/byuoitav/av-control-api/drivers/driverstest"
+	"github.com/byuoitav/av-control-api/mock"
+	"github.com/matryer/is"
+	"go.uber.org/zap"
+)
+
+func adjustRoom(t *testing.T, tv mock.TV) (*GetSetter, avcontrol.RoomConfig) {
+	is := is.New(t)
+
+	registry, err := drivers.NewWithConfig(nil)
+	is.NoErr(err)
+	is.NoErr(registry.Register("driverstest/driver", &driverstest.Driver{
+		Devices: map[string]avcontrol.Device{
+			"ITB-1101-D1": tv,
+		},
+	}))
+
+	gs := &GetSetter{
+		Logger:         zap.NewNop(),
+		DriverRegistry: registry,
+	}
+
+	room := avcontrol.RoomConfig{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceConfig{
+			"ITB-1101-D1": {
+				Address: "ITB-1101-D1",
+				Driver:  "driverstest/driver",
+				Ports: avcontrol.PortConfigs{
+					tvVol,
+					{Name: "", Type: "mute"},
+				},
+			},
+		},
+	}
+
+	return gs, room
+}
+
+func TestAdjust(t *testing.T) {
+	is := is.New(t)
+
+	tv := mock.TV{
+		WithPower: mock.WithPower{PoweredOn: true},
+		WithVolume: mock.WithVolume{
+			Vols: map[string]int{"": 90},
+		},
+		WithMute: mock.WithMute{
+			Ms: map[string]bool{"": true},
+		},
+	}
+
+	gs, room := adjustRoom(t, tv)
+
+	resp, err := gs.Set(context.Background(), room, avcontrol.StateRequest{
+		Adjust: map[avcontrol.DeviceID]avcontrol.DeviceAdjustment{
+			"ITB-1101-D1": {
+				TogglePower: true,
+				ToggleBlank: true,
+				Volumes:     map[string]int{"": 20},
+				ToggleMutes: []string{"", "invalid"},
+			},
+		},
+	})
+	is.NoErr(err)
+
+	is.Equal(resp.Devices["ITB-1101-D1"], avcontrol.DeviceState{
+		PoweredOn: boolP(false),
+		Blanked:   boolP(true),
+		Volumes:   map[string]int{"": 100}, // kept in range without an error
+		Mutes:     map[string]bool{"": false},
+	})
+
+	is.Equal(tv.Vols[""], 100)
+	is.Equal(tv.Ms[""], false)
+
+	is.Equal(len(resp.Errors), 1)
+	is.Equal(resp.Errors[0].Field, "mutes.invalid")
+	is.Equal(resp.Errors[0].Error, ErrInvalidBlock.Error())
+}
+
+func TestAdjustConcurrent(t *testing.T) {
+	is := is.New(t)
+
+	tv := mock.TV{
+		WithVolume: mock.WithVolume{
+			Vols: map[string]int{"": 0},
+		},
+	}
+
+	gs, room := adjustRoom(t, tv)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := gs.Set(context.Background(), room, avcontrol.StateRequest{
+				Adjust: map[avcontrol.DeviceID]avcontrol.DeviceAdjustment{
+					"ITB-1101-D1": {
+						Volumes: map[string]int{"": 5},
+					},
+				},
+			})
+			is.NoErr(err)
+		}()
+	}
+
+	wg.Wait()
+	is.Equal(tv.Vols[""], 50)
+}
+
+func TestAdjustCoarseStep(t *testing.T) {
+	is := is.New(t)
+
+	tv := mock.TV{
+		WithVolume: mock.WithVolume{
+			Vols: map[string]int{"": 90},
+		},
+	}
+
+	gs, room := adjustRoom(t, tv)
+
+	// 91 rounds back to 90 on a port with a step of 5, so the volume should move a whole step instead
+	resp, err := gs.Set(context.Background(), room, avcontrol.StateRequest{
+		Adjust: map[avcontrol.DeviceID]avcontrol.DeviceAdjustment{
+			"ITB-1101-D1": {
+				Volumes: map[string]int{"": 1},
+			},
+		},
+	})
+	is.NoErr(err)
+	is.Equal(len(resp.Errors), 0)
+	is.Equal(tv.Vols[""], 95)
+
+	ctx := avcontrol.WithVolumeScale(context.Background(), avcontrol.VolumeScalePercent)
+	resp, err = gs.Set(ctx, room, avcontrol.StateRequest{
+		Adjust: map[avcontrol.DeviceID]avcontrol.DeviceAdjustment{
+			"ITB-1101-D1": {
+				Volumes: map[string]int{"": -2},
+			},
+		},
+	})
+	is.NoErr(err)
+	is.Equal(tv.Vols[""], 90)
+	is.Equal(resp.Devices["ITB-1101-D1"].Volumes, map[string]int{"": 90})
+}
+
+func TestLockFields(t *testing.T) {
+	is := is.New(t)
+
+	gs := &GetSetter{}
+
+	unlock, err := gs.lockFields(context.Background(), "ITB-1101-DSP1", []string{"volumes.Mic1Gain"})
+	is.NoErr(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// other blocks on the same device aren't blocked
+	unlockOther, err := gs.lockFields(ctx, "ITB-1101-DSP1", []string{"mutes.Mic1Gain", "volumes.Mic2Gain"})
+	is.NoErr(err)
+	unlockOther()
+
+	// but the same block is
+	_, err = gs.lockFields(ctx, "ITB-1101-DSP1", []string{"mutes.Mic2Gain", "volumes.Mic1Gain"})
+	is.Equal(err, context.DeadlineExceeded)
+
+	// and the locks taken before waiting are released
+	unlockOther, err = gs.lockFields(context.Background(), "ITB-1101-DSP1", []string{"mutes.Mic2Gain"})
+	is.NoErr(err)
+	unlockOther()
+
+	unlock()
+}
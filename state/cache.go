@@ -0,0 +1,297 @@
+package state
+
+import (
+	"sync"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+)
+
+const (
+	_inputAudio      = "audio"
+	_inputVideo      = "video"
+	_inputAudioVideo = "audioVideo"
+)
+
+// Cache stores the last value successfully gotten from or set on each field of a device,
+// along with when that value was stored. A nil *Cache is valid and never returns anything.
+type Cache struct {
+	devices map[avcontrol.DeviceID]*cachedDevice
+	mu      sync.Mutex
+}
+
+type cachedDevice struct {
+	poweredOn *cachedBool
+	blanked   *cachedBool
+
+	// inputs is keyed by input type (audio, video, audioVideo)
+	inputs  map[string]*cachedInputs
+	volumes map[string]cachedInt
+	mutes   map[string]cachedBool
+}
+
+type cachedBool struct {
+	value   bool
+	updated time.Time
+}
+
+type cachedInt struct {
+	value   int
+	updated time.Time
+}
+
+type cachedInputs struct {
+	// inputs maps output to input
+	inputs  map[string]string
+	updated time.Time
+}
+
+// NewCache returns an empty Cache.
+func NewCache() *Cache {
+	return &Cache{
+		devices: make(map[avcontrol.DeviceID]*cachedDevice),
+	}
+}
+
+func fresh(updated time.Time, maxAge time.Duration) bool {
+	return maxAge > 0 && time.Since(updated) <= maxAge
+}
+
+// device returns the cached device for id, creating it if it doesn't exist. c.mu must be held.
+func (c *Cache) device(id avcontrol.DeviceID) *cachedDevice {
+	dev, ok := c.devices[id]
+	if !ok {
+		dev = &cachedDevice{
+			inputs:  make(map[string]*cachedInputs),
+			volumes: make(map[string]cachedInt),
+			mutes:   make(map[string]cachedBool),
+		}
+
+		c.devices[id] = dev
+	}
+
+	return dev
+}
+
+// power returns the cached power state of id if it was stored within maxAge.
+func (c *Cache) power(id avcontrol.DeviceID, maxAge time.Duration) *bool {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dev, ok := c.devices[id]
+	if !ok || dev.poweredOn == nil || !fresh(dev.poweredOn.updated, maxAge) {
+		return nil
+	}
+
+	power := dev.poweredOn.value
+	return &power
+}
+
+func (c *Cache) storePower(id avcontrol.DeviceID, power bool) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.device(id).poweredOn = &cachedBool{
+		value:   power,
+		updated: time.Now(),
+	}
+}
+
+// blank returns the cached blanked state of id if it was stored within maxAge.
+func (c *Cache) blank(id avcontrol.DeviceID, maxAge time.Duration) *bool {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dev, ok := c.devices[id]
+	if !ok || dev.blanked == nil || !fresh(dev.blanked.updated, maxAge) {
+		return nil
+	}
+
+	blanked := dev.blanked.value
+	return &blanked
+}
+
+func (c *Cache) storeBlank(id avcontrol.DeviceID, blanked bool) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.device(id).blanked = &cachedBool{
+		value:   blanked,
+		updated: time.Now(),
+	}
+}
+
+// inputs returns a copy of the cached inputs of type typ on id if they were stored within maxAge.
+func (c *Cache) inputs(id avcontrol.DeviceID, typ string, maxAge time.Duration) map[string]string {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dev, ok := c.devices[id]
+	if !ok || dev.inputs[typ] == nil || !fresh(dev.inputs[typ].updated, maxAge) {
+		return nil
+	}
+
+	inputs := make(map[string]string, len(dev.inputs[typ].inputs))
+	for out, in := range dev.inputs[typ].inputs {
+		inputs[out] = in
+	}
+
+	return inputs
+}
+
+// storeInputs replaces every input of type typ on id.
+func (c *Cache) storeInputs(id avcontrol.DeviceID, typ string, inputs map[string]string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached := &cachedInputs{
+		inputs:  make(map[string]string, len(inputs)),
+		updated: time.Now(),
+	}
+
+	for out, in := range inputs {
+		cached.inputs[out] = in
+	}
+
+	c.device(id).inputs[typ] = cached
+}
+
+// storeInput updates the input of type typ for a single output on id. Because the
+// inputs for the other outputs aren't known, the input is only stored if the rest
+// of the inputs of type typ on id have already been stored.
+func (c *Cache) storeInput(id avcontrol.DeviceID, typ, output, input string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached := c.device(id).inputs[typ]; cached != nil {
+		cached.inputs[output] = input
+	}
+}
+
+// volumes returns the cached volumes for each block on id that were stored within maxAge,
+// along with the list of blocks that weren't.
+func (c *Cache) volumes(id avcontrol.DeviceID, blocks []string, maxAge time.Duration) (map[string]int, []string) {
+	if c == nil {
+		return nil, blocks
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var vols map[string]int
+	var stale []string
+
+	dev := c.device(id)
+	for _, block := range blocks {
+		vol, ok := dev.volumes[block]
+		if !ok || !fresh(vol.updated, maxAge) {
+			stale = append(stale, block)
+			continue
+		}
+
+		if vols == nil {
+			vols = make(map[string]int)
+		}
+
+		vols[block] = vol.value
+	}
+
+	return vols, stale
+}
+
+func (c *Cache) storeVolumes(id avcontrol.DeviceID, vols map[string]int) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	dev := c.device(id)
+
+	for block, vol := range vols {
+		dev.volumes[block] = cachedInt{
+			value:   vol,
+			updated: now,
+		}
+	}
+}
+
+// mutes returns the cached mutes for each block on id that were stored within maxAge,
+// along with the list of blocks that weren't.
+func (c *Cache) mutes(id avcontrol.DeviceID, blocks []string, maxAge time.Duration) (map[string]bool, []string) {
+	if c == nil {
+		return nil, blocks
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var mutes map[string]bool
+	var stale []string
+
+	dev := c.device(id)
+	for _, block := range blocks {
+		muted, ok := dev.mutes[block]
+		if !ok || !fresh(muted.updated, maxAge) {
+			stale = append(stale, block)
+			continue
+		}
+
+		if mutes == nil {
+			mutes = make(map[string]bool)
+		}
+
+		mutes[block] = muted.value
+	}
+
+	return mutes, stale
+}
+
+func (c *Cache) storeMutes(id avcontrol.DeviceID, mutes map[string]bool) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	dev := c.device(id)
+
+	for block, muted := range mutes {
+		dev.mutes[block] = cachedBool{
+			value:   muted,
+			updated: now,
+		}
+	}
+}
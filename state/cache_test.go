@@ -0,0 +1,179 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/byuoitav/av-control-api/drivers"
+	"github.com/byuoitav/av-control-api/drivers/driverstest"
+	"github.com/byuoitav/av-control-api/mock"
+	"github.com/matryer/is"
+	"go.uber.org/zap"
+)
+
+func TestNilCache(t *testing.T) {
+	is := is.New(t)
+
+	var c *Cache
+	c.storePower("ITB-1101-D1", true)
+	c.storeVolumes("ITB-1101-D1", map[string]int{"": 30})
+
+	is.True(c.power("ITB-1101-D1", time.Minute) == nil)
+
+	vols, stale := c.volumes("ITB-1101-D1", []string{""}, time.Minute)
+	is.True(vols == nil)
+	is.Equal(stale, []string{""})
+}
+
+func TestCacheFreshness(t *testing.T) {
+	is := is.New(t)
+
+	c := NewCache()
+	c.storePower("ITB-1101-D1", true)
+	c.storeVolumes("ITB-1101-D1", map[string]int{"mic1": 30})
+
+	is.Equal(c.power("ITB-1101-D1", time.Minute), boolP(true))
+	is.True(c.power("ITB-1101-D1", 0) == nil)
+	is.True(c.power("ITB-1101-D2", time.Minute) == nil)
+
+	// pretend the power was stored a while ago
+	c.devices["ITB-1101-D1"].poweredOn.updated = time.Now().Add(-time.Hour)
+	is.True(c.power("ITB-1101-D1", time.Minute) == nil)
+
+	vols, stale := c.volumes("ITB-1101-D1", []string{"mic1", "mic2"}, time.Minute)
+	is.Equal(vols, map[string]int{"mic1": 30})
+	is.Equal(stale, []string{"mic2"})
+}
+
+func TestCacheStoreInput(t *testing.T) {
+	is := is.New(t)
+
+	c := NewCache()
+
+	// a single input isn't stored until all of the inputs are known
+	c.storeInput("ITB-1101-SW1", _inputVideo, "1", "2")
+	is.True(c.inputs("ITB-1101-SW1", _inputVideo, time.Minute) == nil)
+
+	c.storeInputs("ITB-1101-SW1", _inputVideo, map[string]string{"1": "1", "2": "1"})
+	c.storeInput("ITB-1101-SW1", _inputVideo, "1", "2")
+	is.Equal(c.inputs("ITB-1101-SW1", _inputVideo, time.Minute), map[string]string{"1": "2", "2": "1"})
+	is.True(c.inputs("ITB-1101-SW1", _inputAudio, time.Minute) == nil)
+}
+
+func TestGetStateFromCache(t *testing.T) {
+	is := is.New(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	driver := &driverstest.Driver{
+		Devices: map[string]avcontrol.Device{
+			"ITB-1101-D1": mock.TV{
+				WithPower: mock.WithPower{
+					PoweredOn: true,
+				},
+				WithAudioVideoInput: mock.WithAudioVideoInput{
+					Inputs: map[string]string{
+						"": "hdmi1",
+					},
+				},
+				WithBlank: mock.WithBlank{
+					Blanked: false,
+				},
+				WithVolume: mock.WithVolume{
+					Vols: map[string]int{
+						"": 30,
+					},
+				},
+				WithMute: mock.WithMute{
+					Ms: map[string]bool{
+						"": false,
+					},
+				},
+			},
+		},
+	}
+
+	registry, err := drivers.NewWithConfig(nil)
+	is.NoErr(err)
+	is.NoErr(registry.Register("driverstest/driver", driver))
+
+	gs := &GetSetter{
+		Logger:         zap.NewNop(),
+		DriverRegistry: registry,
+		Cache:          NewCache(),
+	}
+
+	room := avcontrol.RoomConfig{
+		ID: "ITB-1101",
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceConfig{
+			"ITB-1101-D1": {
+				Address: "ITB-1101-D1",
+				Driver:  "driverstest/driver",
+				Ports: avcontrol.PortConfigs{
+					{
+						Name: "",
+						Type: "volume",
+					},
+					{
+						Name: "",
+						Type: "mute",
+					},
+				},
+			},
+		},
+	}
+
+	expected := avcontrol.StateResponse{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+			"ITB-1101-D1": {
+				PoweredOn: boolP(true),
+				Blanked:   boolP(false),
+				Inputs: map[string]avcontrol.Input{
+					"": {
+						AudioVideo: stringP("hdmi1"),
+					},
+				},
+				Volumes: map[string]int{
+					"": 30,
+				},
+				Mutes: map[string]bool{
+					"": false,
+				},
+			},
+		},
+	}
+
+	// fill the cache
+	resp, err := gs.Get(ctx, room)
+	is.NoErr(err)
+	is.Equal(resp, expected)
+
+	// make the device fail, so that any call to the device returns an error
+	broken := errors.New("broken")
+	driver.Devices["ITB-1101-D1"] = mock.TV{
+		WithPower:           mock.WithPower{Error: broken},
+		WithAudioVideoInput: mock.WithAudioVideoInput{Error: broken},
+		WithBlank:           mock.WithBlank{Error: broken},
+		WithVolume:          mock.WithVolume{Error: broken},
+		WithMute:            mock.WithMute{Error: broken},
+	}
+
+	// devices are cached by the registry, so clear it out
+	registry, err = drivers.NewWithConfig(nil)
+	is.NoErr(err)
+	is.NoErr(registry.Register("driverstest/driver", driver))
+	gs.DriverRegistry = registry
+
+	resp, err = gs.Get(avcontrol.WithMaxAge(ctx, time.Minute), room)
+	is.NoErr(err)
+	is.Equal(resp, expected)
+
+	// without a max age, every field should come from the device
+	resp, err = gs.Get(ctx, room)
+	is.NoErr(err)
+	is.Equal(len(resp.Errors), 5)
+}
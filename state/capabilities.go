@@ -0,0 +1,91 @@
+package state
+
+import (
+	"context"
+	"fmt"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"go.uber.org/zap"
+)
+
+type getDeviceCapabilitiesRequest struct {
+	id     avcontrol.DeviceID
+	device avcontrol.DeviceConfig
+	driver avcontrol.Driver
+	log    *zap.Logger
+}
+
+type getDeviceCapabilitiesResponse struct {
+	id   avcontrol.DeviceID
+	caps avcontrol.DeviceCapabilities
+}
+
+// GetCapabilities creates each device in the RoomConfig and reports which DeviceWith* interfaces it implements.
+// No commands are sent to the devices.
+func (gs *GetSetter) GetCapabilities(ctx context.Context, room avcontrol.RoomConfig) (avcontrol.RoomCapabilities, error) {
+	if len(room.Devices) == 0 {
+		return avcontrol.RoomCapabilities{}, nil
+	}
+
+	if err := gs.checkDrivers(room); err != nil {
+		return avcontrol.RoomCapabilities{}, err
+	}
+
+	id := avcontrol.CtxRequestID(ctx)
+	log := gs.Logger
+	if len(id) > 0 {
+		log = gs.Logger.With(zap.String("requestID", id))
+	}
+
+	resps := make(chan getDeviceCapabilitiesResponse)
+	defer close(resps)
+
+	roomCaps := avcontrol.RoomCapabilities{
+		Devices: make(map[avcontrol.DeviceID]avcontrol.DeviceCapabilities),
+	}
+
+	for id, dev := range room.Devices {
+		req := getDeviceCapabilitiesRequest{
+			id:     id,
+			device: dev,
+			driver: gs.DriverRegistry.Get(dev.Driver),
+			log:    log.With(zap.String("deviceID", string(id))),
+		}
+
+		go func() {
+			resps <- req.do(ctx)
+		}()
+	}
+
+	for i := 0; i < len(room.Devices); i++ {
+		resp := <-resps
+		roomCaps.Devices[resp.id] = resp.caps
+	}
+
+	return roomCaps, nil
+}
+
+func (req *getDeviceCapabilitiesRequest) do(ctx context.Context) getDeviceCapabilitiesResponse {
+	ctx, span := startDevice(ctx, "get device capabilities", req.id, req.device)
+	defer span.End()
+
+	resp := getDeviceCapabilitiesResponse{
+		id: req.id,
+	}
+
+	req.log.Debug("Getting device")
+
+	opCtx, done := startOp(ctx, req.id, req.device, "createDevice", "")
+	dev, err := createDevice(opCtx, req.driver, req.device)
+	done(err)
+
+	if err != nil {
+		req.log.Warn("unable to get device", zap.Error(err))
+		str := fmt.Sprintf("unable to get device: %s", err)
+		resp.caps.Error = &str
+		return resp
+	}
+
+	resp.caps.Capabilities = avcontrol.Capabilities(dev)
+	return resp
+}
@@ -0,0 +1,77 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/byuoitav/av-control-api/drivers"
+	"github.com/byuoitav/av-control-api/drivers/driverstest"
+	"github.com/byuoitav/av-control-api/mock"
+	"github.com/matryer/is"
+	"go.uber.org/zap"
+)
+
+func TestCapabilities(t *testing.T) {
+	is := is.New(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	registry, err := drivers.NewWithConfig(nil)
+	is.NoErr(err)
+
+	registry.MustRegister("driverstest/driver", &driverstest.Driver{
+		Devices: map[string]avcontrol.Device{
+			"ITB-1101-D1":  mock.Projector{},
+			"ITB-1101-SW1": mock.VideoSwitcher{},
+		},
+	})
+
+	gs := &GetSetter{
+		Logger:         zap.NewNop(),
+		DriverRegistry: registry,
+	}
+
+	room := avcontrol.RoomConfig{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceConfig{
+			"ITB-1101-D1": {
+				Address: "ITB-1101-D1",
+				Driver:  "driverstest/driver",
+			},
+			"ITB-1101-SW1": {
+				Address: "ITB-1101-SW1",
+				Driver:  "driverstest/driver",
+			},
+			"ITB-1101-D2": {
+				Address: "ITB-1101-D2",
+				Driver:  "driverstest/driver",
+				Config: map[string]interface{}{
+					"psk": "override",
+				},
+			},
+		},
+	}
+
+	caps, err := gs.GetCapabilities(ctx, room)
+	is.NoErr(err)
+	is.Equal(caps.Devices["ITB-1101-D1"].Capabilities, []string{"power", "blank", "audioVideoInput", "health", "info"})
+	is.Equal(caps.Devices["ITB-1101-SW1"].Capabilities, []string{"audioInput", "videoInput", "health", "info"})
+	is.True(caps.Devices["ITB-1101-D2"].Error != nil) // driverstest.Driver doesn't support device config
+
+	room.Devices["ITB-1101-D3"] = avcontrol.DeviceConfig{
+		Address: "ITB-1101-D3",
+		Driver:  "unknown",
+	}
+
+	caps, err = gs.GetCapabilities(ctx, room)
+	is.NoErr(err)
+	is.True(caps.Devices["ITB-1101-D3"].Error != nil)
+	is.Equal(*caps.Devices["ITB-1101-D3"].Error, "unable to get device: unknown: driver not registered")
+
+	gs.StrictDrivers = true
+	_, err = gs.GetCapabilities(ctx, room)
+	is.True(errors.Is(err, ErrDriverNotRegistered))
+}
@@ -0,0 +1,59 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+)
+
+// ErrorCode returns the avcontrol.ErrorCode constant that describes err,
+// or "" if err isn't one of the errors returned by this package.
+func ErrorCode(err error) string {
+	var netErr net.Error
+
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrNotCapable):
+		return avcontrol.ErrorCodeNotCapable
+	case errors.Is(err, ErrInvalidBlock):
+		return avcontrol.ErrorCodeInvalidBlock
+	case errors.Is(err, ErrInvalidPort):
+		return avcontrol.ErrorCodeInvalidPort
+	case errors.Is(err, ErrInvalidDevice):
+		return avcontrol.ErrorCodeInvalidDevice
+	case errors.Is(err, ErrInvalidScale):
+		return avcontrol.ErrorCodeInvalidScale
+	case errors.Is(err, ErrOutOfRange):
+		return avcontrol.ErrorCodeOutOfRange
+	case errors.Is(err, ErrDriverNotRegistered):
+		return avcontrol.ErrorCodeDriverNotRegistered
+	case errors.Is(err, avcontrol.ErrDeviceConfigNotSupported):
+		return avcontrol.ErrorCodeConfigNotSupported
+	case errors.Is(err, ErrPhaseSkipped):
+		return avcontrol.ErrorCodePhaseSkipped
+	case errors.Is(err, ErrNoStateGettable):
+		return avcontrol.ErrorCodeNoDevices
+	case errors.Is(err, context.DeadlineExceeded):
+		return avcontrol.ErrorCodeTimeout
+	case errors.Is(err, context.Canceled):
+		return avcontrol.ErrorCodeCanceled
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return avcontrol.ErrorCodeTimeout
+	case errors.As(err, &netErr):
+		return avcontrol.ErrorCodeUnreachable
+	default:
+		return ""
+	}
+}
+
+// deviceErrorCode returns the code for an error that happened while getting or setting the state of a device.
+func deviceErrorCode(err error) string {
+	if code := ErrorCode(err); code != "" {
+		return code
+	}
+
+	return avcontrol.ErrorCodeDeviceError
+}
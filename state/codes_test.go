@@ -0,0 +1,25 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/matryer/is"
+)
+
+func TestErrorCode(t *testing.T) {
+	is := is.New(t)
+
+	is.Equal(ErrorCode(nil), "")
+	is.Equal(ErrorCode(errors.New("something")), "")
+	is.Equal(ErrorCode(fmt.Errorf("dsp: %w", ErrDriverNotRegistered)), avcontrol.ErrorCodeDriverNotRegistered)
+	is.Equal(ErrorCode(fmt.Errorf("unable to get volumes: %w", context.DeadlineExceeded)), avcontrol.ErrorCodeTimeout)
+	is.Equal(ErrorCode(&net.OpError{Op: "dial", Err: errors.New("connection refused")}), avcontrol.ErrorCodeUnreachable)
+
+	is.Equal(deviceErrorCode(errors.New("something")), avcontrol.ErrorCodeDeviceError)
+	is.Equal(deviceErrorCode(ErrNotCapable), avcontrol.ErrorCodeNotCapable)
+}
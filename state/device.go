@@ -0,0 +1,27 @@
+package state
+
+import (
+	"context"
+	"fmt"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+)
+
+// createDevice gets the device described by config from driver, using the device's config overrides if it has any.
+// If driver is nil, an ErrDriverNotRegistered error is returned.
+func createDevice(ctx context.Context, driver avcontrol.Driver, config avcontrol.DeviceConfig) (avcontrol.Device, error) {
+	if driver == nil {
+		return nil, fmt.Errorf("%s: %w", config.Driver, ErrDriverNotRegistered)
+	}
+
+	if len(config.Config) == 0 {
+		return driver.CreateDevice(ctx, config.Address)
+	}
+
+	d, ok := driver.(avcontrol.DriverWithDeviceConfig)
+	if !ok {
+		return nil, avcontrol.ErrDeviceConfigNotSupported
+	}
+
+	return d.CreateDeviceWithConfig(ctx, config.Address, config.Config)
+}
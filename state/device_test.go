@@ -0,0 +1,58 @@
+package state
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/byuoitav/av-control-api/drivers"
+	"github.com/byuoitav/av-control-api/drivers/driverstest"
+	"github.com/byuoitav/av-control-api/mock"
+	"github.com/matryer/is"
+	"go.uber.org/zap"
+)
+
+func TestSetDeviceConfigNotSupported(t *testing.T) {
+	is := is.New(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	registry, err := drivers.NewWithConfig(nil)
+	is.NoErr(err)
+	is.NoErr(registry.Register("driverstest/driver", &driverstest.Driver{
+		Devices: map[string]avcontrol.Device{
+			"ITB-1101-D1": &mock.TV{},
+		},
+	}))
+
+	gs := &GetSetter{
+		Logger:         zap.NewNop(),
+		DriverRegistry: registry,
+	}
+
+	room := avcontrol.RoomConfig{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceConfig{
+			"ITB-1101-D1": {
+				Address: "ITB-1101-D1",
+				Driver:  "driverstest/driver",
+				Config: map[string]interface{}{
+					"psk": "special",
+				},
+			},
+		},
+	}
+
+	resp, err := gs.Set(ctx, room, avcontrol.StateRequest{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+			"ITB-1101-D1": {
+				PoweredOn: boolP(true),
+			},
+		},
+	})
+	is.NoErr(err)
+	is.Equal(len(resp.Errors), 1)
+	is.True(strings.Contains(resp.Errors[0].Error, avcontrol.ErrDeviceConfigNotSupported.Error()))
+}
@@ -0,0 +1,166 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"go.uber.org/zap"
+)
+
+// _eventTimeout is how long gs.Events has to deliver the events from a single request.
+const _eventTimeout = 5 * time.Minute
+
+// remember stores devices as the last known state of each device, without sending any events.
+func (gs *GetSetter) remember(devices map[avcontrol.DeviceID]avcontrol.DeviceState) {
+	if gs.Events == nil {
+		return
+	}
+
+	gs.knownMu.Lock()
+	defer gs.knownMu.Unlock()
+
+	if gs.known == nil {
+		gs.known = make(map[avcontrol.DeviceID]avcontrol.DeviceState)
+	}
+
+	for id, state := range devices {
+		gs.known[id], _ = mergeState(gs.known[id], state)
+	}
+}
+
+// emit stores devices as the last known state of each device, and sends an event to gs.Events in the background
+// for each field that is different from its last known value.
+func (gs *GetSetter) emit(ctx context.Context, log *zap.Logger, roomID string, devices map[avcontrol.DeviceID]avcontrol.DeviceState) {
+	if gs.Events == nil {
+		return
+	}
+
+	now := time.Now()
+	reqID := avcontrol.CtxRequestID(ctx)
+
+	var events []avcontrol.StateEvent
+
+	gs.knownMu.Lock()
+	if gs.known == nil {
+		gs.known = make(map[avcontrol.DeviceID]avcontrol.DeviceState)
+	}
+
+	for id, state := range devices {
+		prev := gs.known[id]
+
+		merged, changed := mergeState(prev, state)
+		gs.known[id] = merged
+
+		if changed == nil {
+			continue
+		}
+
+		for _, event := range fieldChanges(prev, *changed) {
+			event.Time = now
+			event.RequestID = reqID
+			event.Room = roomID
+			event.Device = id
+
+			events = append(events, event)
+		}
+	}
+	gs.knownMu.Unlock()
+
+	if len(events) == 0 {
+		return
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Device != events[j].Device {
+			return events[i].Device < events[j].Device
+		}
+
+		return events[i].Field < events[j].Field
+	})
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), _eventTimeout)
+		defer cancel()
+
+		if err := gs.Events.Send(ctx, events); err != nil {
+			log.Warn("unable to send state events", zap.Int("numEvents", len(events)), zap.Error(err))
+		}
+	}()
+}
+
+// fieldChanges returns an event for each field set in changed, with its old value from prev.
+// Only the Field, Old, and New fields of the events are set.
+func fieldChanges(prev, changed avcontrol.DeviceState) []avcontrol.StateEvent {
+	var events []avcontrol.StateEvent
+	add := func(field string, from, to interface{}) {
+		events = append(events, avcontrol.StateEvent{
+			Field: field,
+			Old:   from,
+			New:   to,
+		})
+	}
+
+	// old values are left as a nil interface{} (rather than a nil pointer) when they aren't known
+	boolValue := func(b *bool) interface{} {
+		if b == nil {
+			return nil
+		}
+
+		return *b
+	}
+
+	stringValue := func(s *string) interface{} {
+		if s == nil {
+			return nil
+		}
+
+		return *s
+	}
+
+	if changed.PoweredOn != nil {
+		add("poweredOn", boolValue(prev.PoweredOn), *changed.PoweredOn)
+	}
+
+	if changed.Blanked != nil {
+		add("blanked", boolValue(prev.Blanked), *changed.Blanked)
+	}
+
+	for output, in := range changed.Inputs {
+		old := prev.Inputs[output]
+
+		if in.AudioVideo != nil {
+			add(fmt.Sprintf("input.%s.audioVideo", output), stringValue(old.AudioVideo), *in.AudioVideo)
+		}
+
+		if in.Audio != nil {
+			add(fmt.Sprintf("input.%s.audio", output), stringValue(old.Audio), *in.Audio)
+		}
+
+		if in.Video != nil {
+			add(fmt.Sprintf("input.%s.video", output), stringValue(old.Video), *in.Video)
+		}
+	}
+
+	for block, vol := range changed.Volumes {
+		var old interface{}
+		if v, ok := prev.Volumes[block]; ok {
+			old = v
+		}
+
+		add(fmt.Sprintf("volumes.%s", block), old, vol)
+	}
+
+	for block, muted := range changed.Mutes {
+		var old interface{}
+		if m, ok := prev.Mutes[block]; ok {
+			old = m
+		}
+
+		add(fmt.Sprintf("mutes.%s", block), old, muted)
+	}
+
+	return events
+}
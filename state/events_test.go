@@ -0,0 +1,87 @@
+package state
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/byuoitav/av-control-api/mock"
+	"github.com/matryer/is"
+)
+
+// chanSink sends the events it is sent to a channel.
+type chanSink chan []avcontrol.StateEvent
+
+func (s chanSink) Send(ctx context.Context, events []avcontrol.StateEvent) error {
+	s <- events
+	return nil
+}
+
+func TestSetEvents(t *testing.T) {
+	is := is.New(t)
+
+	tv := mock.TV{
+		WithPower: mock.WithPower{PoweredOn: true},
+		WithVolume: mock.WithVolume{
+			Vols: map[string]int{"": 30},
+		},
+		WithMute: mock.WithMute{
+			Ms: map[string]bool{"": false},
+		},
+	}
+
+	gs, room := adjustRoom(t, tv)
+	room.ID = "ITB-1101"
+
+	sink := make(chanSink, 1)
+	gs.Events = sink
+
+	ctx := avcontrol.WithRequestID(context.Background(), "ID")
+
+	// the first get only finds out what the state is
+	_, err := gs.Get(ctx, room)
+	is.NoErr(err)
+
+	_, err = gs.Set(ctx, room, avcontrol.StateRequest{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+			"ITB-1101-D1": {
+				PoweredOn: boolP(true), // unchanged
+				Blanked:   boolP(true),
+				Volumes:   map[string]int{"": 50},
+			},
+		},
+	})
+	is.NoErr(err)
+
+	var events []avcontrol.StateEvent
+	select {
+	case events = <-sink:
+	case <-time.After(time.Second):
+		t.Fatalf("no events were sent")
+	}
+
+	for i := range events {
+		is.True(!events[i].Time.IsZero())
+		events[i].Time = time.Time{}
+	}
+
+	is.Equal(events, []avcontrol.StateEvent{
+		{RequestID: "ID", Room: "ITB-1101", Device: "ITB-1101-D1", Field: "blanked", Old: false, New: true},
+		{RequestID: "ID", Room: "ITB-1101", Device: "ITB-1101-D1", Field: "volumes.", Old: 30, New: 50},
+	})
+
+	// setting the same state again doesn't change anything
+	_, err = gs.Set(ctx, room, avcontrol.StateRequest{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+			"ITB-1101-D1": {Volumes: map[string]int{"": 50}},
+		},
+	})
+	is.NoErr(err)
+
+	select {
+	case events := <-sink:
+		t.Fatalf("unexpected events: %+v", events)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
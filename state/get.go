@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	avcontrol "github.com/byuoitav/av-control-api"
 	"go.uber.org/zap"
@@ -20,6 +21,9 @@ type getDeviceStateRequest struct {
 	device avcontrol.DeviceConfig
 	driver avcontrol.Driver
 	log    *zap.Logger
+
+	cache  *Cache
+	maxAge time.Duration
 }
 
 type getDeviceStateResponse struct {
@@ -30,16 +34,16 @@ type getDeviceStateResponse struct {
 }
 
 // Get goes through the devices in the RoomConfig and reports on the state of the room.
+// If gs has a Cache and ctx has a max age (see avcontrol.WithMaxAge), fields that have
+// been cached within that max age are returned without talking to the device.
+// Volumes are returned on the scale set in ctx (see avcontrol.WithVolumeScale).
 func (gs *GetSetter) Get(ctx context.Context, room avcontrol.RoomConfig) (avcontrol.StateResponse, error) {
 	if len(room.Devices) == 0 {
 		return avcontrol.StateResponse{}, nil
 	}
 
-	// make sure the driver for every device in the room exists
-	for _, dev := range room.Devices {
-		if gs.DriverRegistry.Get(dev.Driver) == nil {
-			return avcontrol.StateResponse{}, fmt.Errorf("%s: %w", dev.Driver, ErrDriverNotRegistered)
-		}
+	if err := gs.checkDrivers(room); err != nil {
+		return avcontrol.StateResponse{}, err
 	}
 
 	id := avcontrol.CtxRequestID(ctx)
@@ -61,6 +65,8 @@ func (gs *GetSetter) Get(ctx context.Context, room avcontrol.RoomConfig) (avcont
 			device: dev,
 			driver: gs.DriverRegistry.Get(dev.Driver),
 			log:    log.With(zap.String("deviceID", string(id))),
+			cache:  gs.Cache,
+			maxAge: avcontrol.CtxMaxAge(ctx),
 		}
 
 		go func() {
@@ -75,11 +81,36 @@ func (gs *GetSetter) Get(ctx context.Context, room avcontrol.RoomConfig) (avcont
 		stateResp.Errors = append(stateResp.Errors, resp.errors...)
 	}
 
+	gs.remember(stateResp.Devices)
+
+	var errs []avcontrol.DeviceStateError
+	stateResp.Devices, errs = scaleVolumes(room, stateResp.Devices, avcontrol.CtxVolumeScale(ctx))
+	stateResp.Errors = append(stateResp.Errors, errs...)
+
 	sortErrors(stateResp.Errors)
 	return stateResp, nil
 }
 
+// checkDrivers returns an ErrDriverNotRegistered error if gs.StrictDrivers is set and the driver
+// for any of the devices in room isn't registered.
+func (gs *GetSetter) checkDrivers(room avcontrol.RoomConfig) error {
+	if !gs.StrictDrivers {
+		return nil
+	}
+
+	for _, dev := range room.Devices {
+		if gs.DriverRegistry.Get(dev.Driver) == nil {
+			return fmt.Errorf("%s: %w", dev.Driver, ErrDriverNotRegistered)
+		}
+	}
+
+	return nil
+}
+
 func (req *getDeviceStateRequest) do(ctx context.Context) *getDeviceStateResponse {
+	ctx, span := startDevice(ctx, "get device state", req.id, req.device)
+	defer span.End()
+
 	resp := &getDeviceStateResponse{
 		id: req.id,
 	}
@@ -87,12 +118,15 @@ func (req *getDeviceStateRequest) do(ctx context.Context) *getDeviceStateRespons
 	req.log.Info("Getting state")
 	req.log.Debug("Getting device")
 
-	dev, err := req.driver.CreateDevice(ctx, req.device.Address)
+	opCtx, done := startOp(ctx, req.id, req.device, "createDevice", "")
+	dev, err := createDevice(opCtx, req.driver, req.device)
+	done(err)
 	if err != nil {
 		req.log.Warn("unable to get device", zap.Error(err))
 		resp.errors = append(resp.errors, avcontrol.DeviceStateError{
 			ID:    req.id,
 			Error: fmt.Sprintf("unable to get device: %s", err),
+			Code:  deviceErrorCode(err),
 		})
 		return resp
 	}
@@ -109,187 +143,280 @@ func (req *getDeviceStateRequest) do(ctx context.Context) *getDeviceStateRespons
 			ID:    req.id,
 			Field: field,
 			Error: err.Error(),
+			Code:  deviceErrorCode(err),
 		})
 	}
 
-	// get every field possible
-	wg := sync.WaitGroup{}
+	saveInputs := func(typ string, inputs map[string]string) {
+		resp.Lock()
+		defer resp.Unlock()
 
-	if dev, ok := dev.(avcontrol.DeviceWithPower); ok {
-		wg.Add(1)
+		if resp.state.Inputs == nil {
+			resp.state.Inputs = make(map[string]avcontrol.Input)
+		}
 
-		go func() {
-			req.log.Info("Getting power")
-			defer wg.Done()
+		for out, in := range inputs {
+			input := resp.state.Inputs[out]
+			save := in
 
-			power, err := dev.Power(ctx)
-			if err != nil {
-				handleErr("power", err)
-				return
+			switch typ {
+			case _inputAudio:
+				input.Audio = &save
+			case _inputVideo:
+				input.Video = &save
+			case _inputAudioVideo:
+				input.AudioVideo = &save
 			}
 
-			req.log.Info("Got power", zap.Bool("poweredOn", power))
-
-			resp.Lock()
-			defer resp.Unlock()
-			resp.state.PoweredOn = &power
-		}()
+			resp.state.Inputs[out] = input
+		}
 	}
 
-	if dev, ok := dev.(avcontrol.DeviceWithAudioInput); ok {
-		wg.Add(1)
-
-		go func() {
-			req.log.Info("Getting audio inputs")
-			defer wg.Done()
-
-			inputs, err := dev.AudioInputs(ctx)
-			if err != nil {
-				handleErr("inputs.$.audio", err)
-				return
-			}
-
-			req.log.Info("Got audio inputs", zap.Any("inputs", inputs))
-
-			resp.Lock()
-			defer resp.Unlock()
+	// get every field possible
+	wg := sync.WaitGroup{}
 
-			if resp.state.Inputs == nil {
-				resp.state.Inputs = make(map[string]avcontrol.Input)
-			}
+	if dev, ok := dev.(avcontrol.DeviceWithPower); ok {
+		if power := req.cache.power(req.id, req.maxAge); power != nil {
+			req.log.Info("Got power from cache", zap.Bool("poweredOn", *power))
+			resp.state.PoweredOn = power
+		} else {
+			wg.Add(1)
+
+			go func() {
+				req.log.Info("Getting power")
+				defer wg.Done()
+
+				opCtx, done := startOp(ctx, req.id, req.device, "power", "power")
+				var power bool
+				err := limit(opCtx, req.driver, req.device, func() (err error) {
+					power, err = dev.Power(opCtx)
+					return err
+				})
+				done(err)
+				if err != nil {
+					handleErr("power", err)
+					return
+				}
+
+				req.log.Info("Got power", zap.Bool("poweredOn", power))
+				req.cache.storePower(req.id, power)
+
+				resp.Lock()
+				defer resp.Unlock()
+				resp.state.PoweredOn = &power
+			}()
+		}
+	}
 
-			for out, in := range inputs {
-				input := resp.state.Inputs[out]
-				save := in
-				input.Audio = &save
-				resp.state.Inputs[out] = input
-			}
-		}()
+	if dev, ok := dev.(avcontrol.DeviceWithAudioInput); ok {
+		if inputs := req.cache.inputs(req.id, _inputAudio, req.maxAge); inputs != nil {
+			req.log.Info("Got audio inputs from cache", zap.Any("inputs", inputs))
+			saveInputs(_inputAudio, inputs)
+		} else {
+			wg.Add(1)
+
+			go func() {
+				req.log.Info("Getting audio inputs")
+				defer wg.Done()
+
+				opCtx, done := startOp(ctx, req.id, req.device, "audioInputs", "inputs.$.audio")
+				var inputs map[string]string
+				err := limit(opCtx, req.driver, req.device, func() (err error) {
+					inputs, err = dev.AudioInputs(opCtx)
+					return err
+				})
+				done(err)
+				if err != nil {
+					handleErr("inputs.$.audio", err)
+					return
+				}
+
+				req.log.Info("Got audio inputs", zap.Any("inputs", inputs))
+				req.cache.storeInputs(req.id, _inputAudio, inputs)
+				saveInputs(_inputAudio, inputs)
+			}()
+		}
 	}
 
 	if dev, ok := dev.(avcontrol.DeviceWithVideoInput); ok {
-		wg.Add(1)
-
-		go func() {
-			req.log.Info("Getting video inputs")
-			defer wg.Done()
-
-			inputs, err := dev.VideoInputs(ctx)
-			if err != nil {
-				handleErr("inputs.$.video", err)
-				return
-			}
-
-			req.log.Info("Got video inputs", zap.Any("inputs", inputs))
-
-			resp.Lock()
-			defer resp.Unlock()
-
-			if resp.state.Inputs == nil {
-				resp.state.Inputs = make(map[string]avcontrol.Input)
-			}
-
-			for out, in := range inputs {
-				input := resp.state.Inputs[out]
-				save := in
-				input.Video = &save
-				resp.state.Inputs[out] = input
-			}
-		}()
+		if inputs := req.cache.inputs(req.id, _inputVideo, req.maxAge); inputs != nil {
+			req.log.Info("Got video inputs from cache", zap.Any("inputs", inputs))
+			saveInputs(_inputVideo, inputs)
+		} else {
+			wg.Add(1)
+
+			go func() {
+				req.log.Info("Getting video inputs")
+				defer wg.Done()
+
+				opCtx, done := startOp(ctx, req.id, req.device, "videoInputs", "inputs.$.video")
+				var inputs map[string]string
+				err := limit(opCtx, req.driver, req.device, func() (err error) {
+					inputs, err = dev.VideoInputs(opCtx)
+					return err
+				})
+				done(err)
+				if err != nil {
+					handleErr("inputs.$.video", err)
+					return
+				}
+
+				req.log.Info("Got video inputs", zap.Any("inputs", inputs))
+				req.cache.storeInputs(req.id, _inputVideo, inputs)
+				saveInputs(_inputVideo, inputs)
+			}()
+		}
 	}
 
 	if dev, ok := dev.(avcontrol.DeviceWithAudioVideoInput); ok {
-		wg.Add(1)
-
-		go func() {
-			req.log.Info("Getting audioVideo inputs")
-			defer wg.Done()
-
-			inputs, err := dev.AudioVideoInputs(ctx)
-			if err != nil {
-				handleErr("inputs.$.audioVideo", err)
-				return
-			}
-
-			req.log.Info("Got audioVideo inputs", zap.Any("inputs", inputs))
-
-			resp.Lock()
-			defer resp.Unlock()
-
-			if resp.state.Inputs == nil {
-				resp.state.Inputs = make(map[string]avcontrol.Input)
-			}
-
-			for out, in := range inputs {
-				input := resp.state.Inputs[out]
-				save := in
-				input.AudioVideo = &save
-				resp.state.Inputs[out] = input
-			}
-		}()
+		if inputs := req.cache.inputs(req.id, _inputAudioVideo, req.maxAge); inputs != nil {
+			req.log.Info("Got audioVideo inputs from cache", zap.Any("inputs", inputs))
+			saveInputs(_inputAudioVideo, inputs)
+		} else {
+			wg.Add(1)
+
+			go func() {
+				req.log.Info("Getting audioVideo inputs")
+				defer wg.Done()
+
+				opCtx, done := startOp(ctx, req.id, req.device, "audioVideoInputs", "inputs.$.audioVideo")
+				var inputs map[string]string
+				err := limit(opCtx, req.driver, req.device, func() (err error) {
+					inputs, err = dev.AudioVideoInputs(opCtx)
+					return err
+				})
+				done(err)
+				if err != nil {
+					handleErr("inputs.$.audioVideo", err)
+					return
+				}
+
+				req.log.Info("Got audioVideo inputs", zap.Any("inputs", inputs))
+				req.cache.storeInputs(req.id, _inputAudioVideo, inputs)
+				saveInputs(_inputAudioVideo, inputs)
+			}()
+		}
 	}
 
 	if dev, ok := dev.(avcontrol.DeviceWithBlank); ok {
-		wg.Add(1)
-
-		go func() {
-			req.log.Info("Getting blank")
-			defer wg.Done()
-
-			blank, err := dev.Blank(ctx)
-			if err != nil {
-				handleErr("blank", err)
-				return
-			}
-
-			req.log.Info("Got blank", zap.Bool("blank", blank))
-
-			resp.Lock()
-			defer resp.Unlock()
-			resp.state.Blanked = &blank
-		}()
+		if blank := req.cache.blank(req.id, req.maxAge); blank != nil {
+			req.log.Info("Got blank from cache", zap.Bool("blank", *blank))
+			resp.state.Blanked = blank
+		} else {
+			wg.Add(1)
+
+			go func() {
+				req.log.Info("Getting blank")
+				defer wg.Done()
+
+				opCtx, done := startOp(ctx, req.id, req.device, "blank", "blank")
+				var blank bool
+				err := limit(opCtx, req.driver, req.device, func() (err error) {
+					blank, err = dev.Blank(opCtx)
+					return err
+				})
+				done(err)
+				if err != nil {
+					handleErr("blank", err)
+					return
+				}
+
+				req.log.Info("Got blank", zap.Bool("blank", blank))
+				req.cache.storeBlank(req.id, blank)
+
+				resp.Lock()
+				defer resp.Unlock()
+				resp.state.Blanked = &blank
+			}()
+		}
 	}
 
 	if dev, ok := dev.(avcontrol.DeviceWithVolume); ok {
-		wg.Add(1)
-
-		go func() {
-			req.log.Info("Getting volumes")
-			defer wg.Done()
-
-			vols, err := dev.Volumes(ctx, req.device.Ports.OfType("volume").Names())
-			if err != nil {
-				handleErr("volumes", err)
-				return
-			}
-
-			req.log.Info("Got volumes", zap.Any("volumes", vols))
-
-			resp.Lock()
-			defer resp.Unlock()
+		vols, stale := req.cache.volumes(req.id, req.device.Ports.OfType("volume").Names(), req.maxAge)
+		if len(vols) > 0 {
+			req.log.Info("Got volumes from cache", zap.Any("volumes", vols))
 			resp.state.Volumes = vols
-		}()
+		}
+
+		if len(stale) > 0 || vols == nil {
+			wg.Add(1)
+
+			go func() {
+				req.log.Info("Getting volumes")
+				defer wg.Done()
+
+				opCtx, done := startOp(ctx, req.id, req.device, "volumes", "volumes")
+				var vols map[string]int
+				err := limit(opCtx, req.driver, req.device, func() (err error) {
+					vols, err = dev.Volumes(opCtx, stale)
+					return err
+				})
+				done(err)
+				if err != nil {
+					handleErr("volumes", err)
+					return
+				}
+
+				req.log.Info("Got volumes", zap.Any("volumes", vols))
+				req.cache.storeVolumes(req.id, vols)
+
+				resp.Lock()
+				defer resp.Unlock()
+
+				if resp.state.Volumes == nil {
+					resp.state.Volumes = vols
+					return
+				}
+
+				for block, vol := range vols {
+					resp.state.Volumes[block] = vol
+				}
+			}()
+		}
 	}
 
 	if dev, ok := dev.(avcontrol.DeviceWithMute); ok {
-		wg.Add(1)
-
-		go func() {
-			req.log.Info("Getting mutes")
-			defer wg.Done()
-
-			mutes, err := dev.Mutes(ctx, req.device.Ports.OfType("mute").Names())
-			if err != nil {
-				handleErr("mutes", err)
-				return
-			}
-
-			req.log.Info("Got mutes", zap.Any("mutes", mutes))
-
-			resp.Lock()
-			defer resp.Unlock()
+		mutes, stale := req.cache.mutes(req.id, req.device.Ports.OfType("mute").Names(), req.maxAge)
+		if len(mutes) > 0 {
+			req.log.Info("Got mutes from cache", zap.Any("mutes", mutes))
 			resp.state.Mutes = mutes
-		}()
+		}
+
+		if len(stale) > 0 || mutes == nil {
+			wg.Add(1)
+
+			go func() {
+				req.log.Info("Getting mutes")
+				defer wg.Done()
+
+				opCtx, done := startOp(ctx, req.id, req.device, "mutes", "mutes")
+				var mutes map[string]bool
+				err := limit(opCtx, req.driver, req.device, func() (err error) {
+					mutes, err = dev.Mutes(opCtx, stale)
+					return err
+				})
+				done(err)
+				if err != nil {
+					handleErr("mutes", err)
+					return
+				}
+
+				req.log.Info("Got mutes", zap.Any("mutes", mutes))
+				req.cache.storeMutes(req.id, mutes)
+
+				resp.Lock()
+				defer resp.Unlock()
+
+				if resp.state.Mutes == nil {
+					resp.state.Mutes = mutes
+					return
+				}
+
+				for block, muted := range mutes {
+					resp.state.Mutes[block] = muted
+				}
+			}()
+		}
 	}
 
 	wg.Wait()
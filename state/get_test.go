@@ -826,36 +826,43 @@ var getTests = []getStateTest{
 					ID:    "ITB-1101-D1",
 					Field: "blank",
 					Error: "can't get blank",
+					Code:  avcontrol.ErrorCodeDeviceError,
 				},
 				{
 					ID:    "ITB-1101-D1",
 					Field: "inputs.$.audioVideo",
 					Error: "can't get audio video inputs",
+					Code:  avcontrol.ErrorCodeDeviceError,
 				},
 				{
 					ID:    "ITB-1101-D1",
 					Field: "mutes",
 					Error: "can't get mutes",
+					Code:  avcontrol.ErrorCodeDeviceError,
 				},
 				{
 					ID:    "ITB-1101-D1",
 					Field: "power",
 					Error: "can't get power",
+					Code:  avcontrol.ErrorCodeDeviceError,
 				},
 				{
 					ID:    "ITB-1101-D1",
 					Field: "volumes",
 					Error: "can't get volumes",
+					Code:  avcontrol.ErrorCodeDeviceError,
 				},
 				{
 					ID:    "ITB-1101-SW1",
 					Field: "inputs.$.audio",
 					Error: "can't get audio inputs",
+					Code:  avcontrol.ErrorCodeDeviceError,
 				},
 				{
 					ID:    "ITB-1101-SW1",
 					Field: "inputs.$.video",
 					Error: "can't get video inputs",
+					Code:  avcontrol.ErrorCodeDeviceError,
 				},
 			},
 		},
@@ -943,6 +950,30 @@ func TestGetState(t *testing.T) {
 	}
 }
 
+func TestGetUnregisteredDriver(t *testing.T) {
+	is := is.New(t)
+
+	gs, room := adjustRoom(t, mock.TV{
+		WithPower: mock.WithPower{PoweredOn: true},
+	})
+
+	room.Devices["ITB-1101-D2"] = avcontrol.DeviceConfig{
+		Address: "ITB-1101-D2",
+		Driver:  "nec/projector",
+	}
+
+	resp, err := gs.Get(context.Background(), room)
+	is.NoErr(err)
+	is.Equal(resp.Devices["ITB-1101-D1"].PoweredOn, boolP(true))
+	is.Equal(resp.Errors, []avcontrol.DeviceStateError{
+		{ID: "ITB-1101-D2", Error: "unable to get device: nec/projector: driver not registered", Code: avcontrol.ErrorCodeDriverNotRegistered},
+	})
+
+	gs.StrictDrivers = true
+	_, err = gs.Get(context.Background(), room)
+	is.True(errors.Is(err, ErrDriverNotRegistered))
+}
+
 /*
 func TestGetWrongDriver(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
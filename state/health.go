@@ -27,11 +27,8 @@ func (gs *GetSetter) GetHealth(ctx context.Context, room avcontrol.RoomConfig) (
 		return avcontrol.RoomHealth{}, nil
 	}
 
-	// make sure the driver for every device in the room exists
-	for _, dev := range room.Devices {
-		if gs.DriverRegistry.Get(dev.Driver) == nil {
-			return avcontrol.RoomHealth{}, fmt.Errorf("%s: %w", dev.Driver, ErrDriverNotRegistered)
-		}
+	if err := gs.checkDrivers(room); err != nil {
+		return avcontrol.RoomHealth{}, err
 	}
 
 	id := avcontrol.CtxRequestID(ctx)
@@ -69,6 +66,9 @@ func (gs *GetSetter) GetHealth(ctx context.Context, room avcontrol.RoomConfig) (
 }
 
 func (req *getDeviceHealthRequest) do(ctx context.Context) getDeviceHealthResponse {
+	ctx, span := startDevice(ctx, "get device health", req.id, req.device)
+	defer span.End()
+
 	resp := getDeviceHealthResponse{
 		id: req.id,
 	}
@@ -76,7 +76,10 @@ func (req *getDeviceHealthRequest) do(ctx context.Context) getDeviceHealthRespon
 	req.log.Info("Getting health")
 	req.log.Debug("Getting device")
 
-	dev, err := req.driver.CreateDevice(ctx, req.device.Address)
+	opCtx, done := startOp(ctx, req.id, req.device, "createDevice", "")
+	dev, err := createDevice(opCtx, req.driver, req.device)
+	done(err)
+
 	if err != nil {
 		req.log.Warn("unable to get device", zap.Error(err))
 		str := fmt.Sprintf("unable to get device: %s", err)
@@ -88,7 +91,14 @@ func (req *getDeviceHealthRequest) do(ctx context.Context) getDeviceHealthRespon
 
 	if dev, ok := dev.(avcontrol.DeviceWithHealth); ok {
 		healthy := true
-		if err := dev.Healthy(ctx); err != nil {
+
+		opCtx, done := startOp(ctx, req.id, req.device, "healthy", "")
+		err := limit(opCtx, req.driver, req.device, func() error {
+			return dev.Healthy(opCtx)
+		})
+		done(err)
+
+		if err != nil {
 			req.log.Warn("unable to get health", zap.Error(err))
 			str := err.Error()
 			resp.health.Error = &str
@@ -127,3 +127,19 @@ func TestHealth(t *testing.T) {
 		})
 	}
 }
+
+func TestHealthUnregisteredDriver(t *testing.T) {
+	is := is.New(t)
+
+	gs, room := adjustRoom(t, mock.TV{})
+	room.Devices["ITB-1101-D2"] = avcontrol.DeviceConfig{
+		Address: "ITB-1101-D2",
+		Driver:  "nec/projector",
+	}
+
+	health, err := gs.GetHealth(context.Background(), room)
+	is.NoErr(err)
+	is.True(health.Devices["ITB-1101-D1"].Error == nil)
+	is.True(health.Devices["ITB-1101-D2"].Error != nil)
+	is.Equal(*health.Devices["ITB-1101-D2"].Error, "unable to get device: nec/projector: driver not registered")
+}
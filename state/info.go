@@ -27,11 +27,8 @@ func (gs *GetSetter) GetInfo(ctx context.Context, room avcontrol.RoomConfig) (av
 		return avcontrol.RoomInfo{}, nil
 	}
 
-	// make sure the driver for every device in the room exists
-	for _, dev := range room.Devices {
-		if gs.DriverRegistry.Get(dev.Driver) == nil {
-			return avcontrol.RoomInfo{}, fmt.Errorf("%s: %w", dev.Driver, ErrDriverNotRegistered)
-		}
+	if err := gs.checkDrivers(room); err != nil {
+		return avcontrol.RoomInfo{}, err
 	}
 
 	id := avcontrol.CtxRequestID(ctx)
@@ -69,6 +66,9 @@ func (gs *GetSetter) GetInfo(ctx context.Context, room avcontrol.RoomConfig) (av
 }
 
 func (req *getDeviceInfoRequest) do(ctx context.Context) getDeviceInfoResponse {
+	ctx, span := startDevice(ctx, "get device info", req.id, req.device)
+	defer span.End()
+
 	resp := getDeviceInfoResponse{
 		id: req.id,
 	}
@@ -76,7 +76,10 @@ func (req *getDeviceInfoRequest) do(ctx context.Context) getDeviceInfoResponse {
 	req.log.Info("Getting info")
 	req.log.Debug("Getting device")
 
-	dev, err := req.driver.CreateDevice(ctx, req.device.Address)
+	opCtx, done := startOp(ctx, req.id, req.device, "createDevice", "")
+	dev, err := createDevice(opCtx, req.driver, req.device)
+	done(err)
+
 	if err != nil {
 		req.log.Warn("unable to get device", zap.Error(err))
 		str := fmt.Sprintf("unable to get device: %s", err)
@@ -87,7 +90,14 @@ func (req *getDeviceInfoRequest) do(ctx context.Context) getDeviceInfoResponse {
 	req.log.Debug("Got device")
 
 	if dev, ok := dev.(avcontrol.DeviceWithInfo); ok {
-		info, err := dev.Info(ctx)
+		opCtx, done := startOp(ctx, req.id, req.device, "info", "")
+		var info interface{}
+		err := limit(opCtx, req.driver, req.device, func() (err error) {
+			info, err = dev.Info(opCtx)
+			return err
+		})
+		done(err)
+
 		if err != nil {
 			req.log.Warn("unable to get info", zap.Error(err))
 			str := err.Error()
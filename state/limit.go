@@ -0,0 +1,39 @@
+package state
+
+import (
+	"context"
+	"fmt"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+)
+
+// limit runs cmd once driver says that a command can be sent to device.
+// If driver doesn't limit its devices, cmd is run right away. Limits are per address, even if
+// devices at the same address have different config. The result of cmd is reported back to
+// driver if it caches its devices, so that it can throw away devices that keep failing.
+func limit(ctx context.Context, driver avcontrol.Driver, device avcontrol.DeviceConfig, cmd func() error) error {
+	if d, ok := driver.(avcontrol.DriverWithDeviceCache); ok {
+		cmd = reported(d, device, cmd)
+	}
+
+	d, ok := driver.(avcontrol.DriverWithLimiter)
+	if !ok {
+		return cmd()
+	}
+
+	release, err := d.Acquire(ctx, device.Address)
+	if err != nil {
+		return fmt.Errorf("unable to wait for device to be free: %w", err)
+	}
+	defer release()
+
+	return cmd()
+}
+
+func reported(d avcontrol.DriverWithDeviceCache, device avcontrol.DeviceConfig, cmd func() error) func() error {
+	return func() error {
+		err := cmd()
+		d.Report(device.Address, device.Config, err)
+		return err
+	}
+}
@@ -0,0 +1,95 @@
+package state
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/byuoitav/av-control-api/drivers"
+	"github.com/matryer/is"
+	"go.uber.org/zap"
+)
+
+// serialDSP records the most volume commands it was sent at once.
+type serialDSP struct {
+	mu      sync.Mutex
+	running int
+	most    int
+}
+
+func (d *serialDSP) Volumes(ctx context.Context, blocks []string) (map[string]int, error) {
+	return map[string]int{}, nil
+}
+
+func (d *serialDSP) SetVolume(ctx context.Context, block string, vol int) error {
+	d.mu.Lock()
+	d.running++
+	if d.running > d.most {
+		d.most = d.running
+	}
+	d.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	d.mu.Lock()
+	d.running--
+	d.mu.Unlock()
+	return nil
+}
+
+func TestSetLimits(t *testing.T) {
+	is := is.New(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	registry, err := drivers.NewWithConfig(map[string]map[string]interface{}{
+		"driverstest/driver": {
+			"limits": map[interface{}]interface{}{
+				"maxConcurrent": 1,
+			},
+		},
+	})
+	is.NoErr(err)
+
+	dsp := &serialDSP{}
+	is.NoErr(registry.Register("driverstest/driver", &settleDriver{dev: dsp}))
+
+	gs := &GetSetter{
+		Logger:         zap.NewNop(),
+		DriverRegistry: registry,
+	}
+
+	room := avcontrol.RoomConfig{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceConfig{
+			"ITB-1101-DSP1": {
+				Address: "ITB-1101-DSP1",
+				Driver:  "driverstest/driver",
+				Ports: avcontrol.PortConfigs{
+					{Name: "mic1", Type: "volume"},
+					{Name: "mic2", Type: "volume"},
+					{Name: "mic3", Type: "volume"},
+					{Name: "mic4", Type: "volume"},
+				},
+			},
+		},
+	}
+
+	resp, err := gs.Set(ctx, room, avcontrol.StateRequest{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+			"ITB-1101-DSP1": {
+				Volumes: map[string]int{
+					"mic1": 10,
+					"mic2": 20,
+					"mic3": 30,
+					"mic4": 40,
+				},
+			},
+		},
+	})
+	is.NoErr(err)
+	is.Equal(len(resp.Errors), 0)
+	is.Equal(dsp.most, 1)
+}
@@ -0,0 +1,44 @@
+package state
+
+import (
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	deviceOpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "avcontrol",
+		Subsystem: "device",
+		Name:      "operation_duration_seconds",
+		Help:      "How long each operation on a device took, including failed operations.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 20},
+	}, []string{"driver", "room", "device", "operation"})
+
+	deviceOpErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "avcontrol",
+		Subsystem: "device",
+		Name:      "operation_errors_total",
+		Help:      "How many operations on a device have failed.",
+	}, []string{"driver", "room", "device", "operation"})
+)
+
+// observe records how long op took on the device with id since start, and whether it failed.
+func observe(driver string, id avcontrol.DeviceID, op string, start time.Time, err error) {
+	labels := prometheus.Labels{
+		"driver":    driver,
+		"room":      id.Room(),
+		"device":    string(id),
+		"operation": op,
+	}
+
+	deviceOpDuration.With(labels).Observe(time.Since(start).Seconds())
+
+	// always create the error counter so that error rates can be computed before the first error
+	counter := deviceOpErrors.With(labels)
+	if err != nil {
+		counter.Inc()
+	}
+}
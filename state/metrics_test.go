@@ -0,0 +1,22 @@
+package state
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserve(t *testing.T) {
+	is := is.New(t)
+
+	errs := deviceOpErrors.WithLabelValues("driverstest/driver", "ITB-1101", "ITB-1101-D9", "power")
+
+	observe("driverstest/driver", "ITB-1101-D9", "power", time.Now(), nil)
+	is.Equal(testutil.ToFloat64(errs), float64(0))
+
+	observe("driverstest/driver", "ITB-1101-D9", "power", time.Now(), errors.New("broken"))
+	is.Equal(testutil.ToFloat64(errs), float64(1))
+}
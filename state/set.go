@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	avcontrol "github.com/byuoitav/av-control-api"
 	"go.uber.org/zap"
@@ -14,6 +15,8 @@ var (
 	ErrInvalidDevice = errors.New("device is invalid in this room")
 	ErrNotCapable    = errors.New("can't set this field on this device")
 	ErrInvalidBlock  = errors.New("invalid block")
+	ErrInvalidPort   = errors.New("invalid port")
+	ErrPhaseSkipped  = errors.New("skipped because an earlier phase had an error")
 )
 
 type setDeviceStateRequest struct {
@@ -22,6 +25,8 @@ type setDeviceStateRequest struct {
 	state  avcontrol.DeviceState
 	driver avcontrol.Driver
 	log    *zap.Logger
+	cache  *Cache
+	scale  string
 }
 
 type setDeviceStateResponse struct {
@@ -33,22 +38,30 @@ type setDeviceStateResponse struct {
 
 // Set goes through the devices in the RoomConfig and sets their states to match whats in the given StateRequest.
 // Takes RoomConfig and StateRequest as input and returns StateResponse.
+//
+// The devices in req.Devices are all set at the same time, and then each of req.Phases
+// is applied in order. The adjustments in each phase are applied before its devices are set. If req.AbortOnError is set, the remaining phases are skipped once
+// a phase has an error, and an ErrPhaseSkipped error is returned for each skipped device.
+//
+// Volumes are given and returned on the scale set in ctx (see avcontrol.WithVolumeScale). Volumes that are
+// out of range for their port are set to the closest volume in range, and an ErrOutOfRange error is returned for them.
 func (gs *GetSetter) Set(ctx context.Context, room avcontrol.RoomConfig, req avcontrol.StateRequest) (avcontrol.StateResponse, error) {
-	if len(room.Devices) == 0 || len(req.Devices) == 0 {
+	if len(room.Devices) == 0 || (len(req.Devices) == 0 && len(req.Adjust) == 0 && len(req.Phases) == 0) {
 		return avcontrol.StateResponse{}, nil
 	}
 
-	// make sure the driver for every device in the room exists
-	for _, dev := range room.Devices {
-		if gs.DriverRegistry.Get(dev.Driver) == nil {
-			return avcontrol.StateResponse{}, fmt.Errorf("%s: %w", dev.Driver, ErrDriverNotRegistered)
-		}
+	if err := gs.checkDrivers(room); err != nil {
+		return avcontrol.StateResponse{}, err
 	}
 
+	phases := append([]avcontrol.StatePhase{{Devices: req.Devices, Adjust: req.Adjust}}, req.Phases...)
+
 	// make sure each of the devices in the request are in this room
-	for id := range req.Devices {
-		if _, ok := room.Devices[id]; !ok {
-			return avcontrol.StateResponse{}, fmt.Errorf("%s: %w", id, ErrInvalidDevice)
+	for _, phase := range phases {
+		for _, id := range phaseDevices(phase) {
+			if _, ok := room.Devices[id]; !ok {
+				return avcontrol.StateResponse{}, fmt.Errorf("%s: %w", id, ErrInvalidDevice)
+			}
 		}
 	}
 
@@ -58,46 +71,135 @@ func (gs *GetSetter) Set(ctx context.Context, room avcontrol.RoomConfig, req avc
 		log = gs.Logger.With(zap.String("requestID", id))
 	}
 
-	expectedResps := 0
-	resps := make(chan *setDeviceStateResponse)
-	defer close(resps)
-
 	stateResp := avcontrol.StateResponse{
 		Devices: make(map[avcontrol.DeviceID]avcontrol.DeviceState),
 	}
 
+	var skip error
+	for i, phase := range phases {
+		if len(phase.Devices) == 0 && len(phase.Adjust) == 0 {
+			continue
+		}
+
+		if skip == nil && phase.Delay > 0 {
+			log.Info("Waiting to start phase", zap.Int("phase", i), zap.Duration("delay", time.Duration(phase.Delay)))
+
+			select {
+			case <-ctx.Done():
+				skip = ctx.Err()
+			case <-time.After(time.Duration(phase.Delay)):
+			}
+		}
+
+		if skip != nil {
+			log.Info("Skipping phase", zap.Int("phase", i), zap.NamedError("reason", skip))
+
+			for _, id := range phaseDevices(phase) {
+				stateResp.Errors = append(stateResp.Errors, avcontrol.DeviceStateError{
+					ID:    id,
+					Error: skip.Error(),
+					Code:  deviceErrorCode(skip),
+					Phase: i,
+				})
+			}
+
+			continue
+		}
+
+		if len(req.Phases) > 0 {
+			log.Info("Starting phase", zap.Int("phase", i))
+		}
+
+		var numErrors int
+		resps := gs.adjustDevices(ctx, log, room, phase.Adjust)
+		resps = append(resps, gs.setDevices(ctx, log, room, phase.Devices)...)
+
+		for _, resp := range resps {
+			if prev, ok := stateResp.Devices[resp.id]; ok {
+				stateResp.Devices[resp.id], _ = mergeState(prev, resp.state)
+			} else {
+				stateResp.Devices[resp.id] = resp.state
+			}
+
+			for _, err := range resp.errors {
+				err.Phase = i
+				stateResp.Errors = append(stateResp.Errors, err)
+			}
+
+			numErrors += len(resp.errors)
+		}
+
+		if req.AbortOnError && numErrors > 0 {
+			skip = ErrPhaseSkipped
+		}
+	}
+
+	gs.publish(room.ID, stateResp.Devices, time.Now())
+	gs.emit(ctx, log, room.ID, stateResp.Devices)
+
+	var errs []avcontrol.DeviceStateError
+	stateResp.Devices, errs = scaleVolumes(room, stateResp.Devices, avcontrol.CtxVolumeScale(ctx))
+	stateResp.Errors = append(stateResp.Errors, errs...)
+
+	sortErrors(stateResp.Errors)
+	return stateResp, nil
+}
+
+// phaseDevices returns the id of each device that phase sets or adjusts.
+func phaseDevices(phase avcontrol.StatePhase) []avcontrol.DeviceID {
+	var ids []avcontrol.DeviceID
+	for id := range phase.Adjust {
+		ids = append(ids, id)
+	}
+
+	for id := range phase.Devices {
+		if _, ok := phase.Adjust[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// setDevices sets the state of each of the devices in states at the same time,
+// and returns once every device has finished.
+func (gs *GetSetter) setDevices(ctx context.Context, log *zap.Logger, room avcontrol.RoomConfig, states map[avcontrol.DeviceID]avcontrol.DeviceState) []*setDeviceStateResponse {
+	var resps []*setDeviceStateResponse
+	respCh := make(chan *setDeviceStateResponse)
+	defer close(respCh)
+
 	for id, dev := range room.Devices {
-		state, ok := req.Devices[id]
+		state, ok := states[id]
 		if !ok {
 			continue
 		}
 
-		expectedResps++
 		req := setDeviceStateRequest{
 			id:     id,
 			device: dev,
 			state:  state,
 			driver: gs.DriverRegistry.Get(dev.Driver),
 			log:    log.With(zap.String("deviceID", string(id))),
+			cache:  gs.Cache,
+			scale:  avcontrol.CtxVolumeScale(ctx),
 		}
 
 		go func() {
-			resps <- req.do(ctx)
+			respCh <- req.do(ctx)
 		}()
 	}
 
-	for i := 0; i < expectedResps; i++ {
-		resp := <-resps
-
-		stateResp.Devices[resp.id] = resp.state
-		stateResp.Errors = append(stateResp.Errors, resp.errors...)
+	for len(resps) < len(states) {
+		resps = append(resps, <-respCh)
 	}
 
-	sortErrors(stateResp.Errors)
-	return stateResp, nil
+	return resps
 }
 
 func (req *setDeviceStateRequest) do(ctx context.Context) *setDeviceStateResponse {
+	ctx, span := startDevice(ctx, "set device state", req.id, req.device)
+	defer span.End()
+
 	resp := &setDeviceStateResponse{
 		id: req.id,
 	}
@@ -105,12 +207,15 @@ func (req *setDeviceStateRequest) do(ctx context.Context) *setDeviceStateRespons
 	req.log.Info("Setting state")
 	req.log.Debug("Getting device")
 
-	dev, err := req.driver.CreateDevice(ctx, req.device.Address)
+	opCtx, done := startOp(ctx, req.id, req.device, "createDevice", "")
+	dev, err := createDevice(opCtx, req.driver, req.device)
+	done(err)
 	if err != nil {
 		req.log.Warn("unable to get device", zap.Error(err))
 		resp.errors = append(resp.errors, avcontrol.DeviceStateError{
 			ID:    req.id,
 			Error: fmt.Sprintf("unable to get device: %s", err),
+			Code:  deviceErrorCode(err),
 		})
 		return resp
 	}
@@ -128,22 +233,40 @@ func (req *setDeviceStateRequest) do(ctx context.Context) *setDeviceStateRespons
 			Field: field,
 			Value: value,
 			Error: err.Error(),
+			Code:  deviceErrorCode(err),
 		})
 	}
 
 	// set every field in req.state
 	wg := sync.WaitGroup{}
 
+	// settled wraps the commands that devices may reject while warming up.
+	// it is replaced below if the device was just powered on.
+	settled := func(set func() error) error {
+		return set()
+	}
+
 	// setting power happens before everything else is run
 	if req.state.PoweredOn != nil {
 		if dev, ok := dev.(avcontrol.DeviceWithPower); ok {
 			req.log.Info("Setting power", zap.Bool("poweredOn", *req.state.PoweredOn))
 
-			if err := dev.SetPower(ctx, *req.state.PoweredOn); err != nil {
+			opCtx, done := startOp(ctx, req.id, req.device, "setPower", "poweredOn")
+			err := limit(opCtx, req.driver, req.device, func() error {
+				return dev.SetPower(opCtx, *req.state.PoweredOn)
+			})
+			done(err)
+
+			if err != nil {
 				handleErr("poweredOn", *req.state.PoweredOn, err)
 			} else {
 				req.log.Info("Set power")
+				req.cache.storePower(req.id, *req.state.PoweredOn)
 				resp.state.PoweredOn = req.state.PoweredOn
+
+				if *req.state.PoweredOn && (len(req.state.Inputs) > 0 || req.state.Blanked != nil) {
+					settled = req.settle(ctx, dev)
+				}
 			}
 		} else {
 			handleErr("poweredOn", *req.state.PoweredOn, ErrNotCapable)
@@ -151,8 +274,10 @@ func (req *setDeviceStateRequest) do(ctx context.Context) *setDeviceStateRespons
 	}
 
 	// figure out which inputs we set
+	inputs := req.resolveInputs(handleErr)
+
 	var setAudioInput, setVideoInput, setAudioVideoInput bool
-	for _, input := range req.state.Inputs {
+	for _, input := range inputs {
 		if input.Audio != nil {
 			setAudioInput = true
 		}
@@ -168,7 +293,7 @@ func (req *setDeviceStateRequest) do(ctx context.Context) *setDeviceStateRespons
 
 	if setAudioInput {
 		if dev, ok := dev.(avcontrol.DeviceWithAudioInput); ok {
-			for output, input := range req.state.Inputs {
+			for output, input := range inputs {
 				if input.Audio == nil {
 					continue
 				}
@@ -178,12 +303,20 @@ func (req *setDeviceStateRequest) do(ctx context.Context) *setDeviceStateRespons
 					req.log.Info("Setting audio input", zap.String("output", output), zap.String("input", input))
 					defer wg.Done()
 
-					if err := dev.SetAudioInput(ctx, output, input); err != nil {
+					opCtx, done := startOp(ctx, req.id, req.device, "setAudioInput", fmt.Sprintf("input.%s.audio", output))
+					err := settled(func() error {
+						return limit(opCtx, req.driver, req.device, func() error {
+							return dev.SetAudioInput(opCtx, output, input)
+						})
+					})
+					done(err)
+					if err != nil {
 						handleErr(fmt.Sprintf("input.%s.audio", output), input, err)
 						return
 					}
 
 					req.log.Info("Set audio input", zap.String("output", output))
+					req.cache.storeInput(req.id, _inputAudio, output, input)
 
 					resp.Lock()
 					defer resp.Unlock()
@@ -204,7 +337,7 @@ func (req *setDeviceStateRequest) do(ctx context.Context) *setDeviceStateRespons
 
 	if setVideoInput {
 		if dev, ok := dev.(avcontrol.DeviceWithVideoInput); ok {
-			for output, input := range req.state.Inputs {
+			for output, input := range inputs {
 				if input.Video == nil {
 					continue
 				}
@@ -214,12 +347,20 @@ func (req *setDeviceStateRequest) do(ctx context.Context) *setDeviceStateRespons
 					req.log.Info("Setting video input", zap.String("output", output), zap.String("input", input))
 					defer wg.Done()
 
-					if err := dev.SetVideoInput(ctx, output, input); err != nil {
+					opCtx, done := startOp(ctx, req.id, req.device, "setVideoInput", fmt.Sprintf("input.%s.video", output))
+					err := settled(func() error {
+						return limit(opCtx, req.driver, req.device, func() error {
+							return dev.SetVideoInput(opCtx, output, input)
+						})
+					})
+					done(err)
+					if err != nil {
 						handleErr(fmt.Sprintf("input.%s.video", output), input, err)
 						return
 					}
 
 					req.log.Info("Set video input", zap.String("output", output))
+					req.cache.storeInput(req.id, _inputVideo, output, input)
 
 					resp.Lock()
 					defer resp.Unlock()
@@ -240,7 +381,7 @@ func (req *setDeviceStateRequest) do(ctx context.Context) *setDeviceStateRespons
 
 	if setAudioVideoInput {
 		if dev, ok := dev.(avcontrol.DeviceWithAudioVideoInput); ok {
-			for output, input := range req.state.Inputs {
+			for output, input := range inputs {
 				if input.AudioVideo == nil {
 					continue
 				}
@@ -250,12 +391,20 @@ func (req *setDeviceStateRequest) do(ctx context.Context) *setDeviceStateRespons
 					req.log.Info("Setting audioVideo input", zap.String("output", output), zap.String("input", input))
 					defer wg.Done()
 
-					if err := dev.SetAudioVideoInput(ctx, output, input); err != nil {
+					opCtx, done := startOp(ctx, req.id, req.device, "setAudioVideoInput", fmt.Sprintf("input.%s.audioVideo", output))
+					err := settled(func() error {
+						return limit(opCtx, req.driver, req.device, func() error {
+							return dev.SetAudioVideoInput(opCtx, output, input)
+						})
+					})
+					done(err)
+					if err != nil {
 						handleErr(fmt.Sprintf("input.%s.audioVideo", output), input, err)
 						return
 					}
 
 					req.log.Info("Set audioVideo input", zap.String("output", output))
+					req.cache.storeInput(req.id, _inputAudioVideo, output, input)
 
 					resp.Lock()
 					defer resp.Unlock()
@@ -282,12 +431,20 @@ func (req *setDeviceStateRequest) do(ctx context.Context) *setDeviceStateRespons
 				req.log.Info("Setting blank", zap.Bool("blanked", *req.state.Blanked))
 				defer wg.Done()
 
-				if err := dev.SetBlank(ctx, *req.state.Blanked); err != nil {
+				opCtx, done := startOp(ctx, req.id, req.device, "setBlank", "blanked")
+				err := settled(func() error {
+					return limit(opCtx, req.driver, req.device, func() error {
+						return dev.SetBlank(opCtx, *req.state.Blanked)
+					})
+				})
+				done(err)
+				if err != nil {
 					handleErr("blanked", *req.state.Blanked, err)
 					return
 				}
 
 				req.log.Info("Set blank")
+				req.cache.storeBlank(req.id, *req.state.Blanked)
 
 				resp.Lock()
 				defer resp.Unlock()
@@ -307,17 +464,34 @@ func (req *setDeviceStateRequest) do(ctx context.Context) *setDeviceStateRespons
 					continue
 				}
 
+				// out of range volumes are still set, just to the closest volume in range
+				port, _ := req.device.Ports.Find("volume", block)
+				native, err := toNative(port, req.scale, vol)
+				if err != nil {
+					handleErr(fmt.Sprintf("volumes.%s", block), vol, err)
+					if !errors.Is(err, ErrOutOfRange) {
+						continue
+					}
+				}
+
 				wg.Add(1)
-				go func(block string, vol int) {
+				go func(block string, requested, vol int) {
 					req.log.Info("Setting volume", zap.String("block", block), zap.Int("level", vol))
 					defer wg.Done()
 
-					if err := dev.SetVolume(ctx, block, vol); err != nil {
-						handleErr(fmt.Sprintf("volumes.%s", block), vol, err)
+					opCtx, done := startOp(ctx, req.id, req.device, "setVolume", fmt.Sprintf("volumes.%s", block))
+					err := limit(opCtx, req.driver, req.device, func() error {
+						return dev.SetVolume(opCtx, block, vol)
+					})
+					done(err)
+
+					if err != nil {
+						handleErr(fmt.Sprintf("volumes.%s", block), requested, err)
 						return
 					}
 
 					req.log.Info("Set volume", zap.String("block", block))
+					req.cache.storeVolumes(req.id, map[string]int{block: vol})
 
 					resp.Lock()
 					defer resp.Unlock()
@@ -327,7 +501,7 @@ func (req *setDeviceStateRequest) do(ctx context.Context) *setDeviceStateRespons
 					}
 
 					resp.state.Volumes[block] = vol
-				}(block, vol)
+				}(block, vol, native)
 			}
 		} else {
 			handleErr("volumes", req.state.Volumes, ErrNotCapable)
@@ -348,12 +522,19 @@ func (req *setDeviceStateRequest) do(ctx context.Context) *setDeviceStateRespons
 					req.log.Info("Setting mute", zap.String("block", block), zap.Bool("muted", muted))
 					defer wg.Done()
 
-					if err := dev.SetMute(ctx, block, muted); err != nil {
+					opCtx, done := startOp(ctx, req.id, req.device, "setMute", fmt.Sprintf("mutes.%s", block))
+					err := limit(opCtx, req.driver, req.device, func() error {
+						return dev.SetMute(opCtx, block, muted)
+					})
+					done(err)
+
+					if err != nil {
 						handleErr(fmt.Sprintf("mutes.%s", block), muted, err)
 						return
 					}
 
 					req.log.Info("Set mute", zap.String("block", block))
+					req.cache.storeMutes(req.id, map[string]bool{block: muted})
 
 					resp.Lock()
 					defer resp.Unlock()
@@ -375,3 +556,70 @@ func (req *setDeviceStateRequest) do(ctx context.Context) *setDeviceStateRespons
 	req.log.Info("Finished setting state")
 	return resp
 }
+
+// resolveInputs checks req.state.Inputs against the device's input and output ports, and translates port labels
+// into the names the driver knows the ports by. Inputs that can't be set are reported with handleErr and left out
+// of the returned inputs. If the device has no input or output ports, req.state.Inputs is returned as is.
+// The response, cache, and events use the translated names, so that they match what Get returns.
+func (req *setDeviceStateRequest) resolveInputs(handleErr func(string, interface{}, error)) map[string]avcontrol.Input {
+	if len(req.device.Ports.OfType("input")) == 0 && len(req.device.Ports.OfType("output")) == 0 {
+		return req.state.Inputs
+	}
+
+	resolved := make(map[string]avcontrol.Input)
+	resolve := func(output, kind string, input *string) {
+		if input == nil {
+			return
+		}
+
+		out, err := req.resolvePort("output", output, kind)
+		if err != nil {
+			handleErr(fmt.Sprintf("input.%s.%s", output, kind), *input, err)
+			return
+		}
+
+		in, err := req.resolvePort("input", *input, kind)
+		if err != nil {
+			handleErr(fmt.Sprintf("input.%s.%s", output, kind), *input, err)
+			return
+		}
+
+		i := resolved[out]
+		switch kind {
+		case avcontrol.PortKindAudio:
+			i.Audio = &in
+		case avcontrol.PortKindVideo:
+			i.Video = &in
+		case avcontrol.PortKindAudioVideo:
+			i.AudioVideo = &in
+		}
+
+		resolved[out] = i
+	}
+
+	for output, input := range req.state.Inputs {
+		resolve(output, avcontrol.PortKindAudio, input.Audio)
+		resolve(output, avcontrol.PortKindVideo, input.Video)
+		resolve(output, avcontrol.PortKindAudioVideo, input.AudioVideo)
+	}
+
+	return resolved
+}
+
+// resolvePort returns the name of the port of type typ (input or output) that name refers to,
+// if it can carry a signal of kind. Any name is valid if the device has no ports of type typ.
+func (req *setDeviceStateRequest) resolvePort(typ, name, kind string) (string, error) {
+	if len(req.device.Ports.OfType(typ)) == 0 {
+		return name, nil
+	}
+
+	port, ok := req.device.Ports.Find(typ, name)
+	switch {
+	case !ok:
+		return "", fmt.Errorf("%w: %q is not an %s on this device", ErrInvalidPort, name, typ)
+	case !port.Carries(kind):
+		return "", fmt.Errorf("%w: %s %q can't carry %s", ErrInvalidPort, typ, name, kind)
+	}
+
+	return port.Name, nil
+}
@@ -525,42 +525,49 @@ var setTests = []setStateTest{
 					Field: "blanked",
 					Value: false,
 					Error: "can't set blank",
+					Code:  avcontrol.ErrorCodeDeviceError,
 				},
 				{
 					ID:    "ITB-1101-D1",
 					Field: "input..audioVideo",
 					Value: "hdmi2",
 					Error: "can't set audio video inputs",
+					Code:  avcontrol.ErrorCodeDeviceError,
 				},
 				{
 					ID:    "ITB-1101-D1",
 					Field: "mutes.aux",
 					Value: true,
 					Error: "can't set mutes",
+					Code:  avcontrol.ErrorCodeDeviceError,
 				},
 				{
 					ID:    "ITB-1101-D1",
 					Field: "poweredOn",
 					Value: true,
 					Error: "can't set power",
+					Code:  avcontrol.ErrorCodeDeviceError,
 				},
 				{
 					ID:    "ITB-1101-D1",
 					Field: "volumes.headphones",
 					Value: 30,
 					Error: "can't set volumes",
+					Code:  avcontrol.ErrorCodeDeviceError,
 				},
 				{
 					ID:    "ITB-1101-SW1",
 					Field: "input.hdmiOutA.audio",
 					Value: "1",
 					Error: "can't set audio inputs",
+					Code:  avcontrol.ErrorCodeDeviceError,
 				},
 				{
 					ID:    "ITB-1101-SW1",
 					Field: "input.hdmiOutA.video",
 					Value: "2",
 					Error: "can't set video inputs",
+					Code:  avcontrol.ErrorCodeDeviceError,
 				},
 			},
 		},
@@ -601,6 +608,7 @@ var setTests = []setStateTest{
 					Field: "blanked",
 					Value: false,
 					Error: ErrNotCapable.Error(),
+					Code:  avcontrol.ErrorCodeNotCapable,
 				},
 				{
 					ID:    "ITB-1101-D4",
@@ -615,6 +623,7 @@ var setTests = []setStateTest{
 						},
 					},
 					Error: ErrNotCapable.Error(),
+					Code:  avcontrol.ErrorCodeNotCapable,
 				},
 				{
 					ID:    "ITB-1101-D4",
@@ -629,6 +638,7 @@ var setTests = []setStateTest{
 						},
 					},
 					Error: ErrNotCapable.Error(),
+					Code:  avcontrol.ErrorCodeNotCapable,
 				},
 				{
 					ID:    "ITB-1101-D4",
@@ -643,24 +653,28 @@ var setTests = []setStateTest{
 						},
 					},
 					Error: ErrNotCapable.Error(),
+					Code:  avcontrol.ErrorCodeNotCapable,
 				},
 				{
 					ID:    "ITB-1101-D4",
 					Field: "mutes",
 					Value: map[string]bool{"block2": true},
 					Error: ErrNotCapable.Error(),
+					Code:  avcontrol.ErrorCodeNotCapable,
 				},
 				{
 					ID:    "ITB-1101-D4",
 					Field: "poweredOn",
 					Value: true,
 					Error: ErrNotCapable.Error(),
+					Code:  avcontrol.ErrorCodeNotCapable,
 				},
 				{
 					ID:    "ITB-1101-D4",
 					Field: "volumes",
 					Value: map[string]int{"block1": 10},
 					Error: ErrNotCapable.Error(),
+					Code:  avcontrol.ErrorCodeNotCapable,
 				},
 			},
 		},
@@ -701,12 +715,14 @@ var setTests = []setStateTest{
 					Field: "mutes.invalid",
 					Value: true,
 					Error: ErrInvalidBlock.Error(),
+					Code:  avcontrol.ErrorCodeInvalidBlock,
 				},
 				{
 					ID:    "ITB-1101-DSP1",
 					Field: "volumes.invalid",
 					Value: 77,
 					Error: ErrInvalidBlock.Error(),
+					Code:  avcontrol.ErrorCodeInvalidBlock,
 				},
 			},
 		},
@@ -795,6 +811,296 @@ func TestSetState(t *testing.T) {
 	}
 }
 
+func TestSetPhases(t *testing.T) {
+	tests := []struct {
+		name string
+		req  avcontrol.StateRequest
+		resp avcontrol.StateResponse
+	}{
+		{
+			name: "InOrder",
+			req: avcontrol.StateRequest{
+				Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+					"ITB-1101-D1": {
+						PoweredOn: boolP(true),
+					},
+				},
+				Phases: []avcontrol.StatePhase{
+					{
+						Delay: avcontrol.Duration(10 * time.Millisecond),
+						Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+							"ITB-1101-D1": {
+								Blanked: boolP(false),
+							},
+						},
+					},
+				},
+			},
+			resp: avcontrol.StateResponse{
+				Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+					"ITB-1101-D1": {
+						PoweredOn: boolP(true),
+						Blanked:   boolP(false),
+					},
+				},
+			},
+		},
+		{
+			name: "ContinueOnError",
+			req: avcontrol.StateRequest{
+				Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+					"ITB-1101-D2": {
+						PoweredOn: boolP(true),
+					},
+				},
+				Phases: []avcontrol.StatePhase{
+					{
+						Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+							"ITB-1101-D1": {
+								PoweredOn: boolP(true),
+							},
+						},
+					},
+				},
+			},
+			resp: avcontrol.StateResponse{
+				Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+					"ITB-1101-D1": {
+						PoweredOn: boolP(true),
+					},
+					"ITB-1101-D2": {},
+				},
+				Errors: []avcontrol.DeviceStateError{
+					{
+						ID:    "ITB-1101-D2",
+						Field: "poweredOn",
+						Value: true,
+						Error: "can't power on",
+						Code:  avcontrol.ErrorCodeDeviceError,
+					},
+				},
+			},
+		},
+		{
+			name: "AbortOnError",
+			req: avcontrol.StateRequest{
+				AbortOnError: true,
+				Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+					"ITB-1101-D2": {
+						PoweredOn: boolP(true),
+					},
+				},
+				Phases: []avcontrol.StatePhase{
+					{
+						Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+							"ITB-1101-D1": {
+								PoweredOn: boolP(true),
+							},
+						},
+					},
+				},
+			},
+			resp: avcontrol.StateResponse{
+				Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+					"ITB-1101-D2": {},
+				},
+				Errors: []avcontrol.DeviceStateError{
+					{
+						ID:    "ITB-1101-D2",
+						Field: "poweredOn",
+						Value: true,
+						Error: "can't power on",
+						Code:  avcontrol.ErrorCodeDeviceError,
+					},
+					{
+						ID:    "ITB-1101-D1",
+						Error: ErrPhaseSkipped.Error(),
+						Code:  avcontrol.ErrorCodePhaseSkipped,
+						Phase: 1,
+					},
+				},
+			},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			driver := &driverstest.Driver{
+				Devices: map[string]avcontrol.Device{
+					"ITB-1101-D1": mock.Projector{},
+					"ITB-1101-D2": mock.Projector{
+						WithPower: mock.WithPower{
+							SetError: errors.New("can't power on"),
+						},
+					},
+				},
+			}
+
+			registry, err := drivers.NewWithConfig(nil)
+			is.NoErr(err)
+			is.NoErr(registry.Register("driverstest/driver", driver))
+
+			gs := &GetSetter{
+				Logger:         zap.NewNop(),
+				DriverRegistry: registry,
+			}
+
+			room := avcontrol.RoomConfig{
+				ID: "ITB-1101",
+				Devices: map[avcontrol.DeviceID]avcontrol.DeviceConfig{
+					"ITB-1101-D1": {
+						Address: "ITB-1101-D1",
+						Driver:  "driverstest/driver",
+					},
+					"ITB-1101-D2": {
+						Address: "ITB-1101-D2",
+						Driver:  "driverstest/driver",
+					},
+				},
+			}
+
+			resp, err := gs.Set(ctx, room, tt.req)
+			is.NoErr(err)
+			require.Equal(t, tt.resp, resp)
+		})
+	}
+}
+
+func TestSetPhaseInvalidDevice(t *testing.T) {
+	is := is.New(t)
+
+	registry, err := drivers.NewWithConfig(nil)
+	is.NoErr(err)
+	is.NoErr(registry.Register("driverstest/driver", &driverstest.Driver{}))
+
+	gs := &GetSetter{
+		Logger:         zap.NewNop(),
+		DriverRegistry: registry,
+	}
+
+	room := avcontrol.RoomConfig{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceConfig{
+			"ITB-1101-D1": {
+				Address: "ITB-1101-D1",
+				Driver:  "driverstest/driver",
+			},
+		},
+	}
+
+	_, err = gs.Set(context.Background(), room, avcontrol.StateRequest{
+		Phases: []avcontrol.StatePhase{
+			{
+				Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+					"ITB-1101-D2": {
+						PoweredOn: boolP(true),
+					},
+				},
+			},
+		},
+	})
+	is.True(errors.Is(err, ErrInvalidDevice))
+}
+
+func TestSetInputPorts(t *testing.T) {
+	is := is.New(t)
+
+	sw := mock.VideoSwitcher{
+		WithAudioInput: mock.WithAudioInput{
+			Inputs: map[string]string{},
+		},
+		WithVideoInput: mock.WithVideoInput{
+			Inputs: map[string]string{},
+		},
+	}
+
+	registry, err := drivers.NewWithConfig(nil)
+	is.NoErr(err)
+	is.NoErr(registry.Register("driverstest/driver", &driverstest.Driver{
+		Devices: map[string]avcontrol.Device{
+			"ITB-1101-SW1": sw,
+		},
+	}))
+
+	gs := &GetSetter{
+		Logger:         zap.NewNop(),
+		DriverRegistry: registry,
+	}
+
+	room := avcontrol.RoomConfig{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceConfig{
+			"ITB-1101-SW1": {
+				Address: "ITB-1101-SW1",
+				Driver:  "driverstest/driver",
+				Ports: avcontrol.PortConfigs{
+					{Name: "1", Type: "output", Label: "Projector"},
+					{Name: "2", Type: "output", Kind: avcontrol.PortKindAudio},
+					{Name: "hdmi1", Type: "input", Label: "HDMI 1", Kind: avcontrol.PortKindAudioVideo},
+					{Name: "mic", Type: "input", Kind: avcontrol.PortKindAudio},
+				},
+			},
+		},
+	}
+
+	resp, err := gs.Set(context.Background(), room, avcontrol.StateRequest{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+			"ITB-1101-SW1": {
+				Inputs: map[string]avcontrol.Input{
+					"Projector": {Video: stringP("HDMI 1")},
+					"1":         {Audio: stringP("hdmi5")},
+					"2":         {Video: stringP("hdmi1")},
+					"3":         {Audio: stringP("mic")},
+				},
+			},
+		},
+	})
+	is.NoErr(err)
+
+	// the response uses the port names, not the labels in the request
+	is.Equal(resp.Devices["ITB-1101-SW1"].Inputs, map[string]avcontrol.Input{
+		"1": {Video: stringP("hdmi1")},
+	})
+	is.Equal(sw.WithVideoInput.Inputs, map[string]string{"1": "hdmi1"})
+	is.Equal(len(sw.WithAudioInput.Inputs), 0)
+
+	errs := make(map[string]string)
+	for _, e := range resp.Errors {
+		errs[e.Field] = e.Error
+	}
+
+	is.Equal(errs, map[string]string{
+		"input.1.audio": `invalid port: "hdmi5" is not an input on this device`,
+		"input.2.video": `invalid port: output "2" can't carry video`,
+		"input.3.audio": `invalid port: "3" is not an output on this device`,
+	})
+}
+
+func TestSetUnregisteredDriver(t *testing.T) {
+	is := is.New(t)
+
+	gs, room := adjustRoom(t, mock.TV{})
+	room.Devices["ITB-1101-D2"] = avcontrol.DeviceConfig{
+		Address: "ITB-1101-D2",
+		Driver:  "nec/projector",
+	}
+
+	resp, err := gs.Set(context.Background(), room, avcontrol.StateRequest{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+			"ITB-1101-D1": {PoweredOn: boolP(true)},
+			"ITB-1101-D2": {PoweredOn: boolP(true)},
+		},
+	})
+	is.NoErr(err)
+	is.Equal(resp.Devices["ITB-1101-D1"].PoweredOn, boolP(true))
+	is.Equal(len(resp.Errors), 1)
+	is.Equal(resp.Errors[0].ID, avcontrol.DeviceID("ITB-1101-D2"))
+	is.Equal(resp.Errors[0].Code, avcontrol.ErrorCodeDriverNotRegistered)
+}
+
 //func TestSetWrongDriver(t *testing.T) {
 //	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 //	defer cancel()
@@ -0,0 +1,123 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"go.uber.org/zap"
+)
+
+const _defaultSettleInterval = time.Second
+
+// settle waits for dev to report that it is powered on, using the PowerSettle config of req.driver.
+// It returns a function that runs a command on dev. If dev never reported that it was powered on, the command
+// is retried when it fails, since dev may still be warming up, and errors from the returned function say so.
+// Once dev has reported that it is powered on, failures are returned right away.
+func (req *setDeviceStateRequest) settle(ctx context.Context, dev avcontrol.DeviceWithPower) func(func() error) error {
+	var settle avcontrol.PowerSettle
+	if d, ok := req.driver.(avcontrol.DriverWithPowerSettle); ok {
+		settle = d.PowerSettle()
+	}
+
+	if settle.Timeout <= 0 {
+		return func(set func() error) error {
+			return set()
+		}
+	}
+
+	if settle.Interval <= 0 {
+		settle.Interval = _defaultSettleInterval
+	}
+
+	req.log.Info("Waiting for device to power on", zap.Duration("timeout", settle.Timeout))
+
+	notReady := waitForPower(ctx, req.driver, req.device, dev, settle)
+	if notReady != nil {
+		req.log.Warn("device did not finish powering on", zap.Error(notReady))
+	} else {
+		req.log.Info("Device is powered on")
+	}
+
+	wrap := func(err error) error {
+		if err != nil {
+			return fmt.Errorf("%w (%s)", err, notReady)
+		}
+
+		return nil
+	}
+
+	if notReady == nil {
+		return func(set func() error) error {
+			return set()
+		}
+	}
+
+	return func(set func() error) error {
+		err := set()
+		for i := 0; err != nil && i < settle.Retries; i++ {
+			req.log.Info("Retrying command after power on", zap.Int("attempt", i+1), zap.Error(err))
+
+			timer := time.NewTimer(settle.Interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return wrap(err)
+			case <-timer.C:
+			}
+
+			err = set()
+		}
+
+		return wrap(err)
+	}
+}
+
+// waitForPower polls dev every settle.Interval until it reports that it is powered on,
+// or until settle.Timeout has passed. Polls wait for driver's limits on device.
+// If ctx has a deadline, the wait ends early enough to leave time for the command and its retries.
+func waitForPower(ctx context.Context, driver avcontrol.Driver, device avcontrol.DeviceConfig, dev avcontrol.DeviceWithPower, settle avcontrol.PowerSettle) error {
+	start := time.Now()
+
+	timeout := settle.Timeout
+	if deadline, ok := ctx.Deadline(); ok {
+		reserve := settle.Interval * time.Duration(settle.Retries+1)
+		if left := time.Until(deadline) - reserve; left < timeout {
+			timeout = left
+		}
+	}
+
+	if timeout <= 0 {
+		return errors.New("there was no time left to wait for the device to be ready")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(settle.Interval)
+	defer ticker.Stop()
+
+	for {
+		var poweredOn bool
+		err := limit(ctx, driver, device, func() (err error) {
+			poweredOn, err = dev.Power(ctx)
+			return err
+		})
+		if err == nil && poweredOn {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			waited := time.Since(start).Round(time.Millisecond)
+			if err != nil {
+				return fmt.Errorf("device was not ready after %s: %w", waited, err)
+			}
+
+			return fmt.Errorf("device was not ready after %s", waited)
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/byuoitav/av-control-api/drivers"
+	"github.com/byuoitav/av-control-api/mock"
+	"github.com/matryer/is"
+	"go.uber.org/zap"
+)
+
+// warmingProjector reports that it is off, and rejects input commands,
+// until warmup has passed since it was powered on. It never accepts the input "bogus".
+type warmingProjector struct {
+	mock.WithBlank
+
+	warmup time.Duration
+
+	mu        sync.Mutex
+	poweredOn time.Time
+	input     string
+	sets      int
+}
+
+func (p *warmingProjector) ready() bool {
+	return !p.poweredOn.IsZero() && time.Since(p.poweredOn) >= p.warmup
+}
+
+func (p *warmingProjector) Power(ctx context.Context) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.ready(), nil
+}
+
+func (p *warmingProjector) SetPower(ctx context.Context, poweredOn bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.poweredOn = time.Time{}
+	if poweredOn {
+		p.poweredOn = time.Now()
+	}
+
+	return nil
+}
+
+func (p *warmingProjector) AudioVideoInputs(ctx context.Context) (map[string]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return map[string]string{"": p.input}, nil
+}
+
+func (p *warmingProjector) SetAudioVideoInput(ctx context.Context, output, input string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.sets++
+	if !p.ready() {
+		return errors.New("warming up")
+	}
+
+	if input == "bogus" {
+		return errors.New("invalid input")
+	}
+
+	p.input = input
+	return nil
+}
+
+type settleDriver struct {
+	dev    avcontrol.Device
+	settle avcontrol.PowerSettle
+}
+
+func (d *settleDriver) ParseConfig(config map[string]interface{}) error {
+	return nil
+}
+
+func (d *settleDriver) CreateDevice(ctx context.Context, addr string) (avcontrol.Device, error) {
+	return d.dev, nil
+}
+
+func (d *settleDriver) PowerSettle() avcontrol.PowerSettle {
+	return d.settle
+}
+
+func TestSetPowerSettle(t *testing.T) {
+	tests := []struct {
+		name     string
+		warmup   time.Duration
+		settle   avcontrol.PowerSettle
+		deadline time.Duration
+		input    string
+		errors   int
+		errMsg   string
+		sets     int
+	}{
+		{
+			name:   "WaitsForPower",
+			warmup: 20 * time.Millisecond,
+			settle: avcontrol.PowerSettle{
+				Timeout:  time.Second,
+				Interval: 5 * time.Millisecond,
+			},
+		},
+		{
+			name:   "NoSettle",
+			warmup: time.Second,
+			errors: 1,
+			errMsg: "warming up",
+		},
+		{
+			name:   "RetriesAfterTimeout",
+			warmup: 50 * time.Millisecond,
+			settle: avcontrol.PowerSettle{
+				Timeout:  20 * time.Millisecond,
+				Interval: 10 * time.Millisecond,
+				Retries:  10,
+			},
+		},
+		{
+			name:   "NeverReady",
+			warmup: time.Hour,
+			settle: avcontrol.PowerSettle{
+				Timeout:  20 * time.Millisecond,
+				Interval: 10 * time.Millisecond,
+				Retries:  1,
+			},
+			errors: 1,
+			errMsg: "warming up (device was not ready after 2",
+		},
+		{
+			name:   "NoRetriesOnceReady",
+			warmup: 20 * time.Millisecond,
+			settle: avcontrol.PowerSettle{
+				Timeout:  time.Second,
+				Interval: 5 * time.Millisecond,
+				Retries:  3,
+			},
+			input:  "bogus",
+			errors: 1,
+			errMsg: "invalid input",
+			sets:   1,
+		},
+		{
+			name:   "BoundedByDeadline",
+			warmup: time.Hour,
+			settle: avcontrol.PowerSettle{
+				Timeout:  time.Hour,
+				Interval: 10 * time.Millisecond,
+				Retries:  1,
+			},
+			deadline: 200 * time.Millisecond,
+			errors:   1,
+			errMsg:   "warming up (device was not ready after 1",
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			registry, err := drivers.NewWithConfig(nil)
+			is.NoErr(err)
+			dev := &warmingProjector{warmup: tt.warmup}
+			is.NoErr(registry.Register("driverstest/driver", &settleDriver{
+				dev:    dev,
+				settle: tt.settle,
+			}))
+
+			input := tt.input
+			if input == "" {
+				input = "hdmi2"
+			}
+
+			gs := &GetSetter{
+				Logger:         zap.NewNop(),
+				DriverRegistry: registry,
+			}
+
+			room := avcontrol.RoomConfig{
+				Devices: map[avcontrol.DeviceID]avcontrol.DeviceConfig{
+					"ITB-1101-D1": {
+						Address: "ITB-1101-D1",
+						Driver:  "driverstest/driver",
+					},
+				},
+			}
+
+			ctx := ctx
+			if tt.deadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.deadline)
+				defer cancel()
+			}
+
+			start := time.Now()
+			resp, err := gs.Set(ctx, room, avcontrol.StateRequest{
+				Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+					"ITB-1101-D1": {
+						PoweredOn: boolP(true),
+						Inputs: map[string]avcontrol.Input{
+							"": {
+								AudioVideo: stringP(input),
+							},
+						},
+					},
+				},
+			})
+			is.NoErr(err)
+			is.Equal(len(resp.Errors), tt.errors)
+
+			if tt.deadline > 0 {
+				is.True(time.Since(start) < tt.deadline) // the wait ended in time to retry the command
+			}
+
+			if tt.errors > 0 {
+				is.True(strings.Contains(resp.Errors[0].Error, tt.errMsg))
+			}
+
+			if tt.sets > 0 {
+				dev.mu.Lock()
+				is.Equal(dev.sets, tt.sets)
+				dev.mu.Unlock()
+			}
+		})
+	}
+}
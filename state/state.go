@@ -1,6 +1,9 @@
 package state
 
 import (
+	"sync"
+	"time"
+
 	avcontrol "github.com/byuoitav/av-control-api"
 	"go.uber.org/zap"
 )
@@ -9,4 +12,32 @@ import (
 type GetSetter struct {
 	Logger         *zap.Logger
 	DriverRegistry avcontrol.DriverRegistry
+
+	// Cache, if set, stores the last known value of each field on every device.
+	// Get serves fields from the cache when they are newer than the max age on
+	// the request's context (see avcontrol.WithMaxAge).
+	Cache *Cache
+
+	// Events, if set, is sent an event for each field that Set changes on a device. Changes are found by comparing
+	// each field to its last known value from Get or Set.
+	Events avcontrol.EventSink
+
+	// StrictDrivers makes Get, Set, GetHealth, GetInfo, GetCapabilities and Stream return ErrDriverNotRegistered for the whole
+	// room if the driver for any of its devices isn't registered. Otherwise, only the devices without a driver report the error.
+	StrictDrivers bool
+
+	// PollInterval is how often the state of a room is checked for changes
+	// while it is being streamed. Defaults to 5 seconds.
+	PollInterval time.Duration
+
+	streams   map[string]*roomStream
+	streamsMu sync.Mutex
+
+	// adjusting has a lock for each field of each device that has been adjusted (see avcontrol.DeviceAdjustment)
+	adjusting   map[adjustKey]chan struct{}
+	adjustingMu sync.Mutex
+
+	// known is the last known state of each device, used to find the changes sent to Events
+	known   map[avcontrol.DeviceID]avcontrol.DeviceState
+	knownMu sync.Mutex
 }
@@ -0,0 +1,353 @@
+package state
+
+import (
+	"context"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"go.uber.org/zap"
+)
+
+const (
+	_defaultPollInterval = 5 * time.Second
+	_pollTimeout         = 20 * time.Second
+
+	// _streamBuffer is how many changes a subscriber can fall behind before it is dropped.
+	_streamBuffer = 16
+)
+
+type roomStream struct {
+	room   avcontrol.RoomConfig
+	cancel context.CancelFunc
+
+	// subs maps each subscriber to the volume scale it wants volumes on
+	subs map[chan avcontrol.StateResponse]string
+
+	// state is the last known state of each device in the room
+	state map[avcontrol.DeviceID]avcontrol.DeviceState
+
+	// updated is the last time each device's state was updated
+	updated map[avcontrol.DeviceID]time.Time
+}
+
+// Stream returns a channel that receives the fields that have changed on the devices in the room.
+// The first response on the channel is the full current state of the room. Changes made through Set
+// are sent as soon as they succeed, and the room is polled every PollInterval to catch changes made
+// outside of the API. The channel is closed once ctx is done, or if the receiver falls too far behind.
+//
+// Volumes are sent on the scale set in ctx (see avcontrol.WithVolumeScale). Volumes that can't be converted
+// to it are left out, and an error is sent for each of them instead.
+func (gs *GetSetter) Stream(ctx context.Context, room avcontrol.RoomConfig) (<-chan avcontrol.StateResponse, error) {
+	if len(room.Devices) == 0 {
+		return nil, ErrNoStateGettable
+	}
+
+	if err := gs.checkDrivers(room); err != nil {
+		return nil, err
+	}
+
+	gs.streamsMu.Lock()
+	defer gs.streamsMu.Unlock()
+
+	if gs.streams == nil {
+		gs.streams = make(map[string]*roomStream)
+	}
+
+	stream, ok := gs.streams[room.ID]
+	if !ok {
+		pollCtx, cancel := context.WithCancel(context.Background())
+		stream = &roomStream{
+			subs:    make(map[chan avcontrol.StateResponse]string),
+			cancel:  cancel,
+			state:   make(map[avcontrol.DeviceID]avcontrol.DeviceState),
+			updated: make(map[avcontrol.DeviceID]time.Time),
+		}
+
+		gs.streams[room.ID] = stream
+		go gs.poll(pollCtx, room.ID, stream)
+	}
+
+	// always poll using the newest room config
+	stream.room = room
+
+	scale := avcontrol.CtxVolumeScale(ctx)
+	ch := make(chan avcontrol.StateResponse, _streamBuffer)
+	if len(stream.state) > 0 {
+		snapshot := avcontrol.StateResponse{
+			Devices: make(map[avcontrol.DeviceID]avcontrol.DeviceState, len(stream.state)),
+		}
+
+		for id, state := range stream.state {
+			snapshot.Devices[id], _ = mergeState(avcontrol.DeviceState{}, state)
+		}
+
+		ch <- scaleResponse(room, snapshot, scale)
+	}
+
+	stream.subs[ch] = scale
+
+	go func() {
+		<-ctx.Done()
+		gs.unsubscribe(room.ID, ch)
+	}()
+
+	return ch, nil
+}
+
+func (gs *GetSetter) unsubscribe(roomID string, ch chan avcontrol.StateResponse) {
+	gs.streamsMu.Lock()
+	defer gs.streamsMu.Unlock()
+
+	stream, ok := gs.streams[roomID]
+	if !ok {
+		return
+	}
+
+	if _, ok := stream.subs[ch]; ok {
+		delete(stream.subs, ch)
+		close(ch)
+	}
+
+	if len(stream.subs) == 0 {
+		stream.cancel()
+		delete(gs.streams, roomID)
+	}
+}
+
+// poll gets the state of the room every PollInterval until ctx is done, publishing any changes it finds.
+func (gs *GetSetter) poll(ctx context.Context, roomID string, stream *roomStream) {
+	interval := gs.PollInterval
+	if interval <= 0 {
+		interval = _defaultPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log := gs.Logger.With(zap.String("room", roomID))
+	log.Info("Starting to poll room state", zap.Duration("interval", interval))
+
+	for {
+		gs.streamsMu.Lock()
+		room := stream.room
+		gs.streamsMu.Unlock()
+
+		start := time.Now()
+		getCtx, cancel := context.WithTimeout(ctx, _pollTimeout)
+
+		resp, err := gs.Get(getCtx, room)
+		cancel()
+
+		switch {
+		case ctx.Err() != nil:
+		case err != nil:
+			log.Warn("unable to poll room state", zap.Error(err))
+		default:
+			gs.publish(roomID, resp.Devices, start)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Info("Stopped polling room state")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publish sends the fields in devices that are different from the last known state of the room
+// to every subscriber of the room. asOf is when the state in devices was known to be true; devices
+// whose state has been updated since then are skipped.
+func (gs *GetSetter) publish(roomID string, devices map[avcontrol.DeviceID]avcontrol.DeviceState, asOf time.Time) {
+	gs.streamsMu.Lock()
+	defer gs.streamsMu.Unlock()
+
+	stream, ok := gs.streams[roomID]
+	if !ok {
+		return
+	}
+
+	changes := avcontrol.StateResponse{
+		Devices: make(map[avcontrol.DeviceID]avcontrol.DeviceState),
+	}
+
+	for id, state := range devices {
+		if stream.updated[id].After(asOf) {
+			continue
+		}
+
+		merged, changed := mergeState(stream.state[id], state)
+		stream.state[id] = merged
+		stream.updated[id] = asOf
+
+		if changed != nil {
+			changes.Devices[id] = *changed
+		}
+	}
+
+	if len(changes.Devices) == 0 {
+		return
+	}
+
+	for ch, scale := range stream.subs {
+		select {
+		case ch <- scaleResponse(stream.room, changes, scale):
+		default:
+			// drop subscribers that can't keep up; they can reconnect to get the current state
+			gs.Logger.Warn("dropping slow state subscriber", zap.String("room", roomID))
+			delete(stream.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// scaleResponse returns the devices in resp with their native volumes converted to scale. Volumes that
+// can't be converted are left out, and the errors in the response are the reasons why.
+func scaleResponse(room avcontrol.RoomConfig, resp avcontrol.StateResponse, scale string) avcontrol.StateResponse {
+	devices, errs := scaleVolumes(room, resp.Devices, scale)
+	return avcontrol.StateResponse{
+		Devices: devices,
+		Errors:  errs,
+	}
+}
+
+// mergeState returns a copy of dst with every field set in src copied on top of it.
+// If any of those fields changed the value in dst, the changed fields are also returned.
+func mergeState(dst, src avcontrol.DeviceState) (avcontrol.DeviceState, *avcontrol.DeviceState) {
+	var merged, changed avcontrol.DeviceState
+	var didChange bool
+
+	copyBool := func(b *bool) *bool {
+		if b == nil {
+			return nil
+		}
+
+		v := *b
+		return &v
+	}
+
+	copyString := func(s *string) *string {
+		if s == nil {
+			return nil
+		}
+
+		v := *s
+		return &v
+	}
+
+	merged.PoweredOn = copyBool(dst.PoweredOn)
+	if src.PoweredOn != nil {
+		if dst.PoweredOn == nil || *dst.PoweredOn != *src.PoweredOn {
+			changed.PoweredOn = copyBool(src.PoweredOn)
+			didChange = true
+		}
+
+		merged.PoweredOn = copyBool(src.PoweredOn)
+	}
+
+	merged.Blanked = copyBool(dst.Blanked)
+	if src.Blanked != nil {
+		if dst.Blanked == nil || *dst.Blanked != *src.Blanked {
+			changed.Blanked = copyBool(src.Blanked)
+			didChange = true
+		}
+
+		merged.Blanked = copyBool(src.Blanked)
+	}
+
+	if len(dst.Inputs) > 0 || len(src.Inputs) > 0 {
+		merged.Inputs = make(map[string]avcontrol.Input)
+	}
+
+	for out, in := range dst.Inputs {
+		merged.Inputs[out] = avcontrol.Input{
+			AudioVideo: copyString(in.AudioVideo),
+			Audio:      copyString(in.Audio),
+			Video:      copyString(in.Video),
+		}
+	}
+
+	for out, in := range src.Inputs {
+		prev := merged.Inputs[out]
+		next := prev
+
+		var diff avcontrol.Input
+		var inputChanged bool
+
+		mergeInput := func(prev, src *string, next, diff **string) {
+			if src == nil {
+				return
+			}
+
+			if prev == nil || *prev != *src {
+				*diff = copyString(src)
+				inputChanged = true
+			}
+
+			*next = copyString(src)
+		}
+
+		mergeInput(prev.AudioVideo, in.AudioVideo, &next.AudioVideo, &diff.AudioVideo)
+		mergeInput(prev.Audio, in.Audio, &next.Audio, &diff.Audio)
+		mergeInput(prev.Video, in.Video, &next.Video, &diff.Video)
+
+		merged.Inputs[out] = next
+
+		if inputChanged {
+			if changed.Inputs == nil {
+				changed.Inputs = make(map[string]avcontrol.Input)
+			}
+
+			changed.Inputs[out] = diff
+			didChange = true
+		}
+	}
+
+	if len(dst.Volumes) > 0 || len(src.Volumes) > 0 {
+		merged.Volumes = make(map[string]int)
+	}
+
+	for block, vol := range dst.Volumes {
+		merged.Volumes[block] = vol
+	}
+
+	for block, vol := range src.Volumes {
+		if prev, ok := merged.Volumes[block]; !ok || prev != vol {
+			if changed.Volumes == nil {
+				changed.Volumes = make(map[string]int)
+			}
+
+			changed.Volumes[block] = vol
+			didChange = true
+		}
+
+		merged.Volumes[block] = vol
+	}
+
+	if len(dst.Mutes) > 0 || len(src.Mutes) > 0 {
+		merged.Mutes = make(map[string]bool)
+	}
+
+	for block, muted := range dst.Mutes {
+		merged.Mutes[block] = muted
+	}
+
+	for block, muted := range src.Mutes {
+		if prev, ok := merged.Mutes[block]; !ok || prev != muted {
+			if changed.Mutes == nil {
+				changed.Mutes = make(map[string]bool)
+			}
+
+			changed.Mutes[block] = muted
+			didChange = true
+		}
+
+		merged.Mutes[block] = muted
+	}
+
+	if !didChange {
+		return merged, nil
+	}
+
+	return merged, &changed
+}
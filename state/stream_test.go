@@ -0,0 +1,360 @@
+package state
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/byuoitav/av-control-api/drivers"
+	"github.com/byuoitav/av-control-api/drivers/driverstest"
+	"github.com/byuoitav/av-control-api/mock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/matryer/is"
+	"go.uber.org/zap"
+)
+
+var mergeStateTests = []struct {
+	name    string
+	dst     avcontrol.DeviceState
+	src     avcontrol.DeviceState
+	merged  avcontrol.DeviceState
+	changed *avcontrol.DeviceState
+}{
+	{
+		name: "Empty",
+	},
+	{
+		name: "NoChange",
+		dst: avcontrol.DeviceState{
+			PoweredOn: boolP(true),
+			Volumes: map[string]int{
+				"": 30,
+			},
+		},
+		src: avcontrol.DeviceState{
+			PoweredOn: boolP(true),
+		},
+		merged: avcontrol.DeviceState{
+			PoweredOn: boolP(true),
+			Volumes: map[string]int{
+				"": 30,
+			},
+		},
+	},
+	{
+		name: "Power",
+		dst: avcontrol.DeviceState{
+			PoweredOn: boolP(true),
+			Blanked:   boolP(false),
+		},
+		src: avcontrol.DeviceState{
+			PoweredOn: boolP(false),
+		},
+		merged: avcontrol.DeviceState{
+			PoweredOn: boolP(false),
+			Blanked:   boolP(false),
+		},
+		changed: &avcontrol.DeviceState{
+			PoweredOn: boolP(false),
+		},
+	},
+	{
+		name: "Inputs",
+		dst: avcontrol.DeviceState{
+			Inputs: map[string]avcontrol.Input{
+				"1": {
+					Audio: stringP("2"),
+					Video: stringP("2"),
+				},
+				"2": {
+					AudioVideo: stringP("1"),
+				},
+			},
+		},
+		src: avcontrol.DeviceState{
+			Inputs: map[string]avcontrol.Input{
+				"1": {
+					Audio: stringP("2"),
+					Video: stringP("3"),
+				},
+				"3": {
+					AudioVideo: stringP("4"),
+				},
+			},
+		},
+		merged: avcontrol.DeviceState{
+			Inputs: map[string]avcontrol.Input{
+				"1": {
+					Audio: stringP("2"),
+					Video: stringP("3"),
+				},
+				"2": {
+					AudioVideo: stringP("1"),
+				},
+				"3": {
+					AudioVideo: stringP("4"),
+				},
+			},
+		},
+		changed: &avcontrol.DeviceState{
+			Inputs: map[string]avcontrol.Input{
+				"1": {
+					Video: stringP("3"),
+				},
+				"3": {
+					AudioVideo: stringP("4"),
+				},
+			},
+		},
+	},
+	{
+		name: "VolumesAndMutes",
+		dst: avcontrol.DeviceState{
+			Volumes: map[string]int{
+				"mic1": 30,
+				"mic2": 40,
+			},
+			Mutes: map[string]bool{
+				"mic1": false,
+			},
+		},
+		src: avcontrol.DeviceState{
+			Volumes: map[string]int{
+				"mic1": 30,
+				"mic2": 45,
+			},
+			Mutes: map[string]bool{
+				"mic1": false,
+				"mic2": true,
+			},
+		},
+		merged: avcontrol.DeviceState{
+			Volumes: map[string]int{
+				"mic1": 30,
+				"mic2": 45,
+			},
+			Mutes: map[string]bool{
+				"mic1": false,
+				"mic2": true,
+			},
+		},
+		changed: &avcontrol.DeviceState{
+			Volumes: map[string]int{
+				"mic2": 45,
+			},
+			Mutes: map[string]bool{
+				"mic2": true,
+			},
+		},
+	},
+}
+
+func TestMergeState(t *testing.T) {
+	for _, tt := range mergeStateTests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, changed := mergeState(tt.dst, tt.src)
+			if diff := cmp.Diff(tt.merged, merged); diff != "" {
+				t.Fatalf("got incorrect merged state (-want, +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(tt.changed, changed); diff != "" {
+				t.Fatalf("got incorrect changes (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestStream(t *testing.T) {
+	is := is.New(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	driver := &driverstest.Driver{
+		Devices: map[string]avcontrol.Device{
+			"ITB-1101-D1": mock.Projector{
+				WithPower: mock.WithPower{
+					PoweredOn: false,
+				},
+				WithAudioVideoInput: mock.WithAudioVideoInput{
+					Inputs: map[string]string{
+						"": "hdmi1",
+					},
+				},
+			},
+		},
+	}
+
+	registry, err := drivers.NewWithConfig(nil)
+	is.NoErr(err)
+	is.NoErr(registry.Register("driverstest/driver", driver))
+
+	gs := &GetSetter{
+		Logger:         zap.NewNop(),
+		DriverRegistry: registry,
+		PollInterval:   time.Hour,
+	}
+
+	room := avcontrol.RoomConfig{
+		ID: "ITB-1101",
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceConfig{
+			"ITB-1101-D1": {
+				Address: "ITB-1101-D1",
+				Driver:  "driverstest/driver",
+			},
+		},
+	}
+
+	streamCtx, streamCancel := context.WithCancel(ctx)
+	changes, err := gs.Stream(streamCtx, room)
+	is.NoErr(err)
+
+	// the first poll should send the whole state of the room
+	resp := <-changes
+	is.Equal(resp, avcontrol.StateResponse{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+			"ITB-1101-D1": {
+				PoweredOn: boolP(false),
+				Blanked:   boolP(false),
+				Inputs: map[string]avcontrol.Input{
+					"": {
+						AudioVideo: stringP("hdmi1"),
+					},
+				},
+			},
+		},
+	})
+
+	// a set should only send the fields that changed
+	_, err = gs.Set(ctx, room, avcontrol.StateRequest{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+			"ITB-1101-D1": {
+				PoweredOn: boolP(true),
+				Inputs: map[string]avcontrol.Input{
+					"": {
+						AudioVideo: stringP("hdmi1"),
+					},
+				},
+			},
+		},
+	})
+	is.NoErr(err)
+
+	resp = <-changes
+	is.Equal(resp, avcontrol.StateResponse{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+			"ITB-1101-D1": {
+				PoweredOn: boolP(true),
+			},
+		},
+	})
+
+	// new subscribers should get the last known state right away
+	second, err := gs.Stream(streamCtx, room)
+	is.NoErr(err)
+
+	resp = <-second
+	is.Equal(resp, avcontrol.StateResponse{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+			"ITB-1101-D1": {
+				PoweredOn: boolP(true),
+				Blanked:   boolP(false),
+				Inputs: map[string]avcontrol.Input{
+					"": {
+						AudioVideo: stringP("hdmi1"),
+					},
+				},
+			},
+		},
+	})
+
+	// both channels should be closed once the context is done
+	streamCancel()
+
+	_, ok := <-changes
+	is.True(!ok)
+
+	_, ok = <-second
+	is.True(!ok)
+}
+
+func TestStreamEmptyRoom(t *testing.T) {
+	is := is.New(t)
+
+	gs := &GetSetter{
+		Logger: zap.NewNop(),
+	}
+
+	_, err := gs.Stream(context.Background(), avcontrol.RoomConfig{})
+	is.Equal(err, ErrNoStateGettable)
+}
+
+func TestStreamVolumeScale(t *testing.T) {
+	is := is.New(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	dsp := mock.DSP{
+		WithVolume: mock.WithVolume{
+			Vols: map[string]int{
+				"Mic1Gain": -40,
+			},
+		},
+	}
+
+	registry, err := drivers.NewWithConfig(nil)
+	is.NoErr(err)
+	is.NoErr(registry.Register("driverstest/driver", &driverstest.Driver{
+		Devices: map[string]avcontrol.Device{
+			"ITB-1101-DSP1": dsp,
+		},
+	}))
+
+	gs := &GetSetter{
+		Logger:         zap.NewNop(),
+		DriverRegistry: registry,
+		PollInterval:   time.Hour,
+	}
+
+	room := avcontrol.RoomConfig{
+		ID: "ITB-1101",
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceConfig{
+			"ITB-1101-DSP1": {
+				Address: "ITB-1101-DSP1",
+				Driver:  "driverstest/driver",
+				Ports:   avcontrol.PortConfigs{qscGain},
+			},
+		},
+	}
+
+	percent, err := gs.Stream(avcontrol.WithVolumeScale(ctx, avcontrol.VolumeScalePercent), room)
+	is.NoErr(err)
+
+	resp := <-percent
+	is.Equal(resp.Devices["ITB-1101-DSP1"].Volumes, map[string]int{"Mic1Gain": 50})
+
+	// each subscriber gets volumes on its own scale
+	native, err := gs.Stream(ctx, room)
+	is.NoErr(err)
+
+	resp = <-native
+	is.Equal(resp.Devices["ITB-1101-DSP1"].Volumes, map[string]int{"Mic1Gain": -40})
+
+	// including changes published by a set
+	_, err = gs.Set(ctx, room, avcontrol.StateRequest{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+			"ITB-1101-DSP1": {
+				Volumes: map[string]int{"Mic1Gain": -10},
+			},
+		},
+	})
+	is.NoErr(err)
+
+	resp = <-percent
+	is.Equal(resp.Devices["ITB-1101-DSP1"].Volumes, map[string]int{"Mic1Gain": 75})
+
+	resp = <-native
+	is.Equal(resp.Devices["ITB-1101-DSP1"].Volumes, map[string]int{"Mic1Gain": -10})
+}
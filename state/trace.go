@@ -0,0 +1,52 @@
+package state
+
+import (
+	"context"
+	"time"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/byuoitav/av-control-api/state")
+
+func deviceAttributes(id avcontrol.DeviceID, device avcontrol.DeviceConfig) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("avcontrol.room", id.Room()),
+		attribute.String("avcontrol.device.id", string(id)),
+		attribute.String("avcontrol.device.driver", device.Driver),
+	}
+}
+
+// startDevice starts the span that covers everything done to a single device while handling a request.
+func startDevice(ctx context.Context, name string, id avcontrol.DeviceID, device avcontrol.DeviceConfig) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(deviceAttributes(id, device)...))
+}
+
+// startOp starts a span for a single call to a device. The returned function must be called with the
+// result of the call; it ends the span and records the call's metrics. field is the field of the device's
+// state that the call is for.
+func startOp(ctx context.Context, id avcontrol.DeviceID, device avcontrol.DeviceConfig, op, field string) (context.Context, func(error)) {
+	start := time.Now()
+
+	attrs := append(deviceAttributes(id, device), attribute.String("avcontrol.operation", op))
+	if field != "" {
+		attrs = append(attrs, attribute.String("avcontrol.field", field))
+	}
+
+	ctx, span := tracer.Start(ctx, op, trace.WithAttributes(attrs...))
+
+	return ctx, func(err error) {
+		observe(device.Driver, id, op, start, err)
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		span.End()
+	}
+}
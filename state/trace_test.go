@@ -0,0 +1,46 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/matryer/is"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestStartOp(t *testing.T) {
+	is := is.New(t)
+
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+
+	dev := avcontrol.DeviceConfig{Driver: "driverstest/driver"}
+
+	ctx, span := startDevice(context.Background(), "set device state", "ITB-1101-D9", dev)
+	_, done := startOp(ctx, "ITB-1101-D9", dev, "SetPower", "poweredOn")
+	done(errors.New("broken"))
+	span.End()
+
+	spans := rec.Ended()
+	is.Equal(len(spans), 2)
+
+	op := spans[0]
+	is.Equal(op.Name(), "SetPower")
+	is.Equal(op.Parent().SpanID(), spans[1].SpanContext().SpanID())
+	is.Equal(op.Status().Code, codes.Error)
+
+	attrs := make(map[attribute.Key]string)
+	for _, kv := range op.Attributes() {
+		attrs[kv.Key] = kv.Value.AsString()
+	}
+
+	is.Equal(attrs["avcontrol.room"], "ITB-1101")
+	is.Equal(attrs["avcontrol.device.driver"], "driverstest/driver")
+	is.Equal(attrs["avcontrol.field"], "poweredOn")
+}
@@ -18,11 +18,16 @@ func containsString(slice []string, s string) bool {
 }
 
 // sortErrors sorts a slice of api.DeviceStateError. Sorting is done in this order:
-//   1. ID
-//   2. Field
-//   3. Error
+//   1. Phase
+//   2. ID
+//   3. Field
+//   4. Error
 func sortErrors(errors []avcontrol.DeviceStateError) {
 	sort.Slice(errors, func(i, j int) bool {
+		if errors[i].Phase != errors[j].Phase {
+			return errors[i].Phase < errors[j].Phase
+		}
+
 		if errors[i].ID != errors[j].ID {
 			return errors[i].ID < errors[j].ID
 		}
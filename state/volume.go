@@ -0,0 +1,142 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+)
+
+var (
+	ErrOutOfRange   = errors.New("out of range")
+	ErrInvalidScale = errors.New("invalid volume scale")
+)
+
+// toNative converts vol on scale to the native value of port. The result is rounded to the port's step and kept within
+// its range; if vol is outside of the range, the closest value in it is returned along with an ErrOutOfRange error.
+// Ports without a range get native and dB volumes as they are.
+func toNative(port avcontrol.PortConfig, scale string, vol int) (int, error) {
+	if err := checkScale(port, scale); err != nil {
+		return 0, err
+	}
+
+	if !port.HasRange() {
+		return vol, nil
+	}
+
+	lo, hi := scaleRange(port, scale)
+	native := float64(port.Min) + (float64(vol)-lo)*float64(port.Max-port.Min)/(hi-lo)
+
+	step := port.Step
+	if step <= 0 {
+		step = 1
+	}
+
+	n := port.Min + int(math.Round((native-float64(port.Min))/float64(step)))*step
+	switch {
+	case n < port.Min:
+		n = port.Min
+	case n > port.Max:
+		n = port.Max
+	}
+
+	if native < float64(port.Min) || native > float64(port.Max) {
+		lo, _ := fromNative(port, scale, port.Min)
+		hi, _ := fromNative(port, scale, port.Max)
+		set, _ := fromNative(port, scale, n)
+		return n, fmt.Errorf("%w: %d is not between %d and %d, so it was set to %d", ErrOutOfRange, vol, lo, hi, set)
+	}
+
+	return n, nil
+}
+
+// fromNative converts the native value of port to scale.
+func fromNative(port avcontrol.PortConfig, scale string, native int) (int, error) {
+	if err := checkScale(port, scale); err != nil {
+		return 0, err
+	}
+
+	if !port.HasRange() {
+		return native, nil
+	}
+
+	lo, hi := scaleRange(port, scale)
+	return int(math.Round(lo + float64(native-port.Min)*(hi-lo)/float64(port.Max-port.Min))), nil
+}
+
+// scaleRange returns the values on scale at port's Min and Max. port must have a range.
+func scaleRange(port avcontrol.PortConfig, scale string) (float64, float64) {
+	switch {
+	case scale == avcontrol.VolumeScalePercent:
+		return 0, 100
+	case scale == avcontrol.VolumeScaleDB && port.Unit != avcontrol.VolumeScaleDB:
+		return float64(port.MinDB), float64(port.MaxDB)
+	default:
+		return float64(port.Min), float64(port.Max)
+	}
+}
+
+// checkScale returns an error if volumes on port can't be converted to or from scale.
+func checkScale(port avcontrol.PortConfig, scale string) error {
+	switch scale {
+	case avcontrol.VolumeScaleNative:
+		return nil
+	case avcontrol.VolumeScalePercent:
+		if !port.HasRange() {
+			return fmt.Errorf("%w: %q doesn't have a range, so it can't be converted to a percent", ErrInvalidScale, port.Name)
+		}
+
+		return nil
+	case avcontrol.VolumeScaleDB:
+		if port.Unit != avcontrol.VolumeScaleDB && !port.HasGain() {
+			return fmt.Errorf("%w: %q isn't in dB and doesn't have a gain range", ErrInvalidScale, port.Name)
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown scale %q", ErrInvalidScale, scale)
+	}
+}
+
+// scaleVolumes returns a copy of devices with their native volumes converted to scale. Volumes that can't be
+// converted are left out, and an error is returned for each of them.
+func scaleVolumes(room avcontrol.RoomConfig, devices map[avcontrol.DeviceID]avcontrol.DeviceState, scale string) (map[avcontrol.DeviceID]avcontrol.DeviceState, []avcontrol.DeviceStateError) {
+	if scale == avcontrol.VolumeScaleNative {
+		return devices, nil
+	}
+
+	var errs []avcontrol.DeviceStateError
+	scaled := make(map[avcontrol.DeviceID]avcontrol.DeviceState, len(devices))
+
+	for id, state := range devices {
+		if len(state.Volumes) == 0 {
+			scaled[id] = state
+			continue
+		}
+
+		vols := make(map[string]int, len(state.Volumes))
+		for block, native := range state.Volumes {
+			port, _ := room.Devices[id].Ports.Find("volume", block)
+			port.Name = block
+
+			vol, err := fromNative(port, scale, native)
+			if err != nil {
+				errs = append(errs, avcontrol.DeviceStateError{
+					ID:    id,
+					Field: fmt.Sprintf("volumes.%s", block),
+					Error: err.Error(),
+					Code:  deviceErrorCode(err),
+				})
+				continue
+			}
+
+			vols[block] = vol
+		}
+
+		state.Volumes = vols
+		scaled[id] = state
+	}
+
+	return scaled, errs
+}
@@ -0,0 +1,155 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/byuoitav/av-control-api/drivers"
+	"github.com/byuoitav/av-control-api/drivers/driverstest"
+	"github.com/byuoitav/av-control-api/mock"
+	"github.com/matryer/is"
+	"go.uber.org/zap"
+)
+
+var (
+	qscGain = avcontrol.PortConfig{Name: "Mic1Gain", Type: "volume", Min: -100, Max: 20, Unit: "dB"}
+	tvVol   = avcontrol.PortConfig{Name: "", Type: "volume", Min: 0, Max: 100, Step: 5}
+	rawVol  = avcontrol.PortConfig{Name: "raw", Type: "volume"}
+	rawGain = avcontrol.PortConfig{Name: "Mic2Gain", Type: "volume", Min: 0, Max: 1200, MinDB: -100, MaxDB: 20}
+)
+
+func TestToNative(t *testing.T) {
+	tests := []struct {
+		name   string
+		port   avcontrol.PortConfig
+		scale  string
+		vol    int
+		native int
+		err    error
+	}{
+		{name: "Native", port: rawVol, scale: avcontrol.VolumeScaleNative, vol: 1234, native: 1234},
+		{name: "NativeClamped", port: tvVol, scale: avcontrol.VolumeScaleNative, vol: 120, native: 100, err: ErrOutOfRange},
+		{name: "Percent", port: qscGain, scale: avcontrol.VolumeScalePercent, vol: 50, native: -40},
+		{name: "PercentStep", port: tvVol, scale: avcontrol.VolumeScalePercent, vol: 33, native: 35},
+		{name: "PercentClamped", port: qscGain, scale: avcontrol.VolumeScalePercent, vol: -5, native: -100, err: ErrOutOfRange},
+		{name: "PercentNoRange", port: rawVol, scale: avcontrol.VolumeScalePercent, vol: 50, err: ErrInvalidScale},
+		{name: "DB", port: qscGain, scale: avcontrol.VolumeScaleDB, vol: -12, native: -12},
+		{name: "DBClamped", port: qscGain, scale: avcontrol.VolumeScaleDB, vol: 30, native: 20, err: ErrOutOfRange},
+		{name: "DBGain", port: rawGain, scale: avcontrol.VolumeScaleDB, vol: -12, native: 880},
+		{name: "DBGainClamped", port: rawGain, scale: avcontrol.VolumeScaleDB, vol: 30, native: 1200, err: ErrOutOfRange},
+		{name: "DBNotDB", port: tvVol, scale: avcontrol.VolumeScaleDB, vol: -12, err: ErrInvalidScale},
+		{name: "DBNoRange", port: avcontrol.PortConfig{Name: "raw", Type: "volume", MaxDB: 20}, scale: avcontrol.VolumeScaleDB, vol: -12, err: ErrInvalidScale},
+		{name: "UnknownScale", port: tvVol, scale: "loudness", vol: 1, err: ErrInvalidScale},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			native, err := toNative(tt.port, tt.scale, tt.vol)
+			is.True(errors.Is(err, tt.err))
+
+			if tt.err == nil || errors.Is(tt.err, ErrOutOfRange) {
+				is.Equal(native, tt.native)
+			}
+		})
+	}
+}
+
+func TestFromNative(t *testing.T) {
+	tests := []struct {
+		name   string
+		port   avcontrol.PortConfig
+		scale  string
+		native int
+		vol    int
+	}{
+		{name: "Native", port: rawVol, scale: avcontrol.VolumeScaleNative, native: 1234, vol: 1234},
+		{name: "Percent", port: tvVol, scale: avcontrol.VolumeScalePercent, native: 35, vol: 35},
+		{name: "DB", port: qscGain, scale: avcontrol.VolumeScaleDB, native: -12, vol: -12},
+		{name: "DBGain", port: rawGain, scale: avcontrol.VolumeScaleDB, native: 880, vol: -12},
+		{name: "DBGainMin", port: rawGain, scale: avcontrol.VolumeScaleDB, native: 0, vol: -100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			vol, err := fromNative(tt.port, tt.scale, tt.native)
+			is.NoErr(err)
+			is.Equal(vol, tt.vol)
+		})
+	}
+}
+
+func TestSetVolumePercent(t *testing.T) {
+	is := is.New(t)
+
+	dsp := mock.DSP{
+		WithVolume: mock.WithVolume{
+			Vols: map[string]int{
+				"Mic1Gain": 0,
+			},
+		},
+	}
+
+	registry, err := drivers.NewWithConfig(nil)
+	is.NoErr(err)
+	is.NoErr(registry.Register("driverstest/driver", &driverstest.Driver{
+		Devices: map[string]avcontrol.Device{
+			"ITB-1101-DSP1": dsp,
+		},
+	}))
+
+	gs := &GetSetter{
+		Logger:         zap.NewNop(),
+		DriverRegistry: registry,
+	}
+
+	room := avcontrol.RoomConfig{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceConfig{
+			"ITB-1101-DSP1": {
+				Address: "ITB-1101-DSP1",
+				Driver:  "driverstest/driver",
+				Ports:   avcontrol.PortConfigs{qscGain},
+			},
+		},
+	}
+
+	ctx := avcontrol.WithVolumeScale(context.Background(), avcontrol.VolumeScalePercent)
+
+	resp, err := gs.Set(ctx, room, avcontrol.StateRequest{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+			"ITB-1101-DSP1": {
+				Volumes: map[string]int{"Mic1Gain": 75},
+			},
+		},
+	})
+	is.NoErr(err)
+	is.Equal(len(resp.Errors), 0)
+	is.Equal(dsp.Vols["Mic1Gain"], -10)
+	is.Equal(resp.Devices["ITB-1101-DSP1"].Volumes, map[string]int{"Mic1Gain": 75})
+
+	resp, err = gs.Set(ctx, room, avcontrol.StateRequest{
+		Devices: map[avcontrol.DeviceID]avcontrol.DeviceState{
+			"ITB-1101-DSP1": {
+				Volumes: map[string]int{"Mic1Gain": 150},
+			},
+		},
+	})
+	is.NoErr(err)
+	is.Equal(dsp.Vols["Mic1Gain"], 20)
+	is.Equal(len(resp.Errors), 1)
+	is.Equal(resp.Errors[0].Error, "out of range: 150 is not between 0 and 100, so it was set to 100")
+	is.Equal(resp.Errors[0].Value, 150) // the requested volume, not the one that was set
+
+	resp, err = gs.Get(avcontrol.WithVolumeScale(context.Background(), avcontrol.VolumeScaleDB), room)
+	is.NoErr(err)
+	is.Equal(resp.Devices["ITB-1101-DSP1"].Volumes, map[string]int{"Mic1Gain": 20})
+
+	resp, err = gs.Get(ctx, room)
+	is.NoErr(err)
+	is.Equal(resp.Devices["ITB-1101-DSP1"].Volumes, map[string]int{"Mic1Gain": 100})
+}
@@ -0,0 +1,188 @@
+// Package validate checks room configs for problems that would otherwise only show up when the room is used.
+package validate
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+)
+
+// PortTypes are the port types that the API knows how to use.
+var PortTypes = []string{"volume", "mute", "input", "output"}
+
+// PortKinds are the kinds of signal an input or output port can carry.
+var PortKinds = []string{avcontrol.PortKindAudio, avcontrol.PortKindVideo, avcontrol.PortKindAudioVideo}
+
+// Room is a room config to validate. It is the same as avcontrol.RoomConfig, except that
+// the proxy is kept as the string it was given so that it can be checked.
+type Room struct {
+	ID      string                                        `json:"id"`
+	Proxy   string                                        `json:"proxy"`
+	Devices map[avcontrol.DeviceID]avcontrol.DeviceConfig `json:"devices"`
+	Scenes  map[string]avcontrol.StateRequest             `json:"scenes"`
+
+	Connections []avcontrol.Connection `json:"connections"`
+}
+
+// Problem is something wrong with a room config.
+type Problem struct {
+	// Field is the path to the value that has the problem, like "devices.ITB-1101-D1.driver".
+	Field string `json:"field"`
+
+	// Message describes the problem.
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	return p.Field + ": " + p.Message
+}
+
+// Validate returns the problems found in room. Devices are checked in order of their IDs, so the same room
+// always gives the same problems in the same order. drivers is the list of registered drivers
+// (see avcontrol.DriverRegistry.List()) that devices in room can use.
+func Validate(room Room, drivers []string) []Problem {
+	var problems []Problem
+	add := func(field, format string, a ...interface{}) {
+		problems = append(problems, Problem{
+			Field:   field,
+			Message: fmt.Sprintf(format, a...),
+		})
+	}
+
+	split := strings.Split(room.ID, "-")
+	switch {
+	case room.ID == "":
+		add("id", "is required")
+	case len(split) != 2 || split[0] == "" || split[1] == "":
+		add("id", "%q is not in the format Building-Room", room.ID)
+	}
+
+	if room.Proxy != "" {
+		proxy, err := url.Parse(room.Proxy)
+		switch {
+		case err != nil:
+			add("proxy", "unable to parse url: %s", err)
+		case proxy.Host == "":
+			add("proxy", "%q has no host, so requests would never be proxied", room.Proxy)
+		}
+	}
+
+	registered := make(map[string]bool, len(drivers))
+	for _, name := range drivers {
+		registered[name] = true
+	}
+
+	ids := make([]avcontrol.DeviceID, 0, len(room.Devices))
+	for id := range room.Devices {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+
+	// addrs is the first device with each address
+	addrs := make(map[string]avcontrol.DeviceID)
+
+	for _, id := range ids {
+		dev := room.Devices[id]
+		field := "devices." + string(id)
+
+		split := strings.SplitN(string(id), "-", 3)
+		switch {
+		case len(split) != 3 || split[0] == "" || split[1] == "" || split[2] == "":
+			add(field, "%q is not in the format Building-Room-Name", id)
+		case room.ID != "" && id.Room() != room.ID:
+			add(field, "belongs to %s, not %s", id.Room(), room.ID)
+		}
+
+		switch {
+		case dev.Driver == "":
+			add(field+".driver", "is required")
+		case !registered[dev.Driver]:
+			add(field+".driver", "driver %q is not registered", dev.Driver)
+		}
+
+		switch first, ok := addrs[strings.ToLower(dev.Address)]; {
+		case dev.Address == "":
+			add(field+".address", "is required")
+		case ok:
+			add(field+".address", "%q is also used by %s", dev.Address, first)
+		default:
+			addrs[strings.ToLower(dev.Address)] = id
+		}
+
+		for i, port := range dev.Ports {
+			field := fmt.Sprintf("%s.ports.%d", field, i)
+
+			if port.Name == "" {
+				add(field+".name", "is required")
+			}
+
+			if !knownPortType(port) {
+				add(field+".type", "unknown port type %q, must be one of %s", port.Type, strings.Join(PortTypes, ", "))
+			}
+
+			if port.Kind != "" && !containsString(PortKinds, port.Kind) {
+				add(field+".kind", "unknown port kind %q, must be one of %s", port.Kind, strings.Join(PortKinds, ", "))
+			}
+
+			if port.Min > port.Max {
+				add(field+".max", "must not be less than min (%d)", port.Min)
+			}
+
+			if port.Step < 0 {
+				add(field+".step", "must not be negative")
+			}
+
+			if port.Unit != "" && port.Unit != avcontrol.VolumeScaleDB {
+				add(field+".unit", "unknown unit %q, must be %s", port.Unit, avcontrol.VolumeScaleDB)
+			}
+		}
+	}
+
+	for i, conn := range room.Connections {
+		field := fmt.Sprintf("connections.%d", i)
+
+		for _, end := range []struct {
+			field string
+			id    avcontrol.DeviceID
+		}{
+			{field + ".from.device", conn.From.Device},
+			{field + ".to.device", conn.To.Device},
+		} {
+			switch {
+			case end.id == "":
+				add(end.field, "is required")
+			case room.ID != "" && end.id.Room() != room.ID:
+				add(end.field, "%s belongs to %s, not %s", end.id, end.id.Room(), room.ID)
+			}
+		}
+	}
+
+	return problems
+}
+
+// knownPortType returns true if port is used for at least one of PortTypes.
+func knownPortType(port avcontrol.PortConfig) bool {
+	for _, typ := range PortTypes {
+		if len(avcontrol.PortConfigs{port}.OfType(typ)) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,147 @@
+package validate
+
+import (
+	"testing"
+
+	avcontrol "github.com/byuoitav/av-control-api"
+	"github.com/google/go-cmp/cmp"
+)
+
+var drivers = []string{"sony/adcp", "qsc"}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		room     Room
+		problems []Problem
+	}{
+		{
+			name: "Valid",
+			room: Room{
+				ID:    "ITB-1101",
+				Proxy: "http://itb-1101-cp1.byu.edu",
+				Devices: map[avcontrol.DeviceID]avcontrol.DeviceConfig{
+					"ITB-1101-D1": {
+						Address: "itb-1101-d1.byu.edu",
+						Driver:  "sony/adcp",
+					},
+					"ITB-1101-DSP1": {
+						Address: "itb-1101-dsp1.byu.edu",
+						Driver:  "qsc",
+						Ports: avcontrol.PortConfigs{
+							{Name: "Mic1Gain", Type: "volume"},
+							{Name: "Mic1Mute", Type: "mute"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "RoomID",
+			room: Room{
+				ID: "ITB1101",
+			},
+			problems: []Problem{
+				{Field: "id", Message: `"ITB1101" is not in the format Building-Room`},
+			},
+		},
+		{
+			name: "Proxy",
+			room: Room{
+				ID:    "ITB-1101",
+				Proxy: "itb-1101-cp1.byu.edu:80",
+			},
+			problems: []Problem{
+				{Field: "proxy", Message: `"itb-1101-cp1.byu.edu:80" has no host, so requests would never be proxied`},
+			},
+		},
+		{
+			name: "UnparsableProxy",
+			room: Room{
+				ID:    "ITB-1101",
+				Proxy: "http://%zz",
+			},
+			problems: []Problem{
+				{Field: "proxy", Message: `unable to parse url: parse "http://%zz": invalid URL escape "%zz"`},
+			},
+		},
+		{
+			name: "Connections",
+			room: Room{
+				ID: "ITB-1101",
+				Connections: []avcontrol.Connection{
+					{
+						From: avcontrol.Endpoint{Device: "ITB-1101-LAPTOP"},
+						To:   avcontrol.Endpoint{Device: "ITB-1101-SW1", Port: "1"},
+					},
+					{
+						From: avcontrol.Endpoint{Device: "ITB-1101-SW1", Port: "1"},
+						To:   avcontrol.Endpoint{Device: "ITB-1102-D1"},
+					},
+					{
+						To: avcontrol.Endpoint{Device: "ITB-1101-D1"},
+					},
+				},
+			},
+			problems: []Problem{
+				{Field: "connections.1.to.device", Message: "ITB-1102-D1 belongs to ITB-1102, not ITB-1101"},
+				{Field: "connections.2.from.device", Message: "is required"},
+			},
+		},
+		{
+			name: "Devices",
+			room: Room{
+				ID: "ITB-1101",
+				Devices: map[avcontrol.DeviceID]avcontrol.DeviceConfig{
+					"ITB-1101-D1": {
+						Address: "itb-1101-d1.byu.edu",
+						Driver:  "sony/bravia",
+					},
+					"ITB-1101-D2": {
+						Address: "ITB-1101-D1.byu.edu",
+						Driver:  "sony/adcp",
+					},
+					"ITB-1102-D1": {
+						Address: "itb-1102-d1.byu.edu",
+						Driver:  "sony/adcp",
+					},
+					"D1": {
+						Address: "d1.byu.edu",
+						Driver:  "sony/adcp",
+					},
+					"ITB-1101-DSP1": {
+						Ports: avcontrol.PortConfigs{
+							{Name: "Mic1Gain", Type: "volume"},
+							{Type: "gain"},
+							{Name: "HDMI1", Type: "input", Kind: "hdmi"},
+							{Name: "Mic2Gain", Type: "volume", Min: 10, Max: -10, Step: -1, Unit: "%"},
+						},
+					},
+				},
+			},
+			problems: []Problem{
+				{Field: "devices.D1", Message: `"D1" is not in the format Building-Room-Name`},
+				{Field: "devices.ITB-1101-D1.driver", Message: `driver "sony/bravia" is not registered`},
+				{Field: "devices.ITB-1101-D2.address", Message: `"ITB-1101-D1.byu.edu" is also used by ITB-1101-D1`},
+				{Field: "devices.ITB-1101-DSP1.driver", Message: "is required"},
+				{Field: "devices.ITB-1101-DSP1.address", Message: "is required"},
+				{Field: "devices.ITB-1101-DSP1.ports.1.name", Message: "is required"},
+				{Field: "devices.ITB-1101-DSP1.ports.1.type", Message: `unknown port type "gain", must be one of volume, mute, input, output`},
+				{Field: "devices.ITB-1101-DSP1.ports.2.kind", Message: `unknown port kind "hdmi", must be one of audio, video, audioVideo`},
+				{Field: "devices.ITB-1101-DSP1.ports.3.max", Message: "must not be less than min (10)"},
+				{Field: "devices.ITB-1101-DSP1.ports.3.step", Message: "must not be negative"},
+				{Field: "devices.ITB-1101-DSP1.ports.3.unit", Message: `unknown unit "%", must be dB`},
+				{Field: "devices.ITB-1102-D1", Message: "belongs to ITB-1102, not ITB-1101"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := Validate(tt.room, drivers)
+			if diff := cmp.Diff(tt.problems, problems); diff != "" {
+				t.Errorf("generated incorrect problems (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}